@@ -0,0 +1,558 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// AsciiDoc rendering backend
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"html"
+	"strings"
+)
+
+// Characters that have to be replaced by a character replacement attribute
+// to appear literally in AsciiDoc text.
+var asciidocReplacements = map[byte]string{
+	'*':  "{asterisk}",
+	'`':  "{backtick}",
+	'^':  "{caret}",
+	'~':  "{tilde}",
+	'+':  "{plus}",
+	'[':  "{startsb}",
+	']':  "{endsb}",
+	'|':  "{vbar}",
+	'\\': "{backslash}",
+}
+
+// AsciiDoc is a type that implements the Renderer interface for AsciiDoc
+// output, as consumed by Asciidoctor.
+//
+// Do not create this directly, instead use the AsciiDocRenderer function.
+type AsciiDoc struct {
+	flags int
+
+	// nesting depth of each kind of list, which determines the item markers
+	ulDepth, olDepth, dlDepth int
+
+	// footnotes already defined, which are only referenced from then on
+	footnotes map[string]struct{}
+
+	// the most recent image, in case it turns out to be a block image
+	lastImage  []byte
+	blockImage []byte
+}
+
+// AsciiDocRenderer creates and configures an AsciiDoc object, which
+// satisfies the Renderer interface.
+//
+// flags is a set of ASCIIDOC_* options ORed together (currently no such
+// options are defined).
+func AsciiDocRenderer(flags int) Renderer {
+	return &AsciiDoc{
+		flags:     flags,
+		footnotes: make(map[string]struct{}),
+	}
+}
+
+func (options *AsciiDoc) GetFlags() int {
+	return options.flags
+}
+
+func (options *AsciiDoc) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	blankLine(out)
+	if lang := codeLanguage(info); lang != "" {
+		out.WriteString("[source,")
+		out.WriteString(lang)
+		out.WriteString("]\n")
+	}
+	delimitedBlock(out, text, "----")
+}
+
+func (options *AsciiDoc) TitleBlock(out *bytes.Buffer, text []byte) {
+	title, authors, date := titleBlockFields(text)
+	blankLine(out)
+	out.WriteString("= ")
+	options.NormalText(out, []byte(title))
+	out.WriteByte('\n')
+	if len(authors) > 0 {
+		options.NormalText(out, []byte(strings.Join(authors, "; ")))
+		out.WriteByte('\n')
+	}
+	if date != "" {
+		out.WriteString(":revdate: ")
+		out.WriteString(date)
+		out.WriteByte('\n')
+	}
+}
+
+func (options *AsciiDoc) BlockQuote(out *bytes.Buffer, text []byte) {
+	blankLine(out)
+	delimitedBlock(out, text, "____")
+}
+
+func (options *AsciiDoc) BlockHtml(out *bytes.Buffer, text []byte) {
+	blankLine(out)
+	delimitedBlock(out, text, "++++")
+}
+
+func (options *AsciiDoc) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	marker := out.Len()
+	blankLine(out)
+
+	if id != "" {
+		out.WriteString("[[")
+		out.WriteString(id)
+		out.WriteString("]]\n")
+	}
+
+	// a single = is reserved for the document title; Asciidoctor stops at
+	// five levels of sections
+	if level > 5 {
+		level = 5
+	}
+	out.WriteString(strings.Repeat("=", level+1))
+	out.WriteByte(' ')
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteByte('\n')
+}
+
+func (options *AsciiDoc) HRule(out *bytes.Buffer) {
+	blankLine(out)
+	out.WriteString("'''\n")
+}
+
+func (options *AsciiDoc) List(out *bytes.Buffer, text func() bool, flags int) {
+	marker := out.Len()
+	blankLine(out)
+
+	depth := &options.ulDepth
+	if flags&LIST_TYPE_DEFINITION != 0 {
+		depth = &options.dlDepth
+	} else if flags&LIST_TYPE_ORDERED != 0 {
+		depth = &options.olDepth
+	}
+	*depth++
+	ok := text()
+	*depth--
+
+	if !ok {
+		out.Truncate(marker)
+		return
+	}
+}
+
+func (options *AsciiDoc) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	switch {
+	case flags&LIST_TYPE_TERM != 0:
+		out.Write(bytes.Replace(text, []byte("\n"), []byte(" "), -1))
+		out.WriteString(options.termMarker())
+		out.WriteByte('\n')
+
+	case flags&LIST_TYPE_DEFINITION != 0:
+		// only one definition belongs to a term; attach any others to it
+		b := bytes.TrimRight(out.Bytes(), "\n")
+		if !bytes.HasSuffix(b, []byte(options.termMarker())) {
+			out.WriteString("+\n")
+		}
+		writeListItemContent(out, text, "")
+
+	case flags&LIST_TYPE_ORDERED != 0:
+		writeListItemContent(out, text, strings.Repeat(".", options.olDepth)+" ")
+
+	default:
+		writeListItemContent(out, text, strings.Repeat("*", options.ulDepth)+" ")
+	}
+}
+
+func (options *AsciiDoc) termMarker() string {
+	return strings.Repeat(":", options.dlDepth+1)
+}
+
+// Write the contents of a list item. The first paragraph goes right after
+// the item marker, every other block is attached with a list continuation,
+// except for nested lists which attach themselves.
+func writeListItemContent(out *bytes.Buffer, text []byte, marker string) {
+	chunks := asciidocChunks(text)
+	out.WriteString(marker)
+	if len(chunks) == 0 || isAsciiDocBlockStart(chunks[0]) {
+		out.WriteString("{empty}\n")
+	} else {
+		out.Write(chunks[0])
+		out.WriteByte('\n')
+		chunks = chunks[1:]
+	}
+	for _, chunk := range chunks {
+		if !isAsciiDocListStart(chunk) {
+			out.WriteString("+\n")
+		}
+		out.Write(chunk)
+		out.WriteByte('\n')
+	}
+}
+
+// Split rendered AsciiDoc into the blocks separated by blank lines, keeping
+// delimited blocks in one piece.
+func asciidocChunks(text []byte) [][]byte {
+	var chunks [][]byte
+	var current []byte
+	delimiter := ""
+	for _, line := range bytes.Split(bytes.Trim(text, "\n"), []byte("\n")) {
+		switch {
+		case delimiter != "":
+			if string(line) == delimiter {
+				delimiter = ""
+			}
+		case len(line) == 0:
+			if current != nil {
+				chunks = append(chunks, current)
+				current = nil
+			}
+			continue
+		case isAsciiDocDelimiter(line):
+			delimiter = string(line)
+		}
+		if current != nil {
+			current = append(current, '\n')
+		}
+		current = append(current, line...)
+	}
+	if current != nil {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+func isAsciiDocDelimiter(line []byte) bool {
+	if bytes.Equal(line, []byte("|===")) {
+		return true
+	}
+	if len(line) < 4 {
+		return false
+	}
+	for _, c := range line {
+		if c != line[0] {
+			return false
+		}
+	}
+	return bytes.IndexByte([]byte("-_+=*."), line[0]) >= 0
+}
+
+func isAsciiDocBlockStart(chunk []byte) bool {
+	line := chunk
+	if i := bytes.IndexByte(chunk, '\n'); i >= 0 {
+		line = chunk[:i]
+	}
+	return len(line) == 0 || line[0] == '[' || isAsciiDocDelimiter(line) ||
+		bytes.Equal(line, []byte("'''")) || bytes.HasPrefix(line, []byte("image::"))
+}
+
+func isAsciiDocListStart(chunk []byte) bool {
+	i := 0
+	for i < len(chunk) && (chunk[i] == '*' || chunk[i] == '.') {
+		i++
+	}
+	if i > 0 && i < len(chunk) && chunk[i] == ' ' {
+		return true
+	}
+	line := chunk
+	if j := bytes.IndexByte(chunk, '\n'); j >= 0 {
+		line = chunk[:j]
+	}
+	return bytes.HasSuffix(line, []byte("::"))
+}
+
+// Write text as a delimited block, lengthening the delimiter if the text
+// contains a line that would close the block early.
+func delimitedBlock(out *bytes.Buffer, text []byte, delimiter string) {
+	text = bytes.Trim(text, "\n")
+	for bytes.Contains(append(append([]byte("\n"), text...), '\n'), []byte("\n"+delimiter+"\n")) {
+		delimiter += delimiter[:1]
+	}
+	out.WriteString(delimiter)
+	out.WriteByte('\n')
+	if len(text) > 0 {
+		out.Write(text)
+		out.WriteByte('\n')
+	}
+	out.WriteString(delimiter)
+	out.WriteByte('\n')
+}
+
+func (options *AsciiDoc) Paragraph(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	blankLine(out)
+
+	start := out.Len()
+	options.lastImage = nil
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+
+	// a paragraph holding nothing but an image becomes a block image
+	if options.lastImage != nil && bytes.Equal(out.Bytes()[start:], options.lastImage) {
+		out.Truncate(start)
+		out.Write(options.blockImage)
+		return
+	}
+	out.WriteByte('\n')
+}
+
+func (options *AsciiDoc) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	blankLine(out)
+	out.WriteString("[cols=\"")
+	for i, align := range columnData {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		switch align {
+		case TABLE_ALIGNMENT_LEFT:
+			out.WriteByte('<')
+		case TABLE_ALIGNMENT_RIGHT:
+			out.WriteByte('>')
+		case TABLE_ALIGNMENT_CENTER:
+			out.WriteByte('^')
+		default:
+			out.WriteByte('1')
+		}
+	}
+	out.WriteString("\",options=\"header\"]\n|===\n")
+	out.Write(header)
+	if len(body) > 0 {
+		out.WriteByte('\n')
+		out.Write(body)
+	}
+	out.WriteString("|===\n")
+}
+
+func (options *AsciiDoc) TableRow(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+	out.WriteByte('\n')
+}
+
+func (options *AsciiDoc) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
+	options.TableCell(out, text, align)
+}
+
+func (options *AsciiDoc) TableCell(out *bytes.Buffer, text []byte, align int) {
+	if out.Len() > 0 {
+		out.WriteByte(' ')
+	}
+	out.WriteByte('|')
+	out.WriteString(strings.Replace(tableCellText(text), "|", "\\|", -1))
+}
+
+// Footnotes are written where they are referenced, see FootnoteRefContent.
+func (options *AsciiDoc) Footnotes(out *bytes.Buffer, text func() bool) {
+}
+
+func (options *AsciiDoc) FootnoteItem(out *bytes.Buffer, name, text []byte, flags int) {
+}
+
+func (options *AsciiDoc) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	if kind == LINK_TYPE_EMAIL {
+		out.WriteString("mailto:")
+		out.Write(link)
+		out.WriteByte('[')
+		options.NormalText(out, link)
+		out.WriteByte(']')
+		return
+	}
+	asciidocLinkTarget(out, link)
+	out.WriteString("[]")
+}
+
+func asciidocLinkTarget(out *bytes.Buffer, link []byte) {
+	if bytes.IndexAny(link, " []") >= 0 {
+		out.WriteString("link:++")
+		out.Write(link)
+		out.WriteString("++")
+		return
+	}
+	out.WriteString("link:")
+	out.Write(link)
+}
+
+func (options *AsciiDoc) CodeSpan(out *bytes.Buffer, text []byte) {
+	out.WriteString("`+")
+	out.Write(text)
+	out.WriteString("+`")
+}
+
+func (options *AsciiDoc) DoubleEmphasis(out *bytes.Buffer, text []byte) {
+	out.WriteString("**")
+	out.Write(text)
+	out.WriteString("**")
+}
+
+func (options *AsciiDoc) Emphasis(out *bytes.Buffer, text []byte) {
+	if len(text) == 0 {
+		return
+	}
+	out.WriteString("__")
+	out.Write(text)
+	out.WriteString("__")
+}
+
+func (options *AsciiDoc) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	var attrs bytes.Buffer
+	attrs.WriteByte('[')
+	asciidocQuoted(&attrs, alt)
+	if len(title) > 0 {
+		attrs.WriteString(",title=")
+		asciidocQuoted(&attrs, title)
+	}
+	attrs.WriteByte(']')
+
+	start := out.Len()
+	out.WriteString("image:")
+	out.Write(link)
+	out.Write(attrs.Bytes())
+	options.lastImage = append([]byte(nil), out.Bytes()[start:]...)
+
+	var block bytes.Buffer
+	if len(title) > 0 {
+		block.WriteByte('.')
+		options.NormalText(&block, title)
+		block.WriteByte('\n')
+	}
+	block.WriteString("image::")
+	block.Write(link)
+	block.WriteByte('[')
+	asciidocQuoted(&block, alt)
+	block.WriteString("]\n")
+	options.blockImage = block.Bytes()
+}
+
+// Write an attribute value in double quotes.
+func asciidocQuoted(out *bytes.Buffer, text []byte) {
+	out.WriteByte('"')
+	out.Write(bytes.Replace(text, []byte(`"`), []byte(`\"`), -1))
+	out.WriteByte('"')
+}
+
+func (options *AsciiDoc) LineBreak(out *bytes.Buffer) {
+	out.WriteString(" +\n")
+}
+
+func (options *AsciiDoc) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if len(link) > 1 && link[0] == '#' {
+		out.WriteString("<<")
+		out.Write(link[1:])
+		out.WriteByte(',')
+		out.Write(content)
+		out.WriteString(">>")
+		return
+	}
+	asciidocLinkTarget(out, link)
+	out.WriteByte('[')
+	if len(title) > 0 || bytes.IndexByte(content, '=') >= 0 {
+		asciidocQuoted(out, content)
+	} else {
+		out.Write(content)
+	}
+	if len(title) > 0 {
+		out.WriteString(",title=")
+		asciidocQuoted(out, title)
+	}
+	out.WriteByte(']')
+}
+
+func (options *AsciiDoc) RawHtmlTag(out *bytes.Buffer, tag []byte) {
+	out.WriteString("+++")
+	out.Write(tag)
+	out.WriteString("+++")
+}
+
+func (options *AsciiDoc) TripleEmphasis(out *bytes.Buffer, text []byte) {
+	out.WriteString("**__")
+	out.Write(text)
+	out.WriteString("__**")
+}
+
+func (options *AsciiDoc) StrikeThrough(out *bytes.Buffer, text []byte) {
+	out.WriteString("[line-through]##")
+	out.Write(text)
+	out.WriteString("##")
+}
+
+func (options *AsciiDoc) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
+	out.WriteString("footnote:")
+	out.Write(slugify(ref))
+	out.WriteString("[]")
+}
+
+func (options *AsciiDoc) FootnoteRefContent(out *bytes.Buffer, ref []byte, text []byte, id int, flags int) {
+	slug := slugify(ref)
+	if _, found := options.footnotes[string(slug)]; found {
+		options.FootnoteRef(out, ref, id)
+		return
+	}
+	options.footnotes[string(slug)] = struct{}{}
+
+	// footnotes can only hold inline content
+	text = bytes.TrimSpace(text)
+	text = bytes.Replace(text, []byte("\n\n"), []byte(" "), -1)
+	text = bytes.Replace(text, []byte("\n"), []byte(" "), -1)
+
+	out.WriteString("footnote:")
+	out.Write(slug)
+	out.WriteByte('[')
+	out.Write(bytes.Replace(text, []byte("]"), []byte("\\]"), -1))
+	out.WriteByte(']')
+}
+
+func (options *AsciiDoc) Entity(out *bytes.Buffer, entity []byte) {
+	options.NormalText(out, []byte(html.UnescapeString(string(entity))))
+}
+
+func (options *AsciiDoc) NormalText(out *bytes.Buffer, text []byte) {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '^' && i+1 == len(text) {
+			// may start an inline footnote, which the parser strips again
+			out.WriteByte(c)
+			continue
+		}
+		if r, ok := asciidocReplacements[c]; ok {
+			out.WriteString(r)
+			continue
+		}
+		prev := byte(0)
+		if i > 0 {
+			prev = text[i-1]
+		} else if out.Len() > 0 {
+			prev = out.Bytes()[out.Len()-1]
+		}
+		// underscores and hashes only mark up text at word boundaries
+		if (c == '_' || c == '#') &&
+			(!isalnum(prev) || i+1 == len(text) || !isalnum(text[i+1])) {
+			out.WriteString("pass:c[")
+			out.WriteByte(c)
+			out.WriteByte(']')
+			continue
+		}
+		out.WriteByte(c)
+	}
+}
+
+func (options *AsciiDoc) DocumentHeader(out *bytes.Buffer) {
+	options.footnotes = make(map[string]struct{})
+}
+
+func (options *AsciiDoc) DocumentFooter(out *bytes.Buffer) {
+}
@@ -0,0 +1,134 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the AsciiDoc renderer
+//
+
+package blackfriday
+
+import (
+	"testing"
+)
+
+func doTestsAsciiDoc(t *testing.T, tests []string, extensions int) {
+	doTestsWithRenderer(t, tests, extensions, func() Renderer {
+		return AsciiDocRenderer(0)
+	})
+}
+
+func TestAsciiDocBlocks(t *testing.T) {
+	var tests = []string{
+		"# Header 1 {#top}\n\nSome text.\n\n###### Six\n",
+		"[[top]]\n== Header 1\n\nSome text.\n\n====== Six\n",
+
+		"> quoted\n>\n> > nested\n",
+		"_____\nquoted\n\n____\nnested\n____\n_____\n",
+
+		"```go\nfunc main() {}\n```\n",
+		"[source,go]\n----\nfunc main() {}\n----\n",
+
+		"```\n----\n```\n",
+		"-----\n----\n-----\n",
+
+		"<div>\nraw\n</div>\n",
+		"++++\n<div>\nraw\n</div>\n++++\n",
+
+		"one\n\n***\n",
+		"one\n\n'''\n",
+	}
+	doTestsAsciiDoc(t, tests, EXTENSION_FENCED_CODE|EXTENSION_HEADER_IDS)
+}
+
+func TestAsciiDocLists(t *testing.T) {
+	var tests = []string{
+		"* one\n* two\n    * nested\n        1. deep\n* three\n",
+		"* one\n* two\n** nested\n. deep\n* three\n",
+
+		"1. first\n\n    more\n\n        code\n\n2. second\n",
+		". first\n+\nmore\n+\n----\ncode\n----\n. second\n",
+
+		"* ```\n  code\n  ```\n",
+		"* {empty}\n+\n----\ncode\n----\n",
+
+		"Term\n:   Definition one\n\n:   Definition two\n",
+		"Term::\nDefinition one\n+\nDefinition two\n",
+	}
+	doTestsAsciiDoc(t, tests, EXTENSION_FENCED_CODE|EXTENSION_DEFINITION_LISTS)
+}
+
+func TestAsciiDocTable(t *testing.T) {
+	var tests = []string{
+		"Name | Age | Town | Note\n:----|----:|:---:|---\nBob | 31 | Rome | a\\|b\n",
+		"[cols=\"<,>,^,1\",options=\"header\"]\n|===\n|Name |Age |Town |Note\n\n|Bob |31 |Rome |a{vbar}b\n|===\n",
+	}
+	doTestsAsciiDoc(t, tests, EXTENSION_TABLES)
+}
+
+func TestAsciiDocInline(t *testing.T) {
+	var tests = []string{
+		"*em* **strong** ***both*** `code` ~~del~~\n",
+		"__em__ **strong** **__both__** `+code+` [line-through]##del##\n",
+
+		"snake_case, _x_ and a*b [c] #tag\n",
+		"snake_case, __x__ and a{asterisk}b {startsb}c{endsb} pass:c[#]tag\n",
+
+		"not \\_emphasis\\_\n",
+		"not pass:c[_]emphasispass:c[_]\n",
+
+		"a [link](http://example.com \"Title\") and [plain](http://example.com/x)\n",
+		"a link:http://example.com[\"link\",title=\"Title\"] and link:http://example.com/x[plain]\n",
+
+		"see [below](#sec)\n",
+		"see <<sec,below>>\n",
+
+		"<http://example.com> and <me@example.com>\n",
+		"link:http://example.com[] and mailto:me@example.com[me@example.com]\n",
+
+		"line  \nbreak\n",
+		"line +\nbreak\n",
+
+		"AT&amp;T\n",
+		"AT&T\n",
+	}
+	doTestsAsciiDoc(t, tests, EXTENSION_STRIKETHROUGH)
+}
+
+func TestAsciiDocImages(t *testing.T) {
+	var tests = []string{
+		"![alt](pic.png \"The title\")\n",
+		".The title\nimage::pic.png[\"alt\"]\n",
+
+		"An ![icon](i.png) inline.\n",
+		"An image:i.png[\"icon\"] inline.\n",
+	}
+	doTestsAsciiDoc(t, tests, 0)
+}
+
+func TestAsciiDocFootnotes(t *testing.T) {
+	var tests = []string{
+		"Text[^a] and again[^a].\n\n[^a]: The [note].\n",
+		"Textfootnote:a[The {startsb}note{endsb}.] and againfootnote:a[].\n",
+
+		"Text[^b].\n\n[^b]: First.\n\n    Second.\n",
+		"Textfootnote:b[First. Second.].\n",
+
+		"Inline^[a note] here.\n",
+		"Inlinefootnote:a-note[a note] here.\n",
+	}
+	doTestsAsciiDoc(t, tests, EXTENSION_FOOTNOTES)
+}
+
+func TestAsciiDocTitleBlock(t *testing.T) {
+	var tests = []string{
+		"% The Title\n% Ann; Bob\n% 2020-01-02\n\nText.\n",
+		"= The Title\nAnn; Bob\n:revdate: 2020-01-02\n\nText.\n",
+	}
+	doTestsAsciiDoc(t, tests, EXTENSION_TITLEBLOCK)
+}
@@ -72,6 +72,16 @@ func (p *parser) block(out *bytes.Buffer, data []byte) {
 			}
 		}
 
+		// table of contents marker:
+		//
+		// [TOC]
+		if p.flags&EXTENSION_TOC_MARKER != 0 {
+			if i := p.tocMarker(out, data); i > 0 {
+				data = data[i:]
+				continue
+			}
+		}
+
 		// blank lines.  note: returns the # of bytes to skip
 		if i := p.isEmpty(data); i > 0 {
 			data = data[i:]
@@ -214,22 +224,10 @@ func (p *parser) prefixHeader(out *bytes.Buffer, data []byte) int {
 	i := skipChar(data, level, ' ')
 	end := skipUntilChar(data, i, '\n')
 	skip := end
-	id := ""
+	var attrs HeaderAttributes
 	if p.flags&EXTENSION_HEADER_IDS != 0 {
-		j, k := 0, 0
-		// find start/end of header id
-		for j = i; j < end-1 && (data[j] != '{' || data[j+1] != '#'); j++ {
-		}
-		for k = j + 1; k < end && data[k] != '}'; k++ {
-		}
-		// extract header id iff found
-		if j < end && k < end {
-			id = string(data[j+2 : k])
-			end = j
-			skip = k + 1
-			for end > 0 && data[end-1] == ' ' {
-				end--
-			}
+		if a, textEnd, attrsEnd := findHeaderAttributes(data, i, end, true); attrsEnd > 0 {
+			attrs, end, skip = a, textEnd, attrsEnd
 		}
 	}
 	for end > 0 && data[end-1] == '#' {
@@ -242,18 +240,116 @@ func (p *parser) prefixHeader(out *bytes.Buffer, data []byte) int {
 		end--
 	}
 	if end > i {
-		if id == "" && p.flags&EXTENSION_AUTO_HEADER_IDS != 0 {
-			id = SanitizedAnchorName(string(data[i:end]))
-		}
-		work := func() bool {
-			p.inline(out, data[i:end])
-			return true
+		if attrs.ID == "" && p.flags&EXTENSION_AUTO_HEADER_IDS != 0 {
+			attrs.ID = p.headerID(data[i:end])
 		}
-		p.r.Header(out, work, level, id)
+		p.header(out, data[i:end], level, attrs)
 	}
 	return skip
 }
 
+// headerID makes the ID of a header from its text with the Slugger from the
+// options, or with SanitizedAnchorName from the raw text if there is none
+func (p *parser) headerID(data []byte) string {
+	if p.slugger == nil {
+		return SanitizedAnchorName(string(data))
+	}
+	return p.slugger(headerText(data))
+}
+
+// render a header, with its attributes if the renderer wants them
+func (p *parser) header(out *bytes.Buffer, data []byte, level int, attrs HeaderAttributes) {
+	heading := tocHeading{level: level, plain: headerText(data), id: attrs.ID}
+	work := func() bool {
+		marker := out.Len()
+		p.inline(out, data)
+		heading.text = append([]byte(nil), out.Bytes()[marker:]...)
+		return true
+	}
+	if r, ok := p.r.(HeaderAttributesRenderer); ok {
+		r.HeaderWithAttributes(out, work, level, attrs)
+	} else {
+		p.r.Header(out, work, level, attrs.ID)
+	}
+	p.headings = append(p.headings, heading)
+}
+
+// findHeaderAttributes looks for attributes in braces at the end of the
+// text of a header, data[start:end]. If there are some, it returns them with
+// the end of the text before them and the end of the closing brace, which is
+// zero otherwise; closing #s after them are included. In prefix headers the
+// attributes may be followed by closing #s, and a plain {#id} by more text,
+// as it always could.
+func findHeaderAttributes(data []byte, start, end int, prefix bool) (attrs HeaderAttributes, textEnd, attrsEnd int) {
+	for j := start; j < end-1; j++ {
+		if data[j] != '{' || !isHeaderAttribute(data[j+1:end]) {
+			continue
+		}
+		k := j + 1
+		for k < end && data[k] != '}' {
+			k++
+		}
+		if k >= end {
+			break
+		}
+
+		rest := k + 1
+		for rest < end && (data[rest] == ' ' || prefix && data[rest] == '#') {
+			rest++
+		}
+		plainID := data[j+1] == '#' && bytes.IndexByte(data[j+1:k], ' ') < 0
+		if rest < end && !(prefix && plainID) {
+			continue
+		}
+
+		textEnd = j
+		for textEnd > 0 && data[textEnd-1] == ' ' {
+			textEnd--
+		}
+		if rest < end {
+			rest = k + 1
+		}
+		return parseHeaderAttributes(data[j+1 : k]), textEnd, rest
+	}
+	return attrs, end, 0
+}
+
+// isHeaderAttribute tells whether data can start the attributes in braces
+// after a header: an id, a class or a lone "-".
+func isHeaderAttribute(data []byte) bool {
+	switch {
+	case data[0] == '#':
+		return true
+	case data[0] == '.':
+		return len(data) > 1 && isletter(data[1])
+	case data[0] == '-':
+		return len(data) > 1 && (data[1] == '}' || data[1] == ' ')
+	}
+	return false
+}
+
+// parseHeaderAttributes reads the attributes between the braces after a
+// header, e.g. "#id .class -". Anything else is ignored.
+func parseHeaderAttributes(data []byte) HeaderAttributes {
+	var attrs HeaderAttributes
+	if data[0] == '#' && bytes.IndexByte(data, ' ') < 0 {
+		// a plain id, which may contain anything but spaces
+		attrs.ID = string(data[1:])
+		return attrs
+	}
+	for _, field := range bytes.Fields(data) {
+		switch {
+		case field[0] == '#':
+			attrs.ID = string(field[1:])
+		case field[0] == '.' && len(field) > 1:
+			attrs.Classes = append(attrs.Classes, string(field[1:]))
+		case len(field) == 1 && field[0] == '-':
+			attrs.Classes = append(attrs.Classes, "unnumbered")
+		}
+	}
+	return attrs
+}
+
 func (p *parser) isUnderlinedHeader(data []byte) int {
 	// test of level 1 header
 	if data[0] == '=' {
@@ -299,6 +395,84 @@ func (p *parser) titleBlock(out *bytes.Buffer, data []byte, doRender bool) int {
 	return len(data)
 }
 
+// a line holding only [TOC] or [[_TOC_]], in any case, at the top level of
+// the document and followed by a blank line
+func (p *parser) tocMarker(out *bytes.Buffer, data []byte) int {
+	r, ok := p.r.(TocMarkerRenderer)
+	if !ok || p.nesting > 1 {
+		return 0
+	}
+
+	i := 0
+	for i < 3 && data[i] == ' ' {
+		i++
+	}
+	end := bytes.IndexByte(data, '\n')
+	line := bytes.ToUpper(bytes.TrimRight(data[i:end], " \t"))
+	if !bytes.Equal(line, []byte("[TOC]")) && !bytes.Equal(line, []byte("[[_TOC_]]")) {
+		return 0
+	}
+	end++
+	if end < len(data) && p.isEmpty(data[end:]) == 0 {
+		return 0
+	}
+
+	if !r.TocMarker(out) {
+		return 0
+	}
+	return end
+}
+
+// ParseTitleBlock reads the Pandoc-style title block at the start of a
+// document, as rendered with EXTENSION_TITLEBLOCK, and reports whether there
+// is one.
+func ParseTitleBlock(input []byte) (meta Metadata, ok bool) {
+	var lines [][]byte
+	for _, line := range bytes.Split(input, []byte("\n")) {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(lines) == 0 && len(bytes.TrimSpace(line)) == 0 {
+			// blank lines before the title block
+			continue
+		}
+		if !bytes.HasPrefix(line, []byte("%")) {
+			break
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return meta, false
+	}
+	meta.Title, meta.Authors, meta.Date = titleBlockFields(bytes.Join(lines, []byte("\n")))
+	return meta, true
+}
+
+// Split a Pandoc-style title block into its fields:
+//
+// % title
+// % author(s) (separated by semicolons)
+// % date
+//
+// Missing or empty lines leave the corresponding field blank.
+func titleBlockFields(text []byte) (title string, authors []string, date string) {
+	lines := bytes.Split(text, []byte("\n"))
+	for i, line := range lines {
+		line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("%")))
+		switch i {
+		case 0:
+			title = string(line)
+		case 1:
+			for _, author := range strings.Split(string(line), ";") {
+				if author = strings.TrimSpace(author); author != "" {
+					authors = append(authors, author)
+				}
+			}
+		case 2:
+			date = string(line)
+		}
+	}
+	return
+}
+
 func (p *parser) html(out *bytes.Buffer, data []byte, doRender bool) int {
 	var i, j int
 
@@ -1382,21 +1556,19 @@ func (p *parser) paragraph(out *bytes.Buffer, data []byte) int {
 					eol--
 				}
 
-				// render the header
-				// this ugly double closure avoids forcing variables onto the heap
-				work := func(o *bytes.Buffer, pp *parser, d []byte) func() bool {
-					return func() bool {
-						pp.inline(o, d)
-						return true
+				var attrs HeaderAttributes
+				if p.flags&EXTENSION_HEADER_IDS != 0 {
+					if a, textEnd, attrsEnd := findHeaderAttributes(data, prev, eol, false); attrsEnd > 0 && textEnd > prev {
+						attrs, eol = a, textEnd
 					}
-				}(out, p, data[prev:eol])
+				}
 
-				id := ""
-				if p.flags&EXTENSION_AUTO_HEADER_IDS != 0 {
-					id = SanitizedAnchorName(string(data[prev:eol]))
+				// render the header
+				if attrs.ID == "" && p.flags&EXTENSION_AUTO_HEADER_IDS != 0 {
+					attrs.ID = p.headerID(data[prev:eol])
 				}
 
-				p.r.Header(out, work, level, id)
+				p.header(out, data[prev:eol], level, attrs)
 
 				// find the end of the underline
 				for data[i] != '\n' {
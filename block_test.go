@@ -361,6 +361,16 @@ func TestPrefixAutoHeaderIdExtension(t *testing.T) {
 
 		"# Header\n\n# Header 1\n\n# Header\n\n# Header",
 		"<h1 id=\"header\">Header</h1>\n\n<h1 id=\"header-1\">Header 1</h1>\n\n<h1 id=\"header-1-1\">Header</h1>\n\n<h1 id=\"header-1-2\">Header</h1>\n",
+
+		// without a Slugger, IDs are made from the header as written
+		"# One &amp; *only*\n",
+		"<h1 id=\"one-amp-only\">One &amp; <em>only</em></h1>\n",
+
+		"# See [Foo](http://x)\n",
+		"<h1 id=\"see-foo-http-x\">See <a href=\"http://x\">Foo</a></h1>\n",
+
+		"The `cfg` flag\n---\n",
+		"<h2 id=\"the-cfg-flag\">The <code>cfg</code> flag</h2>\n",
 	}
 	doTestsBlock(t, tests, EXTENSION_AUTO_HEADER_IDS)
 }
@@ -1636,6 +1646,60 @@ func TestListWithFencedCodeBlockNoExtensions(t *testing.T) {
 	doTestsBlock(t, tests, 0)
 }
 
+func TestFencedCodeBlockOptions(t *testing.T) {
+	var tests = []string{
+		"```go {2} title=\"main.go\"\na := 1\nb := a < 2\n```\n",
+		"<figure class=\"code\">\n<figcaption>main.go</figcaption>\n<pre><code class=\"language-go\">" +
+			"<span class=\"line\">a := 1</span>\n<span class=\"line highlight\">b := a &lt; 2</span>\n</code></pre>\n</figure>\n",
+
+		"```text {1,3-4} linenos\none\ntwo\nthree\nfour\n```\n",
+		"<pre><code class=\"language-text\"><span class=\"line highlight\"><span class=\"line-number\">1</span>one</span>\n" +
+			"<span class=\"line\"><span class=\"line-number\">2</span>two</span>\n" +
+			"<span class=\"line highlight\"><span class=\"line-number\">3</span>three</span>\n" +
+			"<span class=\"line highlight\"><span class=\"line-number\">4</span>four</span>\n</code></pre>\n",
+
+		"```py{1}\nx\n```\n",
+		"<pre><code class=\"language-py\"><span class=\"line highlight\">x</span>\n</code></pre>\n",
+
+		"```sh filename=run.sh\nls\n```\n",
+		"<figure class=\"code\">\n<figcaption>run.sh</figcaption>\n<pre><code class=\"language-sh\"><span class=\"line\">ls</span>\n</code></pre>\n</figure>\n",
+
+		"```go plain\nx\n```\n",
+		"<pre><code class=\"language-go\">x\n</code></pre>\n",
+
+		"```go {}\nx\n```\n",
+		"<pre><code class=\"language-go\">x\n</code></pre>\n",
+
+		"```go { , }\nx\n```\n",
+		"<pre><code class=\"language-go\">x\n</code></pre>\n",
+	}
+	doTestsBlock(t, tests, EXTENSION_FENCED_CODE)
+}
+
+type testHighlighter struct{}
+
+func (testHighlighter) Highlight(lang, info string, code []byte) ([]byte, bool) {
+	if lang != "go" {
+		return nil, false
+	}
+	return []byte("<pre class=\"hl\">" + info + ": " + strings.ToUpper(string(code)) + "</pre>\n"), true
+}
+
+func TestCodeHighlighter(t *testing.T) {
+	var tests = []string{
+		"```go {2}\nx\n```\n",
+		"<pre class=\"hl\">go {2}: X\n</pre>\n",
+
+		"```c\nx\n```\n",
+		"<pre><code class=\"language-c\">x\n</code></pre>\n",
+
+		"```c {1}\nx\n```\n",
+		"<pre><code class=\"language-c\"><span class=\"line highlight\">x</span>\n</code></pre>\n",
+	}
+	doTestsBlockWithRunner(t, tests, EXTENSION_FENCED_CODE,
+		runnerWithRendererParameters(HtmlRendererParameters{CodeHighlighter: testHighlighter{}}))
+}
+
 func TestTitleBlock_EXTENSION_TITLEBLOCK(t *testing.T) {
 	var tests = []string{
 		"% Some title\n" +
@@ -1650,6 +1714,367 @@ func TestTitleBlock_EXTENSION_TITLEBLOCK(t *testing.T) {
 	doTestsBlock(t, tests, EXTENSION_TITLEBLOCK)
 }
 
+func TestSlides(t *testing.T) {
+	var tests = []string{
+		"One\n\n***\n\nTwo\n",
+		"<section>\n\n<p>One</p>\n</section>\n\n<section>\n\n<p>Two</p>\n</section>\n",
+
+		"# Part\n\n## First\n\nText\n\n## Second\n\n# Other\n",
+		"<section>\n\n<section>\n\n<h1>Part</h1>\n</section>\n\n<section>\n\n<h2>First</h2>\n\n<p>Text</p>\n</section>\n" +
+			"\n<section>\n\n<h2>Second</h2>\n</section>\n</section>\n\n<section>\n\n<section>\n\n<h1>Other</h1>\n</section>\n</section>\n",
+
+		"## A\n\nText\n\nNote: say hi\n\nmore\n\n## B\n",
+		"<section>\n\n<h2>A</h2>\n\n<p>Text</p>\n\n<aside class=\"notes\">\n<p>say hi</p>\n\n<p>more</p>\n</aside>\n</section>\n" +
+			"\n<section>\n\n<h2>B</h2>\n</section>\n",
+
+		"> ## Quoted\n>\n> ***\n",
+		"<section>\n\n<blockquote>\n<h2>Quoted</h2>\n\n<hr>\n</blockquote>\n</section>\n",
+	}
+	doTestsWithRenderer(t, tests, 0, func() Renderer {
+		return HtmlRendererWithParameters(HTML_SLIDES, "", "", HtmlRendererParameters{SlideLevel: 2})
+	})
+}
+
+func TestSlidesCompletePage(t *testing.T) {
+	renderer := HtmlRendererWithParameters(HTML_SLIDES|HTML_COMPLETE_PAGE, "Deck", "",
+		HtmlRendererParameters{SlideAssetPath: "assets/reveal/", SlideTheme: "black"})
+	doc := string(Markdown([]byte("% Deck\n\nText\n"), renderer, EXTENSION_TITLEBLOCK))
+	for _, want := range []string{
+		"<link rel=\"stylesheet\" href=\"assets/reveal/dist/reveal.css\">\n",
+		"<link rel=\"stylesheet\" href=\"assets/reveal/dist/theme/black.css\">\n",
+		"<body>\n<div class=\"reveal\">\n<div class=\"slides\">\n\n<section>\n<h1 class=\"title\">Deck\n</h1>\n</section>\n",
+		"<section>\n\n<p>Text</p>\n</section>\n</div>\n</div>\n",
+		"<script src=\"assets/reveal/dist/reveal.js\"></script>\n",
+		"<script src=\"assets/reveal/plugin/notes/notes.js\"></script>\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("page does not contain %q:\n%s", want, doc)
+		}
+	}
+	if strings.Contains(doc, "//") {
+		t.Errorf("page refers to remote assets:\n%s", doc)
+	}
+}
+
+func TestToc(t *testing.T) {
+	input := "# Intro\n\n## *Getting* started\n\n### Install &amp; run\n\n## Usage\n\n# Reference\n"
+	renderer := HtmlRenderer(0, "", "").(*Html)
+	Markdown([]byte(input), renderer, EXTENSION_AUTO_HEADER_IDS)
+
+	toc := renderer.Toc()
+	if len(toc) != 2 || len(toc[0].Children) != 2 || len(toc[1].Children) != 0 {
+		t.Fatalf("wrong shape of tree: %+v", toc)
+	}
+	started := toc[0].Children[0]
+	if started.Level != 2 || started.Text != "<em>Getting</em> started" ||
+		started.PlainText != "Getting started" || started.ID != "getting-started" {
+		t.Errorf("wrong entry: %+v", started)
+	}
+	if len(started.Children) != 1 || started.Children[0].PlainText != "Install & run" {
+		t.Errorf("wrong children: %+v", started.Children)
+	}
+	if toc[1].ID != "reference" {
+		t.Errorf("wrong entry: %+v", toc[1])
+	}
+
+	// the renderer only keeps the headers of the last document
+	Markdown([]byte("## Skipped\n\n# Top\n"), renderer, 0)
+	toc = renderer.Toc()
+	if len(toc) != 2 || toc[0].Level != 2 || toc[0].ID != "" || toc[1].PlainText != "Top" {
+		t.Errorf("wrong tree for second document: %+v", toc)
+	}
+
+	// MarkdownToc gives the renderer's own outline, with its unique IDs
+	_, toc = MarkdownToc([]byte("# Same\n\n# Same\n"), renderer, Options{Extensions: EXTENSION_AUTO_HEADER_IDS})
+	if len(toc) != 2 || toc[0].ID != "same" || toc[1].ID != "same-1" {
+		t.Errorf("wrong tree from MarkdownToc: %+v", toc)
+	}
+}
+
+func TestTocOptions(t *testing.T) {
+	var tests = []string{
+		"# A\n\n## B\n\n### C\n",
+		"<nav class=\"toc\">\n<h2>Contents</h2>\n<ol>\n<li><a href=\"#toc_0\">A</a>\n<ol>\n<li><a href=\"#toc_1\">B</a></li>\n</ol></li>\n</ol>\n</nav>\n" +
+			"\n<h1 id=\"toc_0\">A</h1>\n\n<h2 id=\"toc_1\">B</h2>\n\n<h3 id=\"toc_2\">C</h3>\n",
+
+		"Intro\n\n[TOC]\n\n# A\n",
+		"<p>Intro</p>\n\n<nav class=\"toc\">\n<h2>Contents</h2>\n<ol>\n<li><a href=\"#toc_0\">A</a></li>\n</ol>\n</nav>\n" +
+			"\n<h1 id=\"toc_0\">A</h1>\n",
+
+		"Intro\n\n  [[_toc_]]\n\n# A\n",
+		"<p>Intro</p>\n\n<nav class=\"toc\">\n<h2>Contents</h2>\n<ol>\n<li><a href=\"#toc_0\">A</a></li>\n</ol>\n</nav>\n" +
+			"\n<h1 id=\"toc_0\">A</h1>\n",
+
+		"[TOC]\ntext\n\n> [TOC]\n",
+		"<nav class=\"toc\">\n<h2>Contents</h2>\n</nav>\n\n<p>[TOC]\ntext</p>\n\n<blockquote>\n<p>[TOC]</p>\n</blockquote>\n",
+	}
+	doTestsWithRenderer(t, tests, EXTENSION_TOC_MARKER, func() Renderer {
+		return HtmlRendererWithParameters(HTML_TOC|HTML_TOC_ORDERED, "", "",
+			HtmlRendererParameters{TocMaxLevel: 2, TocClass: "toc", TocTitle: "Contents"})
+	})
+
+	tests = []string{
+		"# A\n\n## B\n\n### C\n\n[TOC]\n",
+		"<h1 id=\"toc_0\">A</h1>\n\n<h2 id=\"toc_1\">B</h2>\n\n<h3 id=\"toc_2\">C</h3>\n" +
+			"\n<div>\n<ul>\n<li><a href=\"#toc_1\">B</a>\n<ul>\n<li><a href=\"#toc_2\">C</a></li>\n</ul></li>\n</ul>\n</div>\n",
+	}
+	doTestsWithRenderer(t, tests, EXTENSION_TOC_MARKER, func() Renderer {
+		return HtmlRendererWithParameters(HTML_TOC, "", "", HtmlRendererParameters{TocMinLevel: 2, TocElement: "div"})
+	})
+
+	// without HTML_TOC the marker is an ordinary paragraph
+	tests = []string{
+		"Intro\n\n[TOC]\n\n# A\n",
+		"<p>Intro</p>\n\n<p>[TOC]</p>\n\n<h1>A</h1>\n",
+	}
+	doTestsWithRenderer(t, tests, EXTENSION_TOC_MARKER, func() Renderer {
+		return HtmlRenderer(0, "", "")
+	})
+}
+
+func TestHeaderAttributes(t *testing.T) {
+	var tests = []string{
+		"# Header {#id .big .red}\n",
+		"<h1 id=\"id\" class=\"big red\">Header</h1>\n",
+
+		"# Header {.note}\n",
+		"<h1 class=\"note\">Header</h1>\n",
+
+		"# Header {-}\n",
+		"<h1 class=\"unnumbered\">Header</h1>\n",
+
+		"# Header {#a.b}\n",
+		"<h1 id=\"a.b\">Header</h1>\n",
+
+		"# The {.NET} runtime\n",
+		"<h1>The {.NET} runtime</h1>\n",
+
+		"# The {-} sign {.x}\n",
+		"<h1 class=\"x\">The {-} sign</h1>\n",
+
+		"# Header {.note} ##\n",
+		"<h1 class=\"note\">Header</h1>\n",
+
+		"# Header {#id .note}.\n",
+		"<h1>Header {#id .note}.</h1>\n",
+
+		"# Cost {-5}\n",
+		"<h1>Cost {-5}</h1>\n",
+
+		"# Version {.5}\n",
+		"<h1>Version {.5}</h1>\n",
+	}
+	doTestsBlock(t, tests, EXTENSION_HEADER_IDS)
+
+	// braces in the middle of a header are part of its text
+	if got := string(MarkdownCommon([]byte("# The {.NET} runtime\n"))); got != "<h1>The {.NET} runtime</h1>\n" {
+		t.Errorf("wrong output for braces in a header: %q", got)
+	}
+}
+
+func TestHeaderNumbering(t *testing.T) {
+	var tests = []string{
+		"# One\n\n## Two\n\n## Three {-}\n\n### Four\n\n# Five\n\n### Six\n",
+		"<h1><span class=\"header-section-number\">1</span> One</h1>\n\n" +
+			"<h2><span class=\"header-section-number\">1.1</span> Two</h2>\n\n" +
+			"<h2 class=\"unnumbered\">Three</h2>\n\n" +
+			"<h3><span class=\"header-section-number\">1.1.1</span> Four</h3>\n\n" +
+			"<h1><span class=\"header-section-number\">2</span> Five</h1>\n\n" +
+			"<h3><span class=\"header-section-number\">2.0.1</span> Six</h3>\n",
+	}
+	doTestsWithRenderer(t, tests, EXTENSION_HEADER_IDS, func() Renderer {
+		return HtmlRenderer(HTML_NUMBER_HEADERS, "", "")
+	})
+
+	tests = []string{
+		"# Title\n\n## One\n\n### Two\n",
+		"<nav>\n<ul>\n<li><a href=\"#toc_0\">Title</a>\n<ul>\n" +
+			"<li><a href=\"#toc_1\"><span class=\"header-section-number\">1</span> One</a>\n<ul>\n" +
+			"<li><a href=\"#toc_2\"><span class=\"header-section-number\">1-1</span> Two</a></li>\n</ul></li>\n</ul></li>\n</ul>\n</nav>\n" +
+			"\n<h1 id=\"toc_0\">Title</h1>\n\n" +
+			"<h2 id=\"toc_1\"><span class=\"header-section-number\">1</span> One</h2>\n\n" +
+			"<h3 id=\"toc_2\"><span class=\"header-section-number\">1-1</span> Two</h3>\n",
+	}
+	doTestsWithRenderer(t, tests, 0, func() Renderer {
+		return HtmlRendererWithParameters(HTML_TOC|HTML_NUMBER_HEADERS, "", "",
+			HtmlRendererParameters{HeaderNumberStart: 2, HeaderNumberSeparator: "-"})
+	})
+
+	renderer := HtmlRenderer(HTML_NUMBER_HEADERS, "", "").(*Html)
+	Markdown([]byte("# One\n\n## Two\n"), renderer, 0)
+	if toc := renderer.Toc(); toc[0].Children[0].Number != "1.1" || toc[0].Children[0].PlainText != "1.1 Two" {
+		t.Errorf("wrong numbered entry: %+v", toc[0].Children[0])
+	}
+}
+
+func TestHeaderPermalinks(t *testing.T) {
+	var tests = []string{
+		"# Header {#id}\n",
+		"<h1 id=\"id\">Header <a class=\"anchor\" href=\"#id\">#</a></h1>\n",
+
+		"# No ID\n",
+		"<h1>No ID</h1>\n",
+	}
+	doTestsWithRenderer(t, tests, EXTENSION_HEADER_IDS, func() Renderer {
+		return HtmlRenderer(HTML_HEADER_PERMALINKS, "", "")
+	})
+
+	tests = []string{
+		"# Header {#id}\n",
+		"<h1 id=\"id\"><a class=\"anchor\" href=\"#id\">&para;</a> <span class=\"header-section-number\">1</span> Header</h1>\n",
+	}
+	doTestsWithRenderer(t, tests, EXTENSION_HEADER_IDS, func() Renderer {
+		return HtmlRendererWithParameters(HTML_HEADER_PERMALINKS|HTML_PERMALINKS_BEFORE_TEXT|HTML_NUMBER_HEADERS, "", "",
+			HtmlRendererParameters{PermalinkContents: "&para;"})
+	})
+
+	renderer := HtmlRenderer(HTML_HEADER_PERMALINKS|HTML_TOC, "", "").(*Html)
+	doc := string(Markdown([]byte("# Header\n"), renderer, 0))
+	if toc := renderer.Toc(); toc[0].Text != "Header" {
+		t.Errorf("permalink in table of contents: %q", toc[0].Text)
+	}
+	if !strings.HasPrefix(doc, "<nav>\n<ul>\n<li><a href=\"#toc_0\">Header</a></li>\n</ul>\n</nav>\n") {
+		t.Errorf("permalink in table of contents:\n%s", doc)
+	}
+}
+
+func TestHeaderLevelShift(t *testing.T) {
+	var tests = []string{
+		"# One\n\n##### Five\n\nTwo\n---\n",
+		"<nav>\n<ul>\n<li><a href=\"#toc_0\">One</a>\n<ul>\n<li><a href=\"#toc_1\">Two</a></li>\n</ul></li>\n</ul>\n</nav>\n\n" +
+			"<h3 id=\"toc_0\">One</h3>\n\n<p><strong>Five</strong></p>\n\n<h4 id=\"toc_1\">Two</h4>\n",
+	}
+	doTestsWithRenderer(t, tests, 0, func() Renderer {
+		return HtmlRendererWithParameters(HTML_TOC|HTML_DEMOTE_DEEP_HEADERS, "", "",
+			HtmlRendererParameters{HeaderLevelShift: 2, TocMaxLevel: 2})
+	})
+
+	tests = []string{
+		"# One\n\n##### Five\n",
+		"<h3>One</h3>\n\n<h6>Five</h6>\n",
+
+		"# One {.big}\n",
+		"<h3 class=\"big\">One</h3>\n",
+	}
+	doTestsWithRenderer(t, tests, EXTENSION_HEADER_IDS, func() Renderer {
+		return HtmlRendererWithParameters(0, "", "", HtmlRendererParameters{HeaderLevelShift: 2})
+	})
+
+	tests = []string{
+		"## Two\n",
+		"<h1>Two</h1>\n",
+	}
+	doTestsWithRenderer(t, tests, 0, func() Renderer {
+		return HtmlRendererWithParameters(0, "", "", HtmlRendererParameters{HeaderLevelShift: -3})
+	})
+}
+
+func TestIDPrefix(t *testing.T) {
+	var tests = []string{
+		"# Header\n\nText[^a]\n\n[^a]: Note\n",
+		"<h1 id=\"readme-header\">Header</h1>\n\n" +
+			"<p>Text<sup class=\"footnote-ref\" id=\"fnref:readme-fn-a\"><a href=\"#fn:readme-fn-a\">1</a></sup></p>\n" +
+			"<div class=\"footnotes\">\n\n<hr>\n\n<ol>\n<li id=\"fn:readme-fn-a\">Note\n</li>\n</ol>\n</div>\n",
+	}
+	doTestsWithRenderer(t, tests, EXTENSION_AUTO_HEADER_IDS|EXTENSION_FOOTNOTES, func() Renderer {
+		return HtmlRendererWithParameters(0, "", "", HtmlRendererParameters{IDPrefix: "readme-", FootnoteAnchorPrefix: "fn-"})
+	})
+}
+
+func TestUnderlineHeaderAttributes(t *testing.T) {
+	var tests = []string{
+		"Header {#id .big}\n======\n",
+		"<h1 id=\"id\" class=\"big\">Header</h1>\n",
+
+		"Header {-}\n------\n",
+		"<h2 id=\"header\" class=\"unnumbered\">Header</h2>\n",
+
+		"Cost {-5}\n----\n",
+		"<h2 id=\"cost-5\">Cost {-5}</h2>\n",
+
+		"{#id}\n===\n",
+		"<h1 id=\"id\">{#id}</h1>\n",
+
+		"Why {.NET} matters\n===\n",
+		"<h1 id=\"why-net-matters\">Why {.NET} matters</h1>\n",
+
+		"Why {#net} matters\n===\n",
+		"<h1 id=\"why-net-matters\">Why {#net} matters</h1>\n",
+
+		"Header {#id}  \n===\n",
+		"<h1 id=\"id\">Header</h1>\n",
+	}
+	doTestsBlock(t, tests, EXTENSION_HEADER_IDS|EXTENSION_AUTO_HEADER_IDS)
+}
+
+func TestParseTitleBlock(t *testing.T) {
+	meta, ok := ParseTitleBlock([]byte("\n% Guide & more\r\n% Ann; Bob\n% 2020\n\nText\n"))
+	if !ok || meta.Title != "Guide & more" || len(meta.Authors) != 2 || meta.Authors[1] != "Bob" || meta.Date != "2020" {
+		t.Errorf("wrong title block: %+v, %v", meta, ok)
+	}
+	if meta, ok := ParseTitleBlock([]byte("Text\n% 50\n")); ok {
+		t.Errorf("unexpected title block: %+v", meta)
+	}
+}
+
+func TestTitleBlockCompletePage(t *testing.T) {
+	input := "% Guide & more\n% Ann; Bob\n% 2020\n\nText\n"
+	doc := string(Markdown([]byte(input), HtmlRenderer(HTML_COMPLETE_PAGE, "", ""), EXTENSION_TITLEBLOCK))
+	for _, want := range []string{
+		"  <title>Guide &amp; more</title>\n",
+		"  <meta name=\"author\" content=\"Ann\">\n  <meta name=\"author\" content=\"Bob\">\n",
+		"  <meta name=\"dcterms.date\" content=\"2020\">\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("page does not contain %q:\n%s", want, doc)
+		}
+	}
+
+	// the title given to the renderer wins
+	doc = string(Markdown([]byte(input), HtmlRenderer(HTML_COMPLETE_PAGE, "Site", ""), EXTENSION_TITLEBLOCK))
+	if !strings.Contains(doc, "<title>Site</title>") {
+		t.Errorf("wrong title:\n%s", doc)
+	}
+
+	// and the title block is only read with EXTENSION_TITLEBLOCK
+	doc = string(Markdown([]byte(input), HtmlRenderer(HTML_COMPLETE_PAGE, "", ""), 0))
+	if strings.Contains(doc, "author") {
+		t.Errorf("unexpected metadata:\n%s", doc)
+	}
+}
+
+func TestSections(t *testing.T) {
+	var tests = []string{
+		"Intro\n\n# A\n\nText\n\n## B {#b}\n\n### C\n\n## D\n\n# E\n",
+		"<p>Intro</p>\n\n<section>\n<h1>A</h1>\n\n<p>Text</p>\n\n<section id=\"b\">\n<h2>B</h2>\n\n" +
+			"<section>\n<h3>C</h3>\n</section>\n</section>\n\n<section>\n<h2>D</h2>\n</section>\n</section>\n\n" +
+			"<section>\n<h1>E</h1>\n</section>\n",
+
+		"## Up\n\n# Down\n",
+		"<section>\n<h2>Up</h2>\n</section>\n\n<section>\n<h1>Down</h1>\n</section>\n",
+
+		"# A\n\n> # Quoted\n\nText\n",
+		"<section>\n<h1>A</h1>\n\n<blockquote>\n<h1>Quoted</h1>\n</blockquote>\n\n<p>Text</p>\n</section>\n",
+
+		"# A\n\nText[^1]\n\n[^1]: Note\n",
+		"<section>\n<h1>A</h1>\n\n<p>Text<sup class=\"footnote-ref\" id=\"fnref:1\"><a href=\"#fn:1\">1</a></sup></p>\n</section>\n" +
+			"<div class=\"footnotes\">\n\n<hr>\n\n<ol>\n<li id=\"fn:1\">Note\n</li>\n</ol>\n</div>\n",
+	}
+	doTestsWithRenderer(t, tests, EXTENSION_HEADER_IDS|EXTENSION_FOOTNOTES, func() Renderer {
+		return HtmlRenderer(HTML_SECTIONS, "", "")
+	})
+
+	tests = []string{
+		"# A\n\n## B\n",
+		"<nav>\n<ul>\n<li><a href=\"#toc_0\">A</a>\n<ul>\n<li><a href=\"#toc_1\">B</a></li>\n</ul></li>\n</ul>\n</nav>\n\n" +
+			"<section id=\"toc_0\">\n<h1>A <a class=\"anchor\" href=\"#toc_0\">#</a></h1>\n\n" +
+			"<section id=\"toc_1\">\n<h2>B <a class=\"anchor\" href=\"#toc_1\">#</a></h2>\n</section>\n</section>\n",
+	}
+	doTestsWithRenderer(t, tests, 0, func() Renderer {
+		return HtmlRenderer(HTML_SECTIONS|HTML_TOC|HTML_HEADER_PERMALINKS, "", "")
+	})
+}
+
 func TestBlockComments(t *testing.T) {
 	var tests = []string{
 		"Some text\n\n<!-- comment -->\n",
@@ -0,0 +1,872 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Chat platform rendering backends (Slack, Telegram, Discord)
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Chat platforms supported by the Chat renderer.
+const (
+	chatSlack = iota
+	chatTelegram
+	chatDiscord
+)
+
+// Message length limits of the chat platforms, as counted by
+// SplitChatMessage. Slack accepts longer messages but recommends keeping
+// them below 4000 characters.
+const (
+	SLACK_MESSAGE_LIMIT    = 4000
+	TELEGRAM_MESSAGE_LIMIT = 4096
+	DISCORD_MESSAGE_LIMIT  = 2000
+)
+
+// While rendering, the Chat renderer keeps platform markup apart from text,
+// so that tables can be reduced to plain text and escaping can be applied
+// to the whole message at once in DocumentFooter. Markup is enclosed in
+// chatMarkup and chatMarkupEnd, code in chatCode and chatCodeEnd; anything
+// else is text. These characters are removed from the input.
+const (
+	chatMarkup    = "\uE000"
+	chatMarkupEnd = "\uE001"
+	chatCode      = "\uE002"
+	chatCodeEnd   = "\uE003"
+)
+
+var chatHRule = strings.Repeat("─", 10)
+
+// Inline markup of each platform, as opening and closing strings.
+var chatStyles = [...]struct {
+	emphasis, double, triple, strike, code [2]string
+}{
+	chatSlack: {
+		emphasis: [2]string{"_", "_"},
+		double:   [2]string{"*", "*"},
+		triple:   [2]string{"*_", "_*"},
+		strike:   [2]string{"~", "~"},
+		code:     [2]string{"`", "`"},
+	},
+	chatTelegram: {
+		emphasis: [2]string{"<i>", "</i>"},
+		double:   [2]string{"<b>", "</b>"},
+		triple:   [2]string{"<b><i>", "</i></b>"},
+		strike:   [2]string{"<s>", "</s>"},
+		code:     [2]string{"<code>", "</code>"},
+	},
+	chatDiscord: {
+		emphasis: [2]string{"*", "*"},
+		double:   [2]string{"**", "**"},
+		triple:   [2]string{"***", "***"},
+		strike:   [2]string{"~~", "~~"},
+		code:     [2]string{"`", "`"},
+	},
+}
+
+// Chat is a type that implements the Renderer interface for the restricted
+// markup of chat platforms: Slack mrkdwn, the HTML subset of the Telegram
+// Bot API and Discord Markdown. Constructs the platform lacks are
+// downgraded: headers become bold lines, images become links, tables
+// become code blocks and nested lists are flattened into indented lines.
+//
+// Do not create this directly, instead use the SlackRenderer,
+// TelegramRenderer or DiscordRenderer functions.
+type Chat struct {
+	flags    int
+	platform int
+
+	// start of the message in the output buffer
+	start int
+
+	// item counters of the enclosing lists, innermost last; 0 for
+	// unordered and definition lists
+	lists []int
+
+	footnoteCount int
+
+	// plain text cells of the table being rendered
+	tableCells      []string
+	tableRows       [][]string
+	tableHeaderRows int
+	tableIsHead     bool
+}
+
+// SlackRenderer creates and configures a Chat object that renders Slack
+// mrkdwn, which satisfies the Renderer interface.
+//
+// flags is a set of CHAT_* options ORed together (currently no such
+// options are defined).
+func SlackRenderer(flags int) Renderer {
+	return &Chat{flags: flags, platform: chatSlack}
+}
+
+// TelegramRenderer creates and configures a Chat object that renders the
+// HTML subset accepted by the Telegram Bot API (parse_mode HTML), which
+// satisfies the Renderer interface.
+//
+// flags is a set of CHAT_* options ORed together (currently no such
+// options are defined).
+func TelegramRenderer(flags int) Renderer {
+	return &Chat{flags: flags, platform: chatTelegram}
+}
+
+// DiscordRenderer creates and configures a Chat object that renders
+// Discord Markdown, which satisfies the Renderer interface.
+//
+// flags is a set of CHAT_* options ORed together (currently no such
+// options are defined).
+func DiscordRenderer(flags int) Renderer {
+	return &Chat{flags: flags, platform: chatDiscord}
+}
+
+func (options *Chat) GetFlags() int {
+	return options.flags
+}
+
+// Write s as platform markup.
+func chatWriteMarkup(out *bytes.Buffer, s string) {
+	if s == "" {
+		return
+	}
+	out.WriteString(chatMarkup)
+	out.WriteString(s)
+	out.WriteString(chatMarkupEnd)
+}
+
+// Write text as code, which is shown verbatim by the platform.
+func chatWriteCode(out *bytes.Buffer, text []byte) {
+	if len(text) == 0 {
+		return
+	}
+	out.WriteString(chatCode)
+	out.Write(chatStrip(text))
+	out.WriteString(chatCodeEnd)
+}
+
+// Remove the characters that delimit markup and code from input text.
+func chatStrip(text []byte) []byte {
+	if !bytes.Contains(text, []byte("\xee\x80")) {
+		return text
+	}
+	return bytes.Map(func(r rune) rune {
+		if r >= '\uE000' && r <= '\uE003' {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// chatSegments calls f on each piece of rendered text in turn, with kind
+// set to chatMarkup, chatCode or "" for text.
+func chatSegments(text []byte, f func(kind string, segment []byte)) {
+	for len(text) > 0 {
+		i := bytes.Index(text, []byte(chatMarkup))
+		kind, end := chatMarkup, chatMarkupEnd
+		if j := bytes.Index(text, []byte(chatCode)); j >= 0 && (i < 0 || j < i) {
+			i, kind, end = j, chatCode, chatCodeEnd
+		}
+		if i < 0 {
+			f("", text)
+			return
+		}
+		if i > 0 {
+			f("", text[:i])
+		}
+		text = text[i+len(kind):]
+		j := bytes.Index(text, []byte(end))
+		if j < 0 {
+			j = len(text)
+		}
+		f(kind, text[:j])
+		text = text[j:]
+		if len(text) > 0 {
+			text = text[len(end):]
+		}
+	}
+}
+
+// Reduce rendered text to what the reader sees, dropping the markup.
+func chatPlain(text []byte) string {
+	var buf bytes.Buffer
+	chatSegments(text, func(kind string, segment []byte) {
+		if kind != chatMarkup {
+			buf.Write(segment)
+		}
+	})
+	return buf.String()
+}
+
+// chatWritePrefixed writes rendered text to out, prefixing the first line
+// with first and every following line with rest, or rest without trailing
+// spaces for empty lines. The output ends with a newline.
+func chatWritePrefixed(out *bytes.Buffer, text []byte, first, rest string) {
+	blank := strings.TrimRight(rest, " ")
+	chatWriteMarkup(out, first)
+	chatSegments(bytes.TrimRight(text, "\n"), func(kind string, segment []byte) {
+		lines := bytes.Split(segment, []byte("\n"))
+		for i, line := range lines {
+			if i > 0 {
+				out.WriteByte('\n')
+				if len(line) == 0 && i+1 < len(lines) {
+					chatWriteMarkup(out, blank)
+				} else {
+					chatWriteMarkup(out, rest)
+				}
+			}
+			switch kind {
+			case chatMarkup:
+				chatWriteMarkup(out, string(line))
+			case chatCode:
+				chatWriteCode(out, line)
+			default:
+				out.Write(line)
+			}
+		}
+	})
+	out.WriteByte('\n')
+}
+
+func (options *Chat) style(out *bytes.Buffer, text []byte, style [2]string) {
+	chatWriteMarkup(out, style[0])
+	out.Write(text)
+	chatWriteMarkup(out, style[1])
+}
+
+func (options *Chat) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	blankLine(out)
+	lang := codeLanguage(info)
+	switch options.platform {
+	case chatTelegram:
+		if lang != "" {
+			chatWriteMarkup(out, `<pre><code class="language-`+html.EscapeString(lang)+`">`)
+			chatWriteCode(out, bytes.TrimRight(text, "\n"))
+			chatWriteMarkup(out, "</code></pre>")
+		} else {
+			chatWriteMarkup(out, "<pre>")
+			chatWriteCode(out, bytes.TrimRight(text, "\n"))
+			chatWriteMarkup(out, "</pre>")
+		}
+	default:
+		if options.platform == chatSlack || strings.ContainsAny(lang, "`\n") {
+			lang = ""
+		}
+		chatWriteMarkup(out, "```"+lang+"\n")
+		chatWriteCode(out, text)
+		if len(text) > 0 && text[len(text)-1] != '\n' {
+			out.WriteByte('\n')
+		}
+		chatWriteMarkup(out, "```")
+	}
+	out.WriteByte('\n')
+}
+
+func (options *Chat) TitleBlock(out *bytes.Buffer, text []byte) {
+	title, authors, date := titleBlockFields(text)
+	style := chatStyles[options.platform]
+	blankLine(out)
+	options.style(out, chatStrip([]byte(title)), style.double)
+	out.WriteByte('\n')
+	if len(authors) > 0 {
+		options.style(out, chatStrip([]byte(strings.Join(authors, "; "))), style.emphasis)
+		out.WriteByte('\n')
+	}
+	if date != "" {
+		options.style(out, chatStrip([]byte(date)), style.emphasis)
+		out.WriteByte('\n')
+	}
+}
+
+func (options *Chat) BlockQuote(out *bytes.Buffer, text []byte) {
+	blankLine(out)
+	if options.platform == chatTelegram {
+		chatWriteMarkup(out, "<blockquote>")
+		out.Write(bytes.TrimRight(text, "\n"))
+		chatWriteMarkup(out, "</blockquote>")
+		out.WriteByte('\n')
+		return
+	}
+	chatWritePrefixed(out, text, "> ", "> ")
+}
+
+func (options *Chat) BlockHtml(out *bytes.Buffer, text []byte) {
+	options.BlockCode(out, text, "html")
+}
+
+func (options *Chat) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	marker := out.Len()
+	blankLine(out)
+	style := chatStyles[options.platform].double
+	if options.platform == chatDiscord && level <= 3 {
+		style = [2]string{strings.Repeat("#", level) + " ", ""}
+	}
+	chatWriteMarkup(out, style[0])
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	chatWriteMarkup(out, style[1])
+	out.WriteByte('\n')
+}
+
+func (options *Chat) HRule(out *bytes.Buffer) {
+	blankLine(out)
+	chatWriteMarkup(out, chatHRule)
+	out.WriteByte('\n')
+}
+
+func (options *Chat) List(out *bytes.Buffer, text func() bool, flags int) {
+	marker := out.Len()
+	if len(options.lists) == 0 {
+		blankLine(out)
+	} else {
+		startLine(out)
+	}
+	counter := 0
+	if flags&LIST_TYPE_ORDERED != 0 {
+		counter = 1
+	}
+	options.lists = append(options.lists, counter)
+	ok := text()
+	options.lists = options.lists[:len(options.lists)-1]
+	if !ok {
+		out.Truncate(marker)
+	}
+}
+
+func (options *Chat) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	startLine(out)
+	if flags&LIST_TYPE_TERM != 0 {
+		options.style(out, bytes.TrimSpace(text), chatStyles[options.platform].double)
+		out.WriteByte('\n')
+		return
+	}
+	if flags&LIST_TYPE_DEFINITION != 0 {
+		chatWritePrefixed(out, text, "    ", "    ")
+		return
+	}
+
+	var itemMarker string
+	depth := len(options.lists) - 1
+	switch {
+	case depth < 0:
+		itemMarker = "• "
+	case options.lists[depth] > 0:
+		itemMarker = strconv.Itoa(options.lists[depth]) + ". "
+		options.lists[depth]++
+	case options.platform == chatDiscord:
+		itemMarker = "- "
+	case depth == 0:
+		itemMarker = "• "
+	default:
+		itemMarker = "◦ "
+	}
+	indent := strings.Repeat(" ", utf8.RuneCountInString(itemMarker))
+	chatWritePrefixed(out, text, itemMarker, indent)
+}
+
+func (options *Chat) Paragraph(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	blankLine(out)
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteByte('\n')
+}
+
+func (options *Chat) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	rows, headerRows := options.tableRows, options.tableHeaderRows
+	options.tableRows, options.tableHeaderRows = nil, 0
+
+	widths := make([]int, len(columnData))
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) && utf8.RuneCountInString(cell) > widths[i] {
+				widths[i] = utf8.RuneCountInString(cell)
+			}
+		}
+	}
+
+	var grid bytes.Buffer
+	for r, row := range rows {
+		var line bytes.Buffer
+		for i := range widths {
+			if i > 0 {
+				line.WriteString(" | ")
+			}
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			padCell(&line, cell, widths[i], columnData[i]&(TABLE_ALIGNMENT_LEFT|TABLE_ALIGNMENT_RIGHT))
+		}
+		grid.Write(bytes.TrimRight(line.Bytes(), " "))
+		grid.WriteByte('\n')
+		if r+1 == headerRows {
+			for i, width := range widths {
+				if i > 0 {
+					grid.WriteString("-|-")
+				}
+				grid.WriteString(strings.Repeat("-", width))
+			}
+			grid.WriteByte('\n')
+		}
+	}
+	options.BlockCode(out, grid.Bytes(), "")
+}
+
+func (options *Chat) TableRow(out *bytes.Buffer, text []byte) {
+	options.tableRows = append(options.tableRows, options.tableCells)
+	if options.tableIsHead {
+		options.tableHeaderRows = len(options.tableRows)
+	}
+	options.tableCells = nil
+	options.tableIsHead = false
+}
+
+func (options *Chat) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
+	options.TableCell(out, text, align)
+	options.tableIsHead = true
+}
+
+func (options *Chat) TableCell(out *bytes.Buffer, text []byte, align int) {
+	options.tableCells = append(options.tableCells, tableCellText([]byte(chatPlain(text))))
+}
+
+func (options *Chat) Footnotes(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	blankLine(out)
+	chatWriteMarkup(out, chatHRule)
+	out.WriteByte('\n')
+	options.footnoteCount = 0
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+}
+
+func (options *Chat) FootnoteItem(out *bytes.Buffer, name, text []byte, flags int) {
+	options.footnoteCount++
+	startLine(out)
+	chatWritePrefixed(out, text, "["+strconv.Itoa(options.footnoteCount)+"] ", "    ")
+}
+
+// Links to anchors and relative paths lead nowhere once posted.
+func chatLinkable(link []byte) bool {
+	return len(link) > 0 && link[0] != '#' && !isLocalPath(link)
+}
+
+// Write content as a link to href; content is rendered text.
+func (options *Chat) link(out *bytes.Buffer, href string, content []byte) {
+	switch options.platform {
+	case chatSlack:
+		var target bytes.Buffer
+		slackEscape(&target, []byte(href))
+		chatWriteMarkup(out, "<"+strings.Replace(target.String(), "|", "%7C", -1)+"|")
+		out.Write(content)
+		chatWriteMarkup(out, ">")
+	case chatTelegram:
+		chatWriteMarkup(out, `<a href="`+html.EscapeString(href)+`">`)
+		out.Write(content)
+		chatWriteMarkup(out, "</a>")
+	case chatDiscord:
+		href = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(href)
+		chatWriteMarkup(out, "[")
+		out.Write(content)
+		chatWriteMarkup(out, "]("+href+")")
+	}
+}
+
+func (options *Chat) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	text := chatStrip(link)
+	href := string(text)
+	if kind == LINK_TYPE_EMAIL {
+		if !bytes.HasPrefix(text, []byte("mailto:")) {
+			href = "mailto:" + href
+		}
+		text = bytes.TrimPrefix(text, []byte("mailto:"))
+	}
+	switch {
+	case options.platform == chatDiscord && kind == LINK_TYPE_EMAIL:
+		// Discord shows addresses as they are
+		out.Write(text)
+	case options.platform == chatDiscord:
+		// Discord links bare URLs by itself; the brackets suppress the preview
+		chatWriteMarkup(out, "<"+href+">")
+	default:
+		options.link(out, href, text)
+	}
+}
+
+func (options *Chat) CodeSpan(out *bytes.Buffer, text []byte) {
+	style := chatStyles[options.platform].code
+	if options.platform == chatDiscord && bytes.IndexByte(text, '`') >= 0 {
+		style = [2]string{"`` ", " ``"}
+	}
+	chatWriteMarkup(out, style[0])
+	chatWriteCode(out, text)
+	chatWriteMarkup(out, style[1])
+}
+
+func (options *Chat) DoubleEmphasis(out *bytes.Buffer, text []byte) {
+	options.style(out, text, chatStyles[options.platform].double)
+}
+
+func (options *Chat) Emphasis(out *bytes.Buffer, text []byte) {
+	options.style(out, text, chatStyles[options.platform].emphasis)
+}
+
+func (options *Chat) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	alt = chatStrip(alt)
+	if !chatLinkable(link) {
+		out.Write(alt)
+		return
+	}
+	if len(alt) == 0 {
+		alt = chatStrip(link)
+	}
+	options.link(out, string(chatStrip(link)), alt)
+}
+
+func (options *Chat) LineBreak(out *bytes.Buffer) {
+	out.WriteByte('\n')
+}
+
+func (options *Chat) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if !chatLinkable(link) {
+		out.Write(content)
+		return
+	}
+	options.link(out, string(chatStrip(link)), content)
+}
+
+func (options *Chat) RawHtmlTag(out *bytes.Buffer, tag []byte) {
+}
+
+func (options *Chat) TripleEmphasis(out *bytes.Buffer, text []byte) {
+	options.style(out, text, chatStyles[options.platform].triple)
+}
+
+func (options *Chat) StrikeThrough(out *bytes.Buffer, text []byte) {
+	options.style(out, text, chatStyles[options.platform].strike)
+}
+
+func (options *Chat) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
+	out.WriteString("[" + strconv.Itoa(id) + "]")
+}
+
+func (options *Chat) Entity(out *bytes.Buffer, entity []byte) {
+	options.NormalText(out, []byte(html.UnescapeString(string(entity))))
+}
+
+func (options *Chat) NormalText(out *bytes.Buffer, text []byte) {
+	out.Write(chatStrip(text))
+}
+
+func (options *Chat) DocumentHeader(out *bytes.Buffer) {
+	options.start = out.Len()
+	options.lists = nil
+	options.footnoteCount = 0
+	options.tableCells, options.tableRows = nil, nil
+	options.tableHeaderRows, options.tableIsHead = 0, false
+}
+
+func (options *Chat) DocumentFooter(out *bytes.Buffer) {
+	text := append([]byte(nil), out.Bytes()[options.start:]...)
+	out.Truncate(options.start)
+	text = bytes.TrimLeft(bytes.TrimRight(text, " \n"), "\n")
+	chatSegments(text, func(kind string, segment []byte) {
+		switch kind {
+		case chatMarkup:
+			out.Write(segment)
+		case chatCode:
+			options.escapeCode(out, segment)
+		default:
+			options.escapeText(out, segment)
+		}
+	})
+}
+
+func slackEscape(out *bytes.Buffer, text []byte) {
+	for _, c := range text {
+		switch c {
+		case '&':
+			out.WriteString("&amp;")
+		case '<':
+			out.WriteString("&lt;")
+		case '>':
+			out.WriteString("&gt;")
+		default:
+			out.WriteByte(c)
+		}
+	}
+}
+
+func (options *Chat) escapeCode(out *bytes.Buffer, text []byte) {
+	switch options.platform {
+	case chatTelegram:
+		attrEscape(out, text)
+		return
+	case chatSlack:
+		var buf bytes.Buffer
+		slackEscape(&buf, text)
+		text = buf.Bytes()
+	}
+	// a zero-width space keeps code from closing its block
+	out.Write(bytes.Replace(text, []byte("```"), []byte("``\u200b`"), -1))
+}
+
+func (options *Chat) escapeText(out *bytes.Buffer, text []byte) {
+	switch options.platform {
+	case chatSlack:
+		// Slack has no way to escape its formatting characters
+		slackEscape(out, text)
+	case chatTelegram:
+		attrEscape(out, text)
+	case chatDiscord:
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+			lineStart := out.Len() == options.start || out.Bytes()[out.Len()-1] == '\n'
+			switch {
+			case bytes.IndexByte([]byte("\\*_~`|<>[]"), c) >= 0:
+				out.WriteByte('\\')
+			case lineStart && (c == '#' || c == '-' || c == '+'):
+				out.WriteByte('\\')
+			case lineStart && isdigit(c):
+				// keep a line like "1. text" from turning into a list
+				j := i
+				for j < len(text) && isdigit(text[j]) {
+					j++
+				}
+				out.Write(text[i:j])
+				if j < len(text) && (text[j] == '.' || text[j] == ')') {
+					out.WriteByte('\\')
+				}
+				i = j - 1
+				continue
+			}
+			out.WriteByte(c)
+		}
+	}
+}
+
+// SplitChatMessage splits a message rendered by SlackRenderer,
+// TelegramRenderer or DiscordRenderer into parts that each fit within limit
+// characters, as the platform counts them, so that they can be posted one
+// after the other. A limit of 0 selects the platform's own limit.
+//
+// Messages are split between paragraphs where possible, then between lines
+// and finally between words. Code blocks and Telegram tags that are open at
+// a split are closed at the end of one part and reopened in the next.
+func SplitChatMessage(renderer Renderer, message []byte, limit int) [][]byte {
+	chat, ok := renderer.(*Chat)
+	if !ok {
+		return [][]byte{message}
+	}
+	telegram := chat.platform == chatTelegram
+	if limit <= 0 {
+		limit = [...]int{
+			chatSlack:    SLACK_MESSAGE_LIMIT,
+			chatTelegram: TELEGRAM_MESSAGE_LIMIT,
+			chatDiscord:  DISCORD_MESSAGE_LIMIT,
+		}[chat.platform]
+	}
+
+	tokens := chatTokens(message, telegram)
+	budget := limit
+	if !telegram {
+		// room to close a code block
+		budget -= len("\n```")
+	}
+
+	var parts [][]byte
+	var state chatSplitState
+	for i := 0; i < len(tokens); {
+		if state.fence == "" {
+			for i < len(tokens) && tokens[i].text[0] == '\n' {
+				i++
+			}
+			if i == len(tokens) {
+				break
+			}
+		}
+		prefix := state.reopen()
+		length := utf8.RuneCountInString(prefix)
+		if telegram {
+			length = 0
+		}
+
+		// candidate places to split: between paragraphs, lines and words
+		var splits [3]struct {
+			at, length int
+			state      chatSplitState
+		}
+		current := state.clone()
+		j := i
+		for ; j < len(tokens) && length+tokens[j].length <= budget; j++ {
+			current.update(message, tokens[j], telegram)
+			length += tokens[j].length
+			kind := -1
+			switch tokens[j].text[0] {
+			case '\n':
+				kind = 1
+				if j > i && tokens[j-1].text[0] == '\n' {
+					kind = 0
+				}
+			case ' ':
+				kind = 2
+			}
+			if kind >= 0 {
+				splits[kind].at, splits[kind].length = j+1, length
+				splits[kind].state = current.clone()
+			}
+		}
+
+		end, next := j, current
+		if j < len(tokens) {
+			for kind := range splits {
+				// a split close to the start would waste most of the part
+				if splits[kind].at > i && (kind == 2 || splits[kind].length >= budget/2) {
+					end, next = splits[kind].at, splits[kind].state
+					break
+				}
+			}
+			if end == i {
+				end++
+				next.update(message, tokens[i], telegram)
+			}
+		}
+
+		var part bytes.Buffer
+		part.WriteString(prefix)
+		for _, token := range tokens[i:end] {
+			part.Write(token.text)
+		}
+		text := part.Bytes()
+		if next.fence == "" {
+			text = bytes.TrimRight(text, " \n")
+		} else {
+			text = append(bytes.TrimRight(text, "\n"), "\n```"...)
+		}
+		text = append(text, next.close()...)
+		parts = append(parts, text)
+		i, state = end, next
+	}
+	return parts
+}
+
+type chatToken struct {
+	offset int
+	text   []byte
+	length int
+}
+
+// Break a message into characters, except that Telegram tags and entities
+// are kept whole. Telegram counts message length in UTF-16 code units,
+// without the markup.
+func chatTokens(message []byte, telegram bool) []chatToken {
+	var tokens []chatToken
+	for i := 0; i < len(message); {
+		size, length := 0, 1
+		if telegram && (message[i] == '<' || message[i] == '&') {
+			end := byte('>')
+			if message[i] == '&' {
+				end = ';'
+			} else {
+				length = 0
+			}
+			if j := bytes.IndexByte(message[i:], end); j > 0 {
+				size = j + 1
+			}
+		}
+		if size == 0 {
+			var r rune
+			r, size = utf8.DecodeRune(message[i:])
+			if telegram && r > 0xFFFF {
+				length = 2
+			}
+		}
+		tokens = append(tokens, chatToken{i, message[i : i+size], length})
+		i += size
+	}
+	return tokens
+}
+
+// What is open at a place in a chat message: a code block, given by its
+// opening line, or Telegram tags.
+type chatSplitState struct {
+	fence string
+	tags  []string
+}
+
+func (s chatSplitState) clone() chatSplitState {
+	s.tags = append([]string(nil), s.tags...)
+	return s
+}
+
+func (s *chatSplitState) update(message []byte, token chatToken, telegram bool) {
+	switch {
+	case telegram && bytes.HasPrefix(token.text, []byte("</")):
+		name := chatTagName(token.text)
+		for i := len(s.tags) - 1; i >= 0; i-- {
+			if chatTagName([]byte(s.tags[i])) == name {
+				s.tags = append(s.tags[:i], s.tags[i+1:]...)
+				break
+			}
+		}
+	case telegram && token.text[0] == '<':
+		s.tags = append(s.tags, string(token.text))
+	case !telegram && (token.offset == 0 || message[token.offset-1] == '\n') &&
+		bytes.HasPrefix(message[token.offset:], []byte("```")):
+		if s.fence != "" {
+			s.fence = ""
+			break
+		}
+		line := message[token.offset:]
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		s.fence = string(line)
+	}
+}
+
+func (s chatSplitState) reopen() string {
+	if s.fence != "" {
+		return s.fence + "\n"
+	}
+	return strings.Join(s.tags, "")
+}
+
+func (s chatSplitState) close() string {
+	var buf bytes.Buffer
+	for i := len(s.tags) - 1; i >= 0; i-- {
+		buf.WriteString("</" + chatTagName([]byte(s.tags[i])) + ">")
+	}
+	return buf.String()
+}
+
+func chatTagName(tag []byte) string {
+	tag = bytes.TrimLeft(tag, "</")
+	end := bytes.IndexAny(tag, " >")
+	if end < 0 {
+		end = len(tag)
+	}
+	return string(tag[:end])
+}
@@ -0,0 +1,189 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the chat platform renderers
+//
+
+package blackfriday
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const chatExtensions = EXTENSION_FENCED_CODE | EXTENSION_TABLES | EXTENSION_STRIKETHROUGH |
+	EXTENSION_AUTOLINK | EXTENSION_FOOTNOTES | EXTENSION_DEFINITION_LISTS
+
+func TestSlack(t *testing.T) {
+	var tests = []string{
+		"# Header *one*\n\nSome *em*, **strong**, ***both*** and ~~gone~~.\n",
+		"*Header _one_*\n\nSome _em_, *strong*, *_both_* and ~gone~.",
+
+		"Fish & <chips> `a<b`\n",
+		"Fish &amp;  `a&lt;b`",
+
+		"[site](http://example.com/?a=1&b=2) and [local](#top)\n",
+		"<http://example.com/?a=1&amp;b=2|site> and local",
+
+		"![a cat](http://example.com/cat.png) ![](http://example.com/dog.png) ![pic](pic.png)\n",
+		"<http://example.com/cat.png|a cat> <http://example.com/dog.png|http://example.com/dog.png> pic",
+
+		"Mail <me@example.com> or see http://example.com/x\n",
+		"Mail <mailto:me@example.com|me@example.com> or see <http://example.com/x|http://example.com/x>",
+
+		"> quoted\n>\n> more\n",
+		"> quoted\n>\n> more",
+
+		"* one\n* two\n    * nested\n        1. deep\n* three\n",
+		"• one\n• two\n  ◦ nested\n    1. deep\n• three",
+
+		"```go\nx := \"```\" + a&b\n```\n",
+		"```\nx := \"``\u200b`\" + a&amp;b\n```",
+
+		"Term\n:   Definition\n",
+		"*Term*\n    Definition",
+
+		"one\n\n***\n\ntwo\n",
+		"one\n\n──────────\n\ntwo",
+	}
+	doTestsWithRenderer(t, tests, chatExtensions, func() Renderer {
+		return SlackRenderer(0)
+	})
+}
+
+func TestTelegram(t *testing.T) {
+	var tests = []string{
+		"# Header *one*\n\nSome *em*, **strong**, ***both*** and ~~gone~~.\n",
+		"<b>Header <i>one</i></b>\n\nSome <i>em</i>, <b>strong</b>, <b><i>both</i></b> and <s>gone</s>.",
+
+		"Fish & \"chips\" `a<b`\n",
+		"Fish &amp; &quot;chips&quot; <code>a&lt;b</code>",
+
+		"[site](http://example.com/?a=1&b=2 \"Title\")\n",
+		"<a href=\"http://example.com/?a=1&amp;b=2\">site</a>",
+
+		"![a cat](http://example.com/cat.png)\n",
+		"<a href=\"http://example.com/cat.png\">a cat</a>",
+
+		"> quoted\n>\n> more\n",
+		"<blockquote>quoted\n\nmore</blockquote>",
+
+		"* one\n    * nested\n* two\n",
+		"• one\n  ◦ nested\n• two",
+
+		"```go\nif a < b {}\n```\n\n    plain\n",
+		"<pre><code class=\"language-go\">if a &lt; b {}</code></pre>\n\n<pre>plain</pre>",
+
+		"Text<span>raw</span>.\n",
+		"Textraw.",
+	}
+	doTestsWithRenderer(t, tests, chatExtensions, func() Renderer {
+		return TelegramRenderer(0)
+	})
+}
+
+func TestDiscord(t *testing.T) {
+	var tests = []string{
+		"# One\n\n### Three\n\n#### Four\n",
+		"# One\n\n### Three\n\n**Four**",
+
+		"Some *em*, **strong**, ***both*** and ~~gone~~.\n",
+		"Some *em*, **strong**, ***both*** and ~~gone~~.",
+
+		"Not \\*em\\*, 2 \\< 3, snake\\_case and ``a`b``.\n",
+		"Not \\*em\\*, 2 \\< 3, snake\\_case and `` a`b ``.",
+
+		"\\# not a header\n\n1986\\. A year.\n\n\\- not a list\n",
+		"\\# not a header\n\n1986\\. A year.\n\n\\- not a list",
+
+		"[site](http://example.com/a_(b)) and http://example.com/x_y\n",
+		"[site](http://example.com/a_%28b%29) and <http://example.com/x_y>",
+
+		"* one\n* two\n    1. nested\n    2. again\n",
+		"- one\n- two\n  1. nested\n  2. again",
+
+		"```go\nfunc main() {}\n```\n",
+		"```go\nfunc main() {}\n```",
+
+		"Text[^1]\n\n[^1]: A *note*.\n",
+		"Text\\[1\\]\n\n──────────\n[1] A *note*.",
+	}
+	doTestsWithRenderer(t, tests, chatExtensions, func() Renderer {
+		return DiscordRenderer(0)
+	})
+}
+
+func TestChatTables(t *testing.T) {
+	input := "| Name | Qty |\n|:-----|----:|\n| *apple* | `1` |\n| kiwi & co | 12 |\n"
+	var tests = []struct {
+		renderer Renderer
+		expected string
+	}{
+		{SlackRenderer(0), "```\nName      | Qty\n----------|----\napple     |   1\nkiwi &amp; co |  12\n```"},
+		{TelegramRenderer(0), "<pre>Name      | Qty\n----------|----\napple     |   1\nkiwi &amp; co |  12</pre>"},
+		{DiscordRenderer(0), "```\nName      | Qty\n----------|----\napple     |   1\nkiwi & co |  12\n```"},
+	}
+	for _, test := range tests {
+		actual := string(Markdown([]byte(input), test.renderer, chatExtensions))
+		if actual != test.expected {
+			t.Errorf("\nExpected[%#v]\nActual  [%#v]", test.expected, actual)
+		}
+	}
+}
+
+func TestSplitChatMessage(t *testing.T) {
+	var paragraphs []string
+	for i := 0; i < 20; i++ {
+		paragraphs = append(paragraphs, strings.Repeat("word ", 15))
+	}
+	input := strings.Join(paragraphs, "\n\n") + "\n\n```\n" + strings.Repeat("line of code\n", 30) + "```\n"
+
+	for _, renderer := range []Renderer{SlackRenderer(0), DiscordRenderer(0)} {
+		message := Markdown([]byte(input), renderer, chatExtensions)
+		parts := SplitChatMessage(renderer, message, 200)
+		if len(parts) < 2 {
+			t.Fatalf("expected several parts, got %d", len(parts))
+		}
+		for i, part := range parts {
+			if n := utf8.RuneCount(part); n > 200 {
+				t.Errorf("part %d has %d characters", i, n)
+			}
+			if strings.Count(string(part), "```")%2 != 0 {
+				t.Errorf("part %d has an unbalanced code block:\n%s", i, part)
+			}
+			if strings.HasPrefix(string(part), "word") && !strings.HasSuffix(string(part), "word") {
+				t.Errorf("part %d is not split between paragraphs:\n%s", i, part)
+			}
+		}
+	}
+
+	renderer := TelegramRenderer(0)
+	message := Markdown([]byte("**"+strings.Repeat("bold & brave ", 40)+"end**\n"), renderer, 0)
+	parts := SplitChatMessage(renderer, message, 100)
+	if len(parts) < 5 {
+		t.Fatalf("expected several parts, got %d", len(parts))
+	}
+	for i, part := range parts {
+		s := string(part)
+		if !strings.HasPrefix(s, "<b>") || !strings.HasSuffix(s, "</b>") {
+			t.Errorf("part %d does not keep its tags balanced: %s", i, s)
+		}
+		// tags do not count, and each entity counts as one character
+		s = strings.NewReplacer("<b>", "", "</b>", "", "&amp;", "&").Replace(s)
+		if n := utf8.RuneCountInString(s); n > 100 {
+			t.Errorf("part %d has %d characters", i, n)
+		}
+	}
+
+	if parts := SplitChatMessage(renderer, []byte("short"), 0); len(parts) != 1 || string(parts[0]) != "short" {
+		t.Errorf("short message was split: %q", parts)
+	}
+}
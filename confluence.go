@@ -0,0 +1,412 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Confluence storage format rendering backend
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"html"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A block quote that starts with a GitHub-style alert ("[!NOTE]") or a
+// bold label ("**Warning:**") becomes one of Confluence's panel macros.
+var confluencePanelRe = regexp.MustCompile(
+	`^<p>(?:\[!(?i:(note|tip|important|warning|caution))\]|` +
+		`<strong>(?i:(note|tip|info|important|warning|caution)):?</strong>:?)\s*`)
+
+// Confluence and Jira panel macros for each kind of admonition.
+var panelMacros = map[string]string{
+	"note":      "info",
+	"info":      "info",
+	"important": "info",
+	"tip":       "tip",
+	"warning":   "note",
+	"caution":   "warning",
+}
+
+// Confluence is a type that implements the Renderer interface for the
+// Confluence storage format, the XHTML-based markup Confluence keeps pages
+// in. The output is the body of a page, as used by the Confluence REST API.
+//
+// Do not create this directly, instead use the ConfluenceRenderer function.
+type Confluence struct {
+	flags int
+
+	// Track anchors to keep them unique within the page.
+	ids map[string]int
+}
+
+// ConfluenceRenderer creates and configures a Confluence object, which
+// satisfies the Renderer interface.
+//
+// flags is a set of CONFLUENCE_* options ORed together (currently no such
+// options are defined).
+func ConfluenceRenderer(flags int) Renderer {
+	return &Confluence{
+		flags: flags,
+		ids:   make(map[string]int),
+	}
+}
+
+func (options *Confluence) GetFlags() int {
+	return options.flags
+}
+
+// Write text as a CDATA section, leaving out the characters XML forbids.
+func cdata(out *bytes.Buffer, text []byte) {
+	text = bytes.Map(func(r rune) rune {
+		if r == utf8.RuneError || (r < ' ' && r != '\t' && r != '\n' && r != '\r') {
+			return -1
+		}
+		return r
+	}, text)
+	out.WriteString("<![CDATA[")
+	out.Write(bytes.Replace(text, []byte("]]>"), []byte("]]]]><![CDATA[>"), -1))
+	out.WriteString("]]>")
+}
+
+func confluenceCodeMacro(out *bytes.Buffer, text []byte, lang string) {
+	startLine(out)
+	out.WriteString(`<ac:structured-macro ac:name="code">`)
+	if lang != "" {
+		out.WriteString(`<ac:parameter ac:name="language">`)
+		xmlEscape(out, []byte(lang))
+		out.WriteString(`</ac:parameter>`)
+	}
+	out.WriteString(`<ac:plain-text-body>`)
+	cdata(out, bytes.TrimRight(text, "\n"))
+	out.WriteString("</ac:plain-text-body></ac:structured-macro>\n")
+}
+
+func (options *Confluence) anchor(out *bytes.Buffer, id string) {
+	out.WriteString(`<ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">`)
+	xmlEscape(out, []byte(id))
+	out.WriteString(`</ac:parameter></ac:structured-macro>`)
+}
+
+func (options *Confluence) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	confluenceCodeMacro(out, text, codeLanguage(info))
+}
+
+func (options *Confluence) TitleBlock(out *bytes.Buffer, text []byte) {
+	title, authors, date := titleBlockFields(text)
+	startLine(out)
+	out.WriteString("<h1>")
+	options.NormalText(out, []byte(title))
+	out.WriteString("</h1>\n")
+	if len(authors) == 0 && date == "" {
+		return
+	}
+	out.WriteString("<p>")
+	options.NormalText(out, []byte(strings.Join(authors, "; ")))
+	if len(authors) > 0 && date != "" {
+		out.WriteString("<br />")
+	}
+	options.NormalText(out, []byte(date))
+	out.WriteString("</p>\n")
+}
+
+func (options *Confluence) BlockQuote(out *bytes.Buffer, text []byte) {
+	startLine(out)
+	m := confluencePanelRe.FindSubmatch(text)
+	if m == nil {
+		out.WriteString("<blockquote>\n")
+		out.Write(text)
+		startLine(out)
+		out.WriteString("</blockquote>\n")
+		return
+	}
+
+	kind := string(m[1]) + string(m[2])
+	out.WriteString(`<ac:structured-macro ac:name="`)
+	out.WriteString(panelMacros[strings.ToLower(kind)])
+	out.WriteString("\"><ac:rich-text-body>\n")
+	rest := text[len(m[0]):]
+	if bytes.HasPrefix(rest, []byte("</p>")) {
+		// the label had a paragraph of its own
+		rest = bytes.TrimLeft(rest[len("</p>"):], "\n")
+	} else {
+		out.WriteString("<p>")
+	}
+	out.Write(rest)
+	startLine(out)
+	out.WriteString("</ac:rich-text-body></ac:structured-macro>\n")
+}
+
+// Raw HTML is not valid storage format in general, so show it as code.
+func (options *Confluence) BlockHtml(out *bytes.Buffer, text []byte) {
+	confluenceCodeMacro(out, text, "html")
+}
+
+func (options *Confluence) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	marker := out.Len()
+	startLine(out)
+	out.WriteString("<h")
+	out.WriteString(strconv.Itoa(level))
+	out.WriteByte('>')
+	if id != "" {
+		options.anchor(out, uniqueID(options.ids, id))
+	}
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteString("</h")
+	out.WriteString(strconv.Itoa(level))
+	out.WriteString(">\n")
+}
+
+func (options *Confluence) HRule(out *bytes.Buffer) {
+	startLine(out)
+	out.WriteString("<hr />\n")
+}
+
+func (options *Confluence) List(out *bytes.Buffer, text func() bool, flags int) {
+	marker := out.Len()
+	startLine(out)
+
+	// Confluence has no definition lists; terms and definitions are
+	// written as paragraphs
+	tag := ""
+	if flags&LIST_TYPE_ORDERED != 0 {
+		tag = "ol"
+	} else if flags&LIST_TYPE_DEFINITION == 0 {
+		tag = "ul"
+	}
+	if tag != "" {
+		out.WriteString("<" + tag + ">\n")
+	}
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	if tag != "" {
+		out.WriteString("</" + tag + ">\n")
+	}
+}
+
+func (options *Confluence) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	text = bytes.TrimRight(text, "\n")
+	switch {
+	case flags&LIST_TYPE_TERM != 0:
+		out.WriteString("<p><strong>")
+		out.Write(bytes.TrimSpace(text))
+		out.WriteString("</strong></p>\n")
+
+	case flags&LIST_TYPE_DEFINITION != 0:
+		if flags&LIST_ITEM_CONTAINS_BLOCK != 0 {
+			out.WriteString("<blockquote>\n")
+			out.Write(text)
+			out.WriteString("\n</blockquote>\n")
+			return
+		}
+		out.WriteString(`<p style="margin-left: 30.0px;">`)
+		out.Write(bytes.TrimSpace(text))
+		out.WriteString("</p>\n")
+
+	default:
+		out.WriteString("<li>")
+		out.Write(text)
+		out.WriteString("</li>\n")
+	}
+}
+
+func (options *Confluence) Paragraph(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	startLine(out)
+	out.WriteString("<p>")
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteString("</p>\n")
+}
+
+func (options *Confluence) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	startLine(out)
+	out.WriteString("<table><tbody>\n")
+	out.Write(header)
+	out.Write(body)
+	out.WriteString("</tbody></table>\n")
+}
+
+func (options *Confluence) TableRow(out *bytes.Buffer, text []byte) {
+	out.WriteString("<tr>")
+	out.Write(text)
+	out.WriteString("</tr>\n")
+}
+
+func (options *Confluence) tableCell(out *bytes.Buffer, tag string, text []byte, align int) {
+	out.WriteString("<" + tag)
+	if align != 0 {
+		out.WriteString(` style="text-align: `)
+		out.WriteString(alignments[align-1])
+		out.WriteString(`;"`)
+	}
+	out.WriteByte('>')
+	out.Write(text)
+	out.WriteString("</" + tag + ">")
+}
+
+func (options *Confluence) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
+	options.tableCell(out, "th", text, align)
+}
+
+func (options *Confluence) TableCell(out *bytes.Buffer, text []byte, align int) {
+	options.tableCell(out, "td", text, align)
+}
+
+func (options *Confluence) Footnotes(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	startLine(out)
+	out.WriteString("<hr />\n<ol>\n")
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteString("</ol>\n")
+}
+
+func (options *Confluence) FootnoteItem(out *bytes.Buffer, name, text []byte, flags int) {
+	out.WriteString("<li>")
+	options.anchor(out, "fn-"+string(slugify(name)))
+	out.Write(bytes.TrimRight(text, "\n"))
+	out.WriteString("</li>\n")
+}
+
+func (options *Confluence) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	out.WriteString(`<a href="`)
+	if kind == LINK_TYPE_EMAIL && !bytes.HasPrefix(link, []byte("mailto:")) {
+		out.WriteString("mailto:")
+	}
+	xmlEscape(out, link)
+	out.WriteString(`">`)
+	xmlEscape(out, bytes.TrimPrefix(link, []byte("mailto:")))
+	out.WriteString("</a>")
+}
+
+func (options *Confluence) CodeSpan(out *bytes.Buffer, text []byte) {
+	out.WriteString("<code>")
+	xmlEscape(out, text)
+	out.WriteString("</code>")
+}
+
+func (options *Confluence) DoubleEmphasis(out *bytes.Buffer, text []byte) {
+	out.WriteString("<strong>")
+	out.Write(text)
+	out.WriteString("</strong>")
+}
+
+func (options *Confluence) Emphasis(out *bytes.Buffer, text []byte) {
+	if len(text) == 0 {
+		return
+	}
+	out.WriteString("<em>")
+	out.Write(text)
+	out.WriteString("</em>")
+}
+
+// Local images are expected to be attached to the page.
+func (options *Confluence) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	out.WriteString("<ac:image")
+	if len(alt) > 0 {
+		out.WriteString(` ac:alt="`)
+		xmlEscape(out, alt)
+		out.WriteByte('"')
+	}
+	if len(title) > 0 {
+		out.WriteString(` ac:title="`)
+		xmlEscape(out, title)
+		out.WriteByte('"')
+	}
+	out.WriteByte('>')
+	if isLocalPath(link) {
+		out.WriteString(`<ri:attachment ri:filename="`)
+		xmlEscape(out, []byte(path.Base(string(link))))
+	} else {
+		out.WriteString(`<ri:url ri:value="`)
+		xmlEscape(out, link)
+	}
+	out.WriteString(`" /></ac:image>`)
+}
+
+func (options *Confluence) LineBreak(out *bytes.Buffer) {
+	out.WriteString("<br />\n")
+}
+
+func (options *Confluence) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if len(link) > 1 && link[0] == '#' {
+		out.WriteString(`<ac:link ac:anchor="`)
+		xmlEscape(out, link[1:])
+		out.WriteString(`"><ac:link-body>`)
+		out.Write(content)
+		out.WriteString("</ac:link-body></ac:link>")
+		return
+	}
+	out.WriteString(`<a href="`)
+	xmlEscape(out, link)
+	if len(title) > 0 {
+		out.WriteString(`" title="`)
+		xmlEscape(out, title)
+	}
+	out.WriteString(`">`)
+	out.Write(content)
+	out.WriteString("</a>")
+}
+
+// Raw HTML is not valid storage format in general, so it is dropped.
+func (options *Confluence) RawHtmlTag(out *bytes.Buffer, tag []byte) {
+}
+
+func (options *Confluence) TripleEmphasis(out *bytes.Buffer, text []byte) {
+	out.WriteString("<strong><em>")
+	out.Write(text)
+	out.WriteString("</em></strong>")
+}
+
+func (options *Confluence) StrikeThrough(out *bytes.Buffer, text []byte) {
+	out.WriteString("<del>")
+	out.Write(text)
+	out.WriteString("</del>")
+}
+
+func (options *Confluence) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
+	out.WriteString(`<sup><ac:link ac:anchor="`)
+	xmlEscape(out, []byte("fn-"+string(slugify(ref))))
+	out.WriteString(`"><ac:plain-text-link-body>`)
+	cdata(out, []byte(strconv.Itoa(id)))
+	out.WriteString("</ac:plain-text-link-body></ac:link></sup>")
+}
+
+func (options *Confluence) Entity(out *bytes.Buffer, entity []byte) {
+	// the storage format only knows the XML entities
+	options.NormalText(out, []byte(html.UnescapeString(string(entity))))
+}
+
+func (options *Confluence) NormalText(out *bytes.Buffer, text []byte) {
+	xmlEscape(out, text)
+}
+
+func (options *Confluence) DocumentHeader(out *bytes.Buffer) {
+	options.ids = make(map[string]int)
+}
+
+func (options *Confluence) DocumentFooter(out *bytes.Buffer) {
+}
@@ -0,0 +1,42 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the Confluence renderer
+//
+
+package blackfriday
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfluenceReference(t *testing.T) {
+	doTestsReferenceWithRenderer(t, atlassianReferenceFiles, ".confluence", commonExtensions|EXTENSION_FOOTNOTES,
+		func() Renderer {
+			return ConfluenceRenderer(0)
+		})
+}
+
+func TestConfluenceWellFormed(t *testing.T) {
+	for _, basename := range atlassianReferenceFiles {
+		page, err := ioutil.ReadFile(filepath.Join("testdata", basename+".confluence"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the storage format is a fragment using the ac and ri prefixes
+		doc := `<page xmlns:ac="http://atlassian.com/content" xmlns:ri="http://atlassian.com/resource/identifier">` +
+			string(page) + `</page>`
+		if err := checkWellFormed(doc); err != nil {
+			t.Errorf("%s: %v", basename, err)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 // Package blackfriday is a Markdown processor.
 //
-// It translates plain text with simple formatting rules into HTML or LaTeX.
+// It translates plain text with simple formatting rules into HTML, LaTeX,
+// reStructuredText, AsciiDoc, DocBook, Word (.docx) or OpenDocument (.odt)
+// documents, Jira wiki markup or Confluence storage format pages. Epub
+// bundles several documents into an EPUB book, and the chat renderers
+// produce messages for Slack, Telegram and Discord.
 //
 // Sanitized Anchor Names
 //
@@ -0,0 +1,566 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// DocBook 5 rendering backend
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DocBook is a type that implements the Renderer interface for DocBook 5 XML
+// output. The document is an <article>, with headers opening nested
+// <section> elements.
+//
+// Do not create this directly, instead use the DocBookRenderer function.
+type DocBook struct {
+	flags int
+
+	// the top-level output buffer; headers written anywhere else (e.g. in a
+	// list item) cannot open a section and become bridgeheads instead
+	doc *bytes.Buffer
+
+	// currently open sections, outermost first
+	sections []docbookSection
+
+	// Track IDs to keep them unique within the document.
+	ids map[string]int
+
+	// footnotes already written out, which are only referenced from then on
+	footnotes map[string]string
+
+	// the targets of links to "#id", written as linkend until the end of
+	// the document shows whether some element has that ID
+	linkends map[string]string
+
+	// the most recent image, in case it turns out to be a block image
+	lastImage  []byte
+	blockImage []byte
+}
+
+type docbookSection struct {
+	level    int
+	titleEnd int // output position right after the <title>
+}
+
+// DocBookRenderer creates and configures a DocBook object, which
+// satisfies the Renderer interface.
+//
+// flags is a set of DOCBOOK_* options ORed together (currently no such
+// options are defined).
+func DocBookRenderer(flags int) Renderer {
+	return &DocBook{
+		flags:     flags,
+		ids:       make(map[string]int),
+		footnotes: make(map[string]string),
+		linkends:  make(map[string]string),
+	}
+}
+
+func (options *DocBook) GetFlags() int {
+	return options.flags
+}
+
+func (options *DocBook) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	startLine(out)
+	if lang := codeLanguage(info); lang != "" {
+		out.WriteString("<programlisting language=\"")
+		xmlEscape(out, []byte(lang))
+		out.WriteString("\">")
+	} else {
+		out.WriteString("<programlisting>")
+	}
+	xmlEscape(out, text)
+	out.WriteString("</programlisting>\n")
+}
+
+func (options *DocBook) TitleBlock(out *bytes.Buffer, text []byte) {
+	title, authors, date := titleBlockFields(text)
+	startLine(out)
+	out.WriteString("<info>\n<title>")
+	options.NormalText(out, []byte(title))
+	out.WriteString("</title>\n")
+	for _, author := range authors {
+		out.WriteString("<author><personname>")
+		options.NormalText(out, []byte(author))
+		out.WriteString("</personname></author>\n")
+	}
+	if date != "" {
+		out.WriteString("<date>")
+		options.NormalText(out, []byte(date))
+		out.WriteString("</date>\n")
+	}
+	out.WriteString("</info>\n")
+}
+
+func (options *DocBook) BlockQuote(out *bytes.Buffer, text []byte) {
+	startLine(out)
+	out.WriteString("<blockquote>\n")
+	out.Write(text)
+	startLine(out)
+	out.WriteString("</blockquote>\n")
+}
+
+// DocBook has no way to include HTML, so show it as a listing instead.
+func (options *DocBook) BlockHtml(out *bytes.Buffer, text []byte) {
+	startLine(out)
+	out.WriteString("<programlisting language=\"html\">")
+	xmlEscape(out, text)
+	out.WriteString("</programlisting>\n")
+}
+
+func (options *DocBook) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	marker := out.Len()
+	startLine(out)
+
+	if out != options.doc {
+		out.WriteString("<bridgehead renderas=\"sect")
+		out.WriteString(strconv.Itoa(level))
+		out.WriteByte('"')
+		options.writeID(out, id)
+		out.WriteByte('>')
+		if !text() {
+			out.Truncate(marker)
+			return
+		}
+		out.WriteString("</bridgehead>\n")
+		return
+	}
+
+	options.closeSections(out, level)
+	out.WriteString("<section")
+	options.writeID(out, id)
+	out.WriteString(">\n<title>")
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteString("</title>\n")
+	options.sections = append(options.sections, docbookSection{level, out.Len()})
+}
+
+func (options *DocBook) writeID(out *bytes.Buffer, id string) {
+	if id == "" {
+		return
+	}
+	out.WriteString(" xml:id=\"")
+	xmlEscape(out, []byte(uniqueID(options.ids, xmlID(id))))
+	out.WriteByte('"')
+}
+
+// close all open sections at level or deeper
+func (options *DocBook) closeSections(out *bytes.Buffer, level int) {
+	for len(options.sections) > 0 {
+		last := options.sections[len(options.sections)-1]
+		if last.level < level {
+			break
+		}
+		if out.Len() == last.titleEnd {
+			// a section needs some content
+			out.WriteString("<para/>\n")
+		}
+		out.WriteString("</section>\n")
+		options.sections = options.sections[:len(options.sections)-1]
+	}
+}
+
+// xmlID turns an arbitrary string into a valid XML name for use in xml:id.
+func xmlID(id string) string {
+	var out []rune
+	for i, r := range id {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		case i == 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+			out = append(out, '_')
+		default:
+			r = '-'
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// No equivalent of a horizontal rule exists in DocBook.
+func (options *DocBook) HRule(out *bytes.Buffer) {
+}
+
+func (options *DocBook) List(out *bytes.Buffer, text func() bool, flags int) {
+	marker := out.Len()
+	startLine(out)
+
+	if flags&LIST_TYPE_DEFINITION != 0 {
+		out.WriteString("<variablelist>\n")
+	} else if flags&LIST_TYPE_ORDERED != 0 {
+		out.WriteString("<orderedlist>\n")
+	} else {
+		out.WriteString("<itemizedlist>\n")
+	}
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	if flags&LIST_TYPE_DEFINITION != 0 {
+		out.WriteString("</varlistentry>\n</variablelist>\n")
+	} else if flags&LIST_TYPE_ORDERED != 0 {
+		out.WriteString("</orderedlist>\n")
+	} else {
+		out.WriteString("</itemizedlist>\n")
+	}
+}
+
+func (options *DocBook) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	switch {
+	case flags&LIST_TYPE_TERM != 0:
+		// a term after a definition starts a new entry
+		if bytes.HasSuffix(out.Bytes(), []byte("</listitem>\n")) {
+			out.WriteString("</varlistentry>\n")
+		}
+		if !bytes.HasSuffix(out.Bytes(), []byte("</term>\n")) {
+			out.WriteString("<varlistentry>\n")
+		}
+		out.WriteString("<term>")
+		out.Write(text)
+		out.WriteString("</term>\n")
+
+	case flags&LIST_TYPE_DEFINITION != 0:
+		// an entry has a single listitem, so further definitions join it
+		if bytes.HasSuffix(out.Bytes(), []byte("</listitem>\n")) {
+			out.Truncate(out.Len() - len("</listitem>\n"))
+		} else {
+			out.WriteString("<listitem>\n")
+		}
+		docbookItemContent(out, text)
+		out.WriteString("</listitem>\n")
+
+	default:
+		out.WriteString("<listitem>\n")
+		docbookItemContent(out, text)
+		out.WriteString("</listitem>\n")
+	}
+}
+
+// Write the contents of a list item, wrapping any leading inline text in a
+// paragraph.
+func docbookItemContent(out *bytes.Buffer, text []byte) {
+	if len(bytes.TrimSpace(text)) == 0 {
+		out.WriteString("<para/>\n")
+		return
+	}
+	inline := len(text)
+	for _, tag := range []string{"<para", "<itemizedlist", "<orderedlist", "<variablelist",
+		"<programlisting", "<blockquote", "<informaltable", "<figure", "<mediaobject", "<bridgehead"} {
+		if bytes.HasPrefix(text, []byte(tag)) {
+			inline = 0
+			break
+		}
+		if i := bytes.Index(text, []byte("\n"+tag)); i >= 0 && i < inline {
+			inline = i
+		}
+	}
+	if inline > 0 {
+		out.WriteString("<para>")
+		out.Write(bytes.TrimSpace(text[:inline]))
+		out.WriteString("</para>\n")
+	}
+	if inline < len(text) {
+		out.Write(bytes.TrimLeft(text[inline:], "\n"))
+		startLine(out)
+	}
+}
+
+func (options *DocBook) Paragraph(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	startLine(out)
+
+	out.WriteString("<para>")
+	start := out.Len()
+	options.lastImage = nil
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+
+	// a paragraph holding nothing but an image becomes a block image
+	if options.lastImage != nil && bytes.Equal(out.Bytes()[start:], options.lastImage) {
+		out.Truncate(start - len("<para>"))
+		out.Write(options.blockImage)
+		return
+	}
+	out.WriteString("</para>\n")
+}
+
+func (options *DocBook) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	startLine(out)
+	out.WriteString("<informaltable>\n<tgroup cols=\"")
+	out.WriteString(strconv.Itoa(len(columnData)))
+	out.WriteString("\">\n")
+	for i, align := range columnData {
+		out.WriteString("<colspec colname=\"c")
+		out.WriteString(strconv.Itoa(i + 1))
+		if align != 0 {
+			out.WriteString("\" align=\"")
+			out.WriteString(alignments[align-1])
+		}
+		out.WriteString("\"/>\n")
+	}
+	out.WriteString("<thead>\n")
+	out.Write(header)
+	out.WriteString("</thead>\n<tbody>\n")
+	if len(body) > 0 {
+		out.Write(body)
+	} else {
+		// the body cannot be empty
+		out.WriteString("<row>")
+		for range columnData {
+			out.WriteString("<entry/>")
+		}
+		out.WriteString("</row>\n")
+	}
+	out.WriteString("</tbody>\n</tgroup>\n</informaltable>\n")
+}
+
+func (options *DocBook) TableRow(out *bytes.Buffer, text []byte) {
+	out.WriteString("<row>")
+	out.Write(text)
+	out.WriteString("</row>\n")
+}
+
+func (options *DocBook) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
+	options.TableCell(out, text, align)
+}
+
+func (options *DocBook) TableCell(out *bytes.Buffer, text []byte, align int) {
+	out.WriteString("<entry>")
+	out.Write(text)
+	out.WriteString("</entry>")
+}
+
+// Footnotes are written where they are referenced, see FootnoteRefContent.
+func (options *DocBook) Footnotes(out *bytes.Buffer, text func() bool) {
+}
+
+func (options *DocBook) FootnoteItem(out *bytes.Buffer, name, text []byte, flags int) {
+}
+
+func (options *DocBook) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	if kind == LINK_TYPE_EMAIL {
+		out.WriteString("<email>")
+		xmlEscape(out, link)
+		out.WriteString("</email>")
+		return
+	}
+	out.WriteString("<link xlink:href=\"")
+	xmlEscape(out, link)
+	out.WriteString("\">")
+	xmlEscape(out, link)
+	out.WriteString("</link>")
+}
+
+func (options *DocBook) CodeSpan(out *bytes.Buffer, text []byte) {
+	out.WriteString("<literal>")
+	xmlEscape(out, text)
+	out.WriteString("</literal>")
+}
+
+func (options *DocBook) DoubleEmphasis(out *bytes.Buffer, text []byte) {
+	out.WriteString("<emphasis role=\"strong\">")
+	out.Write(text)
+	out.WriteString("</emphasis>")
+}
+
+func (options *DocBook) Emphasis(out *bytes.Buffer, text []byte) {
+	if len(text) == 0 {
+		return
+	}
+	out.WriteString("<emphasis>")
+	out.Write(text)
+	out.WriteString("</emphasis>")
+}
+
+func (options *DocBook) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	var object bytes.Buffer
+	object.WriteString("<imageobject><imagedata fileref=\"")
+	xmlEscape(&object, link)
+	object.WriteString("\"/></imageobject>")
+	if len(alt) > 0 {
+		object.WriteString("<textobject><phrase>")
+		xmlEscape(&object, alt)
+		object.WriteString("</phrase></textobject>")
+	}
+
+	start := out.Len()
+	out.WriteString("<inlinemediaobject>")
+	out.Write(object.Bytes())
+	out.WriteString("</inlinemediaobject>")
+	options.lastImage = append([]byte(nil), out.Bytes()[start:]...)
+
+	var block bytes.Buffer
+	if len(title) > 0 {
+		block.WriteString("<figure>\n<title>")
+		xmlEscape(&block, title)
+		block.WriteString("</title>\n")
+	}
+	block.WriteString("<mediaobject>")
+	block.Write(object.Bytes())
+	block.WriteString("</mediaobject>\n")
+	if len(title) > 0 {
+		block.WriteString("</figure>\n")
+	}
+	options.blockImage = block.Bytes()
+}
+
+func (options *DocBook) LineBreak(out *bytes.Buffer) {
+	out.WriteString("<?linebreak?>\n")
+}
+
+func (options *DocBook) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if len(link) > 1 && link[0] == '#' {
+		id := xmlID(string(link[1:]))
+		options.linkends[id] = string(link)
+		out.WriteString("<link linkend=\"")
+		xmlEscape(out, []byte(id))
+	} else {
+		out.WriteString("<link xlink:href=\"")
+		xmlEscape(out, link)
+	}
+	if len(title) > 0 {
+		out.WriteString("\" xlink:title=\"")
+		xmlEscape(out, title)
+	}
+	out.WriteString("\">")
+	out.Write(content)
+	out.WriteString("</link>")
+}
+
+// Raw HTML cannot be represented in DocBook, so it is dropped.
+func (options *DocBook) RawHtmlTag(out *bytes.Buffer, tag []byte) {
+}
+
+func (options *DocBook) TripleEmphasis(out *bytes.Buffer, text []byte) {
+	out.WriteString("<emphasis role=\"strong\"><emphasis>")
+	out.Write(text)
+	out.WriteString("</emphasis></emphasis>")
+}
+
+func (options *DocBook) StrikeThrough(out *bytes.Buffer, text []byte) {
+	out.WriteString("<emphasis role=\"strikethrough\">")
+	out.Write(text)
+	out.WriteString("</emphasis>")
+}
+
+func (options *DocBook) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
+	out.WriteString("<footnoteref linkend=\"")
+	xmlEscape(out, []byte(options.footnotes[string(ref)]))
+	out.WriteString("\"/>")
+}
+
+func (options *DocBook) FootnoteRefContent(out *bytes.Buffer, ref []byte, text []byte, id int, flags int) {
+	if _, found := options.footnotes[string(ref)]; found {
+		options.FootnoteRef(out, ref, id)
+		return
+	}
+	fnID := uniqueID(options.ids, xmlID("fn-"+string(slugify(ref))))
+	options.footnotes[string(ref)] = fnID
+
+	out.WriteString("<footnote xml:id=\"")
+	xmlEscape(out, []byte(fnID))
+	out.WriteString("\">")
+	if flags&LIST_ITEM_CONTAINS_BLOCK != 0 {
+		out.WriteByte('\n')
+		out.Write(text)
+		startLine(out)
+	} else {
+		out.WriteString("<para>")
+		out.Write(bytes.TrimSpace(text))
+		out.WriteString("</para>")
+	}
+	out.WriteString("</footnote>")
+}
+
+func (options *DocBook) Entity(out *bytes.Buffer, entity []byte) {
+	// XML only knows a handful of named entities
+	options.NormalText(out, []byte(html.UnescapeString(string(entity))))
+}
+
+func (options *DocBook) NormalText(out *bytes.Buffer, text []byte) {
+	xmlEscape(out, text)
+}
+
+// xmlEscape is like attrEscape, but also drops the characters that are not
+// allowed in XML documents.
+func xmlEscape(out *bytes.Buffer, text []byte) {
+	for len(text) > 0 {
+		i := bytes.IndexFunc(text, func(r rune) bool {
+			return r == utf8.RuneError || (r < ' ' && r != '\t' && r != '\n' && r != '\r')
+		})
+		if i < 0 {
+			attrEscape(out, text)
+			return
+		}
+		attrEscape(out, text[:i])
+		_, size := utf8.DecodeRune(text[i:])
+		text = text[i+size:]
+	}
+}
+
+func (options *DocBook) DocumentHeader(out *bytes.Buffer) {
+	options.doc = out
+	options.sections = nil
+	options.ids = make(map[string]int)
+	options.footnotes = make(map[string]string)
+	options.linkends = make(map[string]string)
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	out.WriteString("<article xmlns=\"http://docbook.org/ns/docbook\" ")
+	out.WriteString("xmlns:xlink=\"http://www.w3.org/1999/xlink\" version=\"5.0\">\n")
+}
+
+func (options *DocBook) DocumentFooter(out *bytes.Buffer) {
+	startLine(out)
+	options.closeSections(out, 1)
+	out.WriteString("</article>\n")
+	options.fixLinkends(out)
+}
+
+// fixLinkends turns the links to IDs that no element of the document has
+// into plain xlink:href links, as a dangling linkend is not valid DocBook.
+func (options *DocBook) fixLinkends(out *bytes.Buffer) {
+	doc := out.Bytes()
+	for id, link := range options.linkends {
+		if _, found := options.ids[id]; found {
+			continue
+		}
+		var linkend, href bytes.Buffer
+		linkend.WriteString("<link linkend=\"")
+		xmlEscape(&linkend, []byte(id))
+		linkend.WriteByte('"')
+		href.WriteString("<link xlink:href=\"")
+		xmlEscape(&href, []byte(link))
+		href.WriteByte('"')
+		doc = bytes.Replace(doc, linkend.Bytes(), href.Bytes(), -1)
+	}
+	out.Reset()
+	out.Write(doc)
+}
+
+// startLine makes sure the next element starts on a new line.
+func startLine(out *bytes.Buffer) {
+	if out.Len() > 0 && out.Bytes()[out.Len()-1] != '\n' {
+		out.WriteByte('\n')
+	}
+}
@@ -0,0 +1,159 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the DocBook renderer
+//
+
+package blackfriday
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+const (
+	docbookHeader = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" +
+		"<article xmlns=\"http://docbook.org/ns/docbook\" " +
+		"xmlns:xlink=\"http://www.w3.org/1999/xlink\" version=\"5.0\">\n"
+	docbookFooter = "</article>\n"
+)
+
+func doTestsDocBook(t *testing.T, tests []string, extensions int) {
+	for i := 1; i < len(tests); i += 2 {
+		tests[i] = docbookHeader + tests[i] + docbookFooter
+	}
+	doTestsWithRenderer(t, tests, extensions, func() Renderer {
+		return DocBookRenderer(0)
+	})
+
+	for i := 0; i+1 < len(tests); i += 2 {
+		output := Markdown([]byte(tests[i]), DocBookRenderer(0), extensions)
+		if err := checkWellFormed(string(output)); err != nil {
+			t.Errorf("\nInput   [%#v]\nOutput is not well-formed XML: %v", tests[i], err)
+		}
+	}
+}
+
+func checkWellFormed(doc string) error {
+	d := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func TestDocBookSections(t *testing.T) {
+	var tests = []string{
+		"Intro.\n\n# One\n\nText.\n\n## One.One\n\n### Deep\n\n## One.Two\n\n# Two\n",
+		"<para>Intro.</para>\n" +
+			"<section xml:id=\"one\">\n<title>One</title>\n<para>Text.</para>\n" +
+			"<section xml:id=\"one-one\">\n<title>One.One</title>\n" +
+			"<section xml:id=\"deep\">\n<title>Deep</title>\n<para/>\n</section>\n" +
+			"</section>\n" +
+			"<section xml:id=\"one-two\">\n<title>One.Two</title>\n<para/>\n</section>\n" +
+			"</section>\n" +
+			"<section xml:id=\"two\">\n<title>Two</title>\n<para/>\n</section>\n",
+
+		"# 1st\n\n# 1st\n\n*   item\n\n    # Listed\n",
+		"<section xml:id=\"_1st\">\n<title>1st</title>\n<para/>\n</section>\n" +
+			"<section xml:id=\"_1st-1\">\n<title>1st</title>\n" +
+			"<itemizedlist>\n<listitem>\n<para>item</para>\n" +
+			"<bridgehead renderas=\"sect1\" xml:id=\"listed\">Listed</bridgehead>\n" +
+			"</listitem>\n</itemizedlist>\n</section>\n",
+	}
+	doTestsDocBook(t, tests, EXTENSION_AUTO_HEADER_IDS)
+}
+
+func TestDocBookLists(t *testing.T) {
+	var tests = []string{
+		"* one\n* two\n    1. nested\n",
+		"<itemizedlist>\n<listitem>\n<para>one</para>\n</listitem>\n" +
+			"<listitem>\n<para>two</para>\n" +
+			"<orderedlist>\n<listitem>\n<para>nested</para>\n</listitem>\n</orderedlist>\n" +
+			"</listitem>\n</itemizedlist>\n",
+
+		"Term\n:   One\n\n:   Two\n\nA\n:   Three\n",
+		"<variablelist>\n<varlistentry>\n<term>Term</term>\n" +
+			"<listitem>\n<para>One</para>\n<para>Two</para>\n</listitem>\n</varlistentry>\n" +
+			"<varlistentry>\n<term>A</term>\n" +
+			"<listitem>\n<para>Three</para>\n</listitem>\n" +
+			"</varlistentry>\n</variablelist>\n",
+	}
+	doTestsDocBook(t, tests, EXTENSION_DEFINITION_LISTS)
+}
+
+func TestDocBookBlocks(t *testing.T) {
+	var tests = []string{
+		"```go\nif a < b {}\n```\n",
+		"<programlisting language=\"go\">if a &lt; b {}\n</programlisting>\n",
+
+		"> quote\n",
+		"<blockquote>\n<para>quote</para>\n</blockquote>\n",
+
+		"a | b | c\n:---|---:|:---:\n1 | 2 | 3\n",
+		"<informaltable>\n<tgroup cols=\"3\">\n" +
+			"<colspec colname=\"c1\" align=\"left\"/>\n" +
+			"<colspec colname=\"c2\" align=\"right\"/>\n" +
+			"<colspec colname=\"c3\" align=\"center\"/>\n" +
+			"<thead>\n<row><entry>a</entry><entry>b</entry><entry>c</entry></row>\n</thead>\n" +
+			"<tbody>\n<row><entry>1</entry><entry>2</entry><entry>3</entry></row>\n</tbody>\n" +
+			"</tgroup>\n</informaltable>\n",
+
+		"![alt](pic.png \"Title\")\n",
+		"<figure>\n<title>Title</title>\n<mediaobject><imageobject><imagedata fileref=\"pic.png\"/></imageobject>" +
+			"<textobject><phrase>alt</phrase></textobject></mediaobject>\n</figure>\n",
+
+		"% Title\n% Ann\n% Today\n",
+		"<info>\n<title>Title</title>\n<author><personname>Ann</personname></author>\n<date>Today</date>\n</info>\n",
+	}
+	doTestsDocBook(t, tests, EXTENSION_FENCED_CODE|EXTENSION_TABLES|EXTENSION_TITLEBLOCK)
+}
+
+func TestDocBookInline(t *testing.T) {
+	var tests = []string{
+		"*a* **b** ***c*** ~~d~~ `<e>`\n",
+		"<para><emphasis>a</emphasis> <emphasis role=\"strong\">b</emphasis> " +
+			"<emphasis role=\"strong\"><emphasis>c</emphasis></emphasis> " +
+			"<emphasis role=\"strikethrough\">d</emphasis> <literal>&lt;e&gt;</literal></para>\n",
+
+		"[x](http://a.b/?c&d \"T\") [y](#sec) <me@a.b> <b>raw</b>\n",
+		"<para><link xlink:href=\"http://a.b/?c&amp;d\" xlink:title=\"T\">x</link> " +
+			"<link xlink:href=\"#sec\">y</link> <email>me@a.b</email> raw</para>\n",
+
+		"[to](#sec) and [gone](#none)\n\n# Sec {#sec}\n",
+		"<para><link linkend=\"sec\">to</link> and <link xlink:href=\"#none\">gone</link></para>\n" +
+			"<section xml:id=\"sec\">\n<title>Sec</title>\n<para/>\n</section>\n",
+
+		"&copy; &amp; \x01\n",
+		"<para>© &amp; </para>\n",
+
+		"Note[^n] again[^n].\n\n[^n]: Text.\n",
+		"<para>Note<footnote xml:id=\"fn-n\"><para>Text.</para></footnote> " +
+			"again<footnoteref linkend=\"fn-n\"/>.</para>\n",
+	}
+	doTestsDocBook(t, tests, EXTENSION_STRIKETHROUGH|EXTENSION_FOOTNOTES|EXTENSION_HEADER_IDS)
+}
+
+func TestDocBookReuse(t *testing.T) {
+	renderer := DocBookRenderer(0)
+	input := []byte("# Sec {#sec}\n\nNote[^n].\n\n[^n]: Text.\n")
+	first := Markdown(input, renderer, EXTENSION_FOOTNOTES|EXTENSION_HEADER_IDS)
+	second := Markdown(input, renderer, EXTENSION_FOOTNOTES|EXTENSION_HEADER_IDS)
+	if string(first) != string(second) {
+		t.Errorf("rendering again changed the output:\n%s\n%s", first, second)
+	}
+}
@@ -0,0 +1,878 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Office Open XML (.docx) rendering backend
+//
+//
+
+package blackfriday
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// DocxRendererParameters holds the options of the Docx renderer that are
+// not flags.
+type DocxRendererParameters struct {
+	// Directory that relative image paths are resolved against. Local PNG,
+	// JPEG and GIF images inside it are embedded in the document; other
+	// images are written as links.
+	BaseDir string
+}
+
+// Docx is a type that implements the Renderer interface for Office Open
+// XML (Microsoft Word) output. The result of Markdown is the complete .docx
+// package, so a Docx object must not be reused for several documents at
+// the same time.
+//
+// Do not create this directly, instead use the DocxRenderer function.
+type Docx struct {
+	flags      int
+	parameters DocxRendererParameters
+
+	start int // position of document.xml in the output
+
+	// Track IDs to keep bookmark names unique within the document.
+	ids       map[string]int
+	bookmarks int
+
+	// lists that are currently open, innermost last, and every list seen
+	// so far, which each get their own numbering instance
+	lists   []docxList
+	ordered []bool
+
+	// relationships of the main document after the fixed ones, by target
+	rels     []docxRel
+	relIDs   map[string]string
+	images   int
+	pictures int
+
+	// footnotes already written out, by reference, and their contents
+	notes     map[string]int
+	footnotes bytes.Buffer
+
+	// the most recent image, in case it turns out to be a figure
+	lastImage []byte
+	lastTitle []byte
+}
+
+type docxList struct {
+	numID      int // 0 for definition lists
+	definition bool
+}
+
+type docxRel struct {
+	id, kind, target string
+	external         bool
+	data             []byte // contents of an embedded part
+}
+
+const (
+	docxPageWidth = 9360    // text width in twentieths of a point (6.5in)
+	docxMaxWidth  = 5943600 // maximum image width in EMU (6.5in)
+	docxIndent    = 720     // indentation step in twentieths of a point
+	docxFixedRels = 4       // styles, numbering, footnotes and settings
+
+	docxNamespaces = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+		`xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" ` +
+		`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+		`xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"`
+)
+
+// the children of <w:pPr> and <w:rPr> this renderer uses, in schema order
+var (
+	docxParagraphProperties = []string{"pStyle", "keepNext", "numPr", "pBdr", "spacing", "ind", "jc"}
+	docxRunProperties       = []string{"rStyle", "rFonts", "b", "i", "strike", "color", "u", "vertAlign"}
+)
+
+// DocxRenderer creates and configures a Docx object, which
+// satisfies the Renderer interface.
+//
+// flags is a set of DOCX_* options ORed together (currently no such
+// options are defined).
+func DocxRenderer(flags int) Renderer {
+	return DocxRendererWithParameters(flags, DocxRendererParameters{})
+}
+
+func DocxRendererWithParameters(flags int, renderParameters DocxRendererParameters) Renderer {
+	return &Docx{
+		flags:      flags,
+		parameters: renderParameters,
+	}
+}
+
+func (options *Docx) GetFlags() int {
+	return options.flags
+}
+
+func (options *Docx) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	out.WriteString("<w:p><w:pPr><w:pStyle w:val=\"SourceCode\"/></w:pPr>")
+	lines := bytes.Split(bytes.TrimSuffix(text, []byte("\n")), []byte("\n"))
+	for i, line := range lines {
+		if i > 0 {
+			out.WriteString("<w:r><w:rPr></w:rPr><w:br/></w:r>")
+		}
+		docxRun(out, "", line)
+	}
+	out.WriteString("</w:p>\n")
+}
+
+func (options *Docx) TitleBlock(out *bytes.Buffer, text []byte) {
+	title, authors, date := titleBlockFields(text)
+	docxParagraph(out, "Title", []byte(title))
+	if len(authors) > 0 {
+		docxParagraph(out, "Subtitle", []byte(strings.Join(authors, "; ")))
+	}
+	if date != "" {
+		docxParagraph(out, "Subtitle", []byte(date))
+	}
+}
+
+func (options *Docx) BlockQuote(out *bytes.Buffer, text []byte) {
+	var content bytes.Buffer
+	docxBlockContent(&content, text)
+	out.Write(docxProperties(content.Bytes(), "<w:pPr>", docxParagraphProperties,
+		func(i int, props map[string]string) {
+			if props["pStyle"] == "" && props["numPr"] == "" {
+				props["pStyle"] = `<w:pStyle w:val="Quote"/>`
+			}
+		}))
+}
+
+// Raw HTML cannot be represented in Word, so show it as code instead.
+func (options *Docx) BlockHtml(out *bytes.Buffer, text []byte) {
+	options.BlockCode(out, text, "html")
+}
+
+func (options *Docx) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	marker := out.Len()
+	out.WriteString("<w:p><w:pPr><w:pStyle w:val=\"Heading")
+	out.WriteString(strconv.Itoa(level))
+	out.WriteString("\"/></w:pPr>")
+
+	bookmark := 0
+	if id != "" {
+		options.bookmarks++
+		bookmark = options.bookmarks
+		out.WriteString("<w:bookmarkStart w:id=\"")
+		out.WriteString(strconv.Itoa(bookmark))
+		out.WriteString("\" w:name=\"")
+		xmlEscape(out, []byte(docxBookmark(uniqueID(options.ids, id))))
+		out.WriteString("\"/>")
+	}
+	start := out.Len()
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	docxWrapRuns(out, start)
+	if bookmark != 0 {
+		out.WriteString("<w:bookmarkEnd w:id=\"")
+		out.WriteString(strconv.Itoa(bookmark))
+		out.WriteString("\"/>")
+	}
+	out.WriteString("</w:p>\n")
+}
+
+// docxBookmark turns a header ID into a valid bookmark name: letters, digits
+// and underscores, starting with a letter and at most 40 characters long.
+func docxBookmark(id string) string {
+	var name []byte
+	for _, c := range []byte(id) {
+		if !isalnum(c) {
+			c = '_'
+		}
+		name = append(name, c)
+	}
+	if len(name) == 0 || !isletter(name[0]) {
+		name = append([]byte("h"), name...)
+	}
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	return string(name)
+}
+
+func (options *Docx) HRule(out *bytes.Buffer) {
+	out.WriteString("<w:p><w:pPr><w:pBdr>")
+	out.WriteString("<w:bottom w:val=\"single\" w:sz=\"6\" w:space=\"1\" w:color=\"auto\"/>")
+	out.WriteString("</w:pBdr></w:pPr></w:p>\n")
+}
+
+func (options *Docx) List(out *bytes.Buffer, text func() bool, flags int) {
+	marker := out.Len()
+
+	list := docxList{definition: flags&LIST_TYPE_DEFINITION != 0}
+	if !list.definition {
+		options.ordered = append(options.ordered, flags&LIST_TYPE_ORDERED != 0)
+		list.numID = len(options.ordered)
+	}
+	options.lists = append(options.lists, list)
+	defer func() {
+		options.lists = options.lists[:len(options.lists)-1]
+	}()
+
+	if !text() {
+		out.Truncate(marker)
+	}
+}
+
+func (options *Docx) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	list := options.lists[len(options.lists)-1]
+	level := len(options.lists) - 1
+	indent := fmt.Sprintf(`<w:ind w:left="%d"/>`, docxIndent*(level+1))
+
+	if flags&LIST_TYPE_TERM != 0 {
+		out.WriteString("<w:p><w:pPr><w:keepNext/></w:pPr>")
+		out.Write(docxProperties(docxRuns(bytes.TrimSpace(text)), "<w:rPr>", docxRunProperties,
+			func(i int, props map[string]string) {
+				props["b"] = "<w:b/>"
+			}))
+		out.WriteString("</w:p>\n")
+		return
+	}
+
+	var content bytes.Buffer
+	docxBlockContent(&content, text)
+	if !list.definition && docxNumbered(content.Bytes()) {
+		// the item starts with a nested list, so give it an empty paragraph
+		// to carry its own number
+		item := append([]byte("<w:p><w:pPr></w:pPr></w:p>\n"), content.Bytes()...)
+		content.Reset()
+		content.Write(item)
+	}
+
+	out.Write(docxProperties(content.Bytes(), "<w:pPr>", docxParagraphProperties,
+		func(i int, props map[string]string) {
+			if i == 0 && !list.definition {
+				props["numPr"] = fmt.Sprintf(`<w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`,
+					level, list.numID)
+			} else if props["numPr"] == "" && props["ind"] == "" {
+				props["ind"] = indent
+			}
+		}))
+}
+
+// docxNumbered reports whether text starts with a list item.
+func docxNumbered(text []byte) bool {
+	end := bytes.Index(text, []byte("</w:pPr>"))
+	return end >= 0 && bytes.HasPrefix(text, []byte("<w:p><w:pPr>")) &&
+		bytes.Contains(text[:end], []byte("<w:numPr>"))
+}
+
+// Write block-level content, wrapping any leading inline content in a
+// paragraph. The result always starts with a paragraph.
+func docxBlockContent(out *bytes.Buffer, text []byte) {
+	inline := len(text)
+	for _, tag := range []string{"<w:p>", "<w:tbl>"} {
+		if i := bytes.Index(text, []byte(tag)); i >= 0 && i < inline {
+			inline = i
+		}
+	}
+	lead := docxRuns(bytes.TrimSpace(text[:inline]))
+	if len(lead) > 0 || !bytes.HasPrefix(text[inline:], []byte("<w:p>")) {
+		out.WriteString("<w:p><w:pPr></w:pPr>")
+		out.Write(lead)
+		out.WriteString("</w:p>\n")
+	}
+	out.Write(text[inline:])
+}
+
+func (options *Docx) Paragraph(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+
+	out.WriteString("<w:p><w:pPr></w:pPr>")
+	start := out.Len()
+	options.lastImage = nil
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	docxWrapRuns(out, start)
+	out.WriteString("</w:p>\n")
+
+	// an image alone in a paragraph is a figure, captioned with its title
+	if options.lastImage != nil && len(options.lastTitle) > 0 &&
+		bytes.Equal(out.Bytes()[start:out.Len()-len("</w:p>\n")], options.lastImage) {
+		docxParagraph(out, "Caption", options.lastTitle)
+	}
+}
+
+// docxParagraph writes text as a paragraph with the given style.
+func docxParagraph(out *bytes.Buffer, style string, text []byte) {
+	out.WriteString("<w:p><w:pPr><w:pStyle w:val=\"")
+	out.WriteString(style)
+	out.WriteString("\"/></w:pPr>")
+	docxRun(out, "", text)
+	out.WriteString("</w:p>\n")
+}
+
+func (options *Docx) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	out.WriteString("<w:tbl><w:tblPr><w:tblStyle w:val=\"Table\"/>")
+	out.WriteString("<w:tblW w:w=\"5000\" w:type=\"pct\"/></w:tblPr>\n<w:tblGrid>")
+	for range columnData {
+		out.WriteString("<w:gridCol w:w=\"")
+		out.WriteString(strconv.Itoa(docxPageWidth / len(columnData)))
+		out.WriteString("\"/>")
+	}
+	out.WriteString("</w:tblGrid>\n")
+
+	// header rows repeat on every page
+	out.Write(bytes.Replace(header, []byte("<w:tr>"),
+		[]byte("<w:tr><w:trPr><w:tblHeader/></w:trPr>"), -1))
+	out.Write(body)
+	out.WriteString("</w:tbl>\n")
+
+	// two tables in a row would be merged into one
+	out.WriteString("<w:p><w:pPr></w:pPr></w:p>\n")
+}
+
+func (options *Docx) TableRow(out *bytes.Buffer, text []byte) {
+	out.WriteString("<w:tr>")
+	out.Write(text)
+	out.WriteString("</w:tr>\n")
+}
+
+func (options *Docx) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
+	options.TableCell(out, docxProperties(docxRuns(text), "<w:rPr>", docxRunProperties,
+		func(i int, props map[string]string) {
+			props["b"] = "<w:b/>"
+		}), align)
+}
+
+func (options *Docx) TableCell(out *bytes.Buffer, text []byte, align int) {
+	out.WriteString("<w:tc><w:p><w:pPr>")
+	if align != 0 {
+		out.WriteString("<w:jc w:val=\"")
+		out.WriteString(alignments[align-1])
+		out.WriteString("\"/>")
+	}
+	out.WriteString("</w:pPr>")
+	out.Write(docxRuns(text))
+	out.WriteString("</w:p></w:tc>")
+}
+
+// Footnotes are collected where they are referenced, see FootnoteRefContent.
+func (options *Docx) Footnotes(out *bytes.Buffer, text func() bool) {
+}
+
+func (options *Docx) FootnoteItem(out *bytes.Buffer, name, text []byte, flags int) {
+}
+
+func (options *Docx) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	var content bytes.Buffer
+	options.NormalText(&content, link)
+	if kind == LINK_TYPE_EMAIL && !bytes.HasPrefix(link, []byte("mailto:")) {
+		link = append([]byte("mailto:"), link...)
+	}
+	options.Link(out, link, nil, content.Bytes())
+}
+
+func (options *Docx) CodeSpan(out *bytes.Buffer, text []byte) {
+	docxRun(out, `<w:rStyle w:val="VerbatimChar"/>`, text)
+}
+
+func (options *Docx) DoubleEmphasis(out *bytes.Buffer, text []byte) {
+	docxRunProperty(out, text, "b", "<w:b/>")
+}
+
+func (options *Docx) Emphasis(out *bytes.Buffer, text []byte) {
+	docxRunProperty(out, text, "i", "<w:i/>")
+}
+
+func (options *Docx) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	id, cx, cy, ok := options.embedImage(link)
+	if !ok {
+		var content bytes.Buffer
+		if len(alt) > 0 {
+			options.NormalText(&content, alt)
+		} else {
+			options.NormalText(&content, link)
+		}
+		options.Link(out, link, title, content.Bytes())
+		return
+	}
+
+	options.pictures++
+	start := out.Len()
+	out.WriteString("<w:r><w:rPr></w:rPr><w:drawing><wp:inline distT=\"0\" distB=\"0\" distL=\"0\" distR=\"0\">")
+	fmt.Fprintf(out, "<wp:extent cx=\"%d\" cy=\"%d\"/>", cx, cy)
+	fmt.Fprintf(out, "<wp:docPr id=\"%d\" name=\"Picture %d\" descr=\"", options.pictures, options.pictures)
+	xmlEscape(out, alt)
+	if len(title) > 0 {
+		out.WriteString("\" title=\"")
+		xmlEscape(out, title)
+	}
+	out.WriteString("\"/>")
+	out.WriteString("<wp:cNvGraphicFramePr><a:graphicFrameLocks noChangeAspect=\"1\"/></wp:cNvGraphicFramePr>")
+	out.WriteString("<a:graphic><a:graphicData uri=\"http://schemas.openxmlformats.org/drawingml/2006/picture\">")
+	fmt.Fprintf(out, "<pic:pic><pic:nvPicPr><pic:cNvPr id=\"%d\" name=\"Picture %d\"/><pic:cNvPicPr/></pic:nvPicPr>",
+		options.pictures, options.pictures)
+	fmt.Fprintf(out, "<pic:blipFill><a:blip r:embed=\"%s\"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>", id)
+	fmt.Fprintf(out, "<pic:spPr><a:xfrm><a:off x=\"0\" y=\"0\"/><a:ext cx=\"%d\" cy=\"%d\"/></a:xfrm>", cx, cy)
+	out.WriteString("<a:prstGeom prst=\"rect\"><a:avLst/></a:prstGeom></pic:spPr></pic:pic>")
+	out.WriteString("</a:graphicData></a:graphic></wp:inline></w:drawing></w:r>")
+
+	options.lastImage = append([]byte(nil), out.Bytes()[start:]...)
+	options.lastTitle = append([]byte(nil), title...)
+}
+
+// embedImage adds a local image to the package and returns the ID of its
+// relationship and its size in EMU, scaled down to fit the page.
+func (options *Docx) embedImage(link []byte) (id string, cx, cy int, ok bool) {
+	img, ok := readLocalImage(options.parameters.BaseDir, link)
+	if !ok {
+		return "", 0, 0, false
+	}
+
+	// assume 96 dpi
+	cx, cy = img.width*9525, img.height*9525
+	if cx > docxMaxWidth {
+		cx, cy = docxMaxWidth, cy*docxMaxWidth/cx
+	}
+
+	if id, found := options.relIDs["image:"+img.file]; found {
+		return id, cx, cy, true
+	}
+	options.images++
+	id = options.addRel(docxRel{
+		kind:   "image",
+		target: fmt.Sprintf("media/image%d.%s", options.images, img.format),
+		data:   img.data,
+	}, "image:"+img.file)
+	return id, cx, cy, true
+}
+
+// localImage is an image read from the local file system to be embedded
+// in a document.
+type localImage struct {
+	file          string // cleaned path of the file
+	data          []byte
+	format        string // "png", "jpeg" or "gif"
+	width, height int    // in pixels
+}
+
+// readLocalImage reads the image that link refers to, if it is a local PNG,
+// JPEG or GIF file. Relative paths are resolved against baseDir, and files
+// outside of it are not read.
+func readLocalImage(baseDir string, link []byte) (img localImage, ok bool) {
+	if img.file, ok = localFile(baseDir, link); !ok {
+		return img, false
+	}
+	data, err := ioutil.ReadFile(img.file)
+	if err != nil {
+		return img, false
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width == 0 || config.Height == 0 {
+		return img, false
+	}
+	img.data, img.format = data, format
+	img.width, img.height = config.Width, config.Height
+	return img, true
+}
+
+func (options *Docx) addRel(rel docxRel, key string) string {
+	if id, found := options.relIDs[key]; found {
+		return id
+	}
+	rel.id = "rId" + strconv.Itoa(docxFixedRels+len(options.rels)+1)
+	options.rels = append(options.rels, rel)
+	options.relIDs[key] = rel.id
+	return rel.id
+}
+
+func (options *Docx) LineBreak(out *bytes.Buffer) {
+	out.WriteString("<w:r><w:rPr></w:rPr><w:br/></w:r>")
+}
+
+func (options *Docx) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if len(link) > 1 && link[0] == '#' {
+		out.WriteString("<w:hyperlink w:anchor=\"")
+		xmlEscape(out, []byte(docxBookmark(string(link[1:]))))
+	} else {
+		id := options.addRel(docxRel{kind: "hyperlink", target: string(link), external: true},
+			"hyperlink:"+string(link))
+		out.WriteString("<w:hyperlink r:id=\"")
+		out.WriteString(id)
+	}
+	if len(title) > 0 {
+		out.WriteString("\" w:tooltip=\"")
+		xmlEscape(out, title)
+	}
+	out.WriteString("\" w:history=\"1\">")
+	out.Write(docxProperties(docxRuns(content), "<w:rPr>", docxRunProperties,
+		func(i int, props map[string]string) {
+			if props["rStyle"] == "" {
+				props["rStyle"] = `<w:rStyle w:val="Hyperlink"/>`
+			}
+		}))
+	out.WriteString("</w:hyperlink>")
+}
+
+// Raw HTML cannot be represented in Word, so it is dropped.
+func (options *Docx) RawHtmlTag(out *bytes.Buffer, tag []byte) {
+}
+
+func (options *Docx) TripleEmphasis(out *bytes.Buffer, text []byte) {
+	var strong bytes.Buffer
+	docxRunProperty(&strong, text, "b", "<w:b/>")
+	docxRunProperty(out, strong.Bytes(), "i", "<w:i/>")
+}
+
+func (options *Docx) StrikeThrough(out *bytes.Buffer, text []byte) {
+	docxRunProperty(out, text, "strike", "<w:strike/>")
+}
+
+func (options *Docx) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
+	// a note can only be referenced once, later references just show its
+	// number
+	docxRun(out, `<w:vertAlign w:val="superscript"/>`, []byte(strconv.Itoa(options.notes[string(ref)])))
+}
+
+func (options *Docx) FootnoteRefContent(out *bytes.Buffer, ref []byte, text []byte, id int, flags int) {
+	if _, found := options.notes[string(ref)]; found {
+		options.FootnoteRef(out, ref, id)
+		return
+	}
+	note := len(options.notes) + 1
+	options.notes[string(ref)] = note
+
+	var content bytes.Buffer
+	docxBlockContent(&content, text)
+	paragraphs := docxProperties(content.Bytes(), "<w:pPr>", docxParagraphProperties,
+		func(i int, props map[string]string) {
+			if props["pStyle"] == "" {
+				props["pStyle"] = `<w:pStyle w:val="FootnoteText"/>`
+			}
+		})
+
+	// the note starts with its own number
+	mark := bytes.Index(paragraphs, []byte("</w:pPr>")) + len("</w:pPr>")
+	fmt.Fprintf(&options.footnotes, "<w:footnote w:id=\"%d\">", note)
+	options.footnotes.Write(paragraphs[:mark])
+	options.footnotes.WriteString("<w:r><w:rPr><w:rStyle w:val=\"FootnoteReference\"/></w:rPr><w:footnoteRef/></w:r>")
+	options.footnotes.WriteString("<w:r><w:rPr></w:rPr><w:t xml:space=\"preserve\"> </w:t></w:r>")
+	options.footnotes.Write(paragraphs[mark:])
+	options.footnotes.WriteString("</w:footnote>\n")
+
+	fmt.Fprintf(out, "<w:r><w:rPr><w:rStyle w:val=\"FootnoteReference\"/></w:rPr><w:footnoteReference w:id=\"%d\"/></w:r>", note)
+}
+
+func (options *Docx) Entity(out *bytes.Buffer, entity []byte) {
+	options.NormalText(out, []byte(html.UnescapeString(string(entity))))
+}
+
+// Text is written as is, and only wrapped in runs by the enclosing element
+// (see docxRuns), because the parser sometimes takes back the last few
+// characters it wrote.
+func (options *Docx) NormalText(out *bytes.Buffer, text []byte) {
+	xmlEscape(out, text)
+}
+
+// docxRun writes text as a run with the given properties.
+func docxRun(out *bytes.Buffer, props string, text []byte) {
+	var escaped bytes.Buffer
+	xmlEscape(&escaped, text)
+	docxWriteRun(out, props, escaped.Bytes())
+}
+
+// docxWriteRun writes escaped text as a run with the given properties.
+func docxWriteRun(out *bytes.Buffer, props string, text []byte) {
+	if len(text) == 0 {
+		return
+	}
+	out.WriteString("<w:r><w:rPr>")
+	out.WriteString(props)
+	out.WriteString("</w:rPr>")
+	// line breaks in the source are just spaces
+	text = bytes.Replace(text, []byte("\n"), []byte(" "), -1)
+	for i, part := range bytes.Split(text, []byte("\t")) {
+		if i > 0 {
+			out.WriteString("<w:tab/>")
+		}
+		if len(part) > 0 {
+			out.WriteString("<w:t xml:space=\"preserve\">")
+			out.Write(part)
+			out.WriteString("</w:t>")
+		}
+	}
+	out.WriteString("</w:r>")
+}
+
+// docxRuns wraps the text in inline content that is not in a run yet.
+func docxRuns(text []byte) []byte {
+	var out bytes.Buffer
+	for len(text) > 0 {
+		end := bytes.IndexByte(text, '<')
+		switch {
+		case end < 0:
+			end = len(text)
+			fallthrough
+		case end > 0:
+			docxWriteRun(&out, "", text[:end])
+		case bytes.HasPrefix(text, []byte("<w:r>")):
+			end = bytes.Index(text, []byte("</w:r>")) + len("</w:r>")
+			out.Write(text[:end])
+		default:
+			end = bytes.IndexByte(text, '>') + 1
+			out.Write(text[:end])
+		}
+		text = text[end:]
+	}
+	return out.Bytes()
+}
+
+// docxWrapRuns wraps the text written to out since start in runs.
+func docxWrapRuns(out *bytes.Buffer, start int) {
+	runs := docxRuns(out.Bytes()[start:])
+	out.Truncate(start)
+	out.Write(runs)
+}
+
+// docxRunProperty adds a property to every run in text.
+func docxRunProperty(out *bytes.Buffer, text []byte, name, prop string) {
+	out.Write(docxProperties(docxRuns(text), "<w:rPr>", docxRunProperties,
+		func(i int, props map[string]string) {
+			props[name] = prop
+		}))
+}
+
+// docxProperties rewrites every properties element in text, where open is
+// either "<w:pPr>" or "<w:rPr>". edit is called with the index of the
+// element and its children by name, which are written back in the given
+// order, as the schema requires.
+func docxProperties(text []byte, open string, order []string, edit func(i int, props map[string]string)) []byte {
+	end := []byte("</" + open[1:])
+	var out bytes.Buffer
+	for i := 0; ; i++ {
+		start := bytes.Index(text, []byte(open))
+		if start < 0 {
+			break
+		}
+		start += len(open)
+		stop := start + bytes.Index(text[start:], end)
+
+		props := make(map[string]string)
+		children := text[start:stop]
+		for len(children) > 0 {
+			// each child is <w:name .../> or <w:name>...</w:name>
+			nameEnd := bytes.IndexAny(children[len("<w:"):], " />") + len("<w:")
+			name := string(children[len("<w:"):nameEnd])
+			childEnd := bytes.IndexByte(children, '>') + 1
+			if children[childEnd-2] != '/' {
+				closing := "</w:" + name + ">"
+				childEnd = bytes.Index(children, []byte(closing)) + len(closing)
+			}
+			props[name] = string(children[:childEnd])
+			children = children[childEnd:]
+		}
+		edit(i, props)
+
+		out.Write(text[:start])
+		for _, name := range order {
+			out.WriteString(props[name])
+		}
+		text = text[stop:]
+	}
+	out.Write(text)
+	return out.Bytes()
+}
+
+func (options *Docx) DocumentHeader(out *bytes.Buffer) {
+	options.start = out.Len()
+	options.ids = make(map[string]int)
+	options.bookmarks = 0
+	options.lists = nil
+	options.ordered = nil
+	options.rels = nil
+	options.relIDs = make(map[string]string)
+	options.images = 0
+	options.pictures = 0
+	options.notes = make(map[string]int)
+	options.footnotes.Reset()
+
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\n")
+	out.WriteString("<w:document " + docxNamespaces + ">\n<w:body>\n")
+}
+
+// DocumentFooter finishes document.xml and replaces it in the output with
+// the complete .docx package.
+func (options *Docx) DocumentFooter(out *bytes.Buffer) {
+	out.WriteString("<w:sectPr><w:pgSz w:w=\"12240\" w:h=\"15840\"/>")
+	out.WriteString("<w:pgMar w:top=\"1440\" w:right=\"1440\" w:bottom=\"1440\" w:left=\"1440\" ")
+	out.WriteString("w:header=\"720\" w:footer=\"720\" w:gutter=\"0\"/></w:sectPr>\n")
+	out.WriteString("</w:body>\n</w:document>\n")
+
+	document := append([]byte(nil), out.Bytes()[options.start:]...)
+	out.Truncate(options.start)
+
+	// writing to a bytes.Buffer cannot fail, so errors are ignored
+	z := zip.NewWriter(out)
+	write := func(name string, data []byte) {
+		f, _ := z.Create(name)
+		f.Write(data)
+	}
+	write("[Content_Types].xml", []byte(docxContentTypes))
+	write("_rels/.rels", []byte(docxPackageRels))
+	write("word/document.xml", document)
+	write("word/_rels/document.xml.rels", options.relationships(true))
+	write("word/styles.xml", []byte(docxStyles))
+	write("word/numbering.xml", options.numbering())
+	write("word/settings.xml", []byte(docxSettings))
+	write("word/footnotes.xml", options.footnotesPart())
+	write("word/_rels/footnotes.xml.rels", options.relationships(false))
+	for _, rel := range options.rels {
+		if rel.data != nil {
+			write("word/"+rel.target, rel.data)
+		}
+	}
+	z.Close()
+}
+
+// relationships writes the relationships part of the main document, or of
+// the footnotes, which share its hyperlinks and images.
+func (options *Docx) relationships(document bool) []byte {
+	const kinds = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	var out bytes.Buffer
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\n")
+	out.WriteString("<Relationships xmlns=\"http://schemas.openxmlformats.org/package/2006/relationships\">\n")
+	if document {
+		for i, part := range []string{"styles", "numbering", "footnotes", "settings"} {
+			fmt.Fprintf(&out, "<Relationship Id=\"rId%d\" Type=\"%s%s\" Target=\"%s.xml\"/>\n", i+1, kinds, part, part)
+		}
+	}
+	for _, rel := range options.rels {
+		fmt.Fprintf(&out, "<Relationship Id=\"%s\" Type=\"%s%s\" Target=\"", rel.id, kinds, rel.kind)
+		xmlEscape(&out, []byte(rel.target))
+		out.WriteByte('"')
+		if rel.external {
+			out.WriteString(" TargetMode=\"External\"")
+		}
+		out.WriteString("/>\n")
+	}
+	out.WriteString("</Relationships>\n")
+	return out.Bytes()
+}
+
+func (options *Docx) numbering() []byte {
+	var out bytes.Buffer
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\n")
+	out.WriteString("<w:numbering " + docxNamespaces + ">\n")
+	bullets := []string{"•", "◦", "▪"}
+	for abstract, ordered := range []bool{false, true} {
+		fmt.Fprintf(&out, "<w:abstractNum w:abstractNumId=\"%d\"><w:multiLevelType w:val=\"multilevel\"/>", abstract)
+		for level := 0; level < 9; level++ {
+			format, text := "bullet", bullets[level%len(bullets)]
+			if ordered {
+				format, text = "decimal", "%"+strconv.Itoa(level+1)+"."
+			}
+			fmt.Fprintf(&out, "<w:lvl w:ilvl=\"%d\"><w:start w:val=\"1\"/><w:numFmt w:val=\"%s\"/>", level, format)
+			fmt.Fprintf(&out, "<w:lvlText w:val=\"%s\"/><w:lvlJc w:val=\"left\"/>", text)
+			fmt.Fprintf(&out, "<w:pPr><w:ind w:left=\"%d\" w:hanging=\"360\"/></w:pPr></w:lvl>", docxIndent*(level+1))
+		}
+		out.WriteString("</w:abstractNum>\n")
+	}
+
+	// every list gets its own instance, so that ordered lists start at one
+	for i, ordered := range options.ordered {
+		if !ordered {
+			fmt.Fprintf(&out, "<w:num w:numId=\"%d\"><w:abstractNumId w:val=\"0\"/></w:num>\n", i+1)
+			continue
+		}
+		fmt.Fprintf(&out, "<w:num w:numId=\"%d\"><w:abstractNumId w:val=\"1\"/>", i+1)
+		for level := 0; level < 9; level++ {
+			fmt.Fprintf(&out, "<w:lvlOverride w:ilvl=\"%d\"><w:startOverride w:val=\"1\"/></w:lvlOverride>", level)
+		}
+		out.WriteString("</w:num>\n")
+	}
+	out.WriteString("</w:numbering>\n")
+	return out.Bytes()
+}
+
+func (options *Docx) footnotesPart() []byte {
+	var out bytes.Buffer
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\n")
+	out.WriteString("<w:footnotes " + docxNamespaces + ">\n")
+	out.WriteString("<w:footnote w:type=\"separator\" w:id=\"-1\"><w:p><w:pPr><w:spacing w:after=\"0\"/></w:pPr>")
+	out.WriteString("<w:r><w:separator/></w:r></w:p></w:footnote>\n")
+	out.WriteString("<w:footnote w:type=\"continuationSeparator\" w:id=\"0\"><w:p><w:pPr><w:spacing w:after=\"0\"/></w:pPr>")
+	out.WriteString("<w:r><w:continuationSeparator/></w:r></w:p></w:footnote>\n")
+	out.Write(options.footnotes.Bytes())
+	out.WriteString("</w:footnotes>\n")
+	return out.Bytes()
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Default Extension="png" ContentType="image/png"/>
+<Default Extension="jpeg" ContentType="image/jpeg"/>
+<Default Extension="gif" ContentType="image/gif"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>
+<Override PartName="/word/settings.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"/>
+<Override PartName="/word/footnotes.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml"/>
+</Types>
+`
+
+const docxPackageRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>
+`
+
+const docxSettings = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:footnotePr><w:footnote w:id="-1"/><w:footnote w:id="0"/></w:footnotePr>
+</w:settings>
+`
+
+const docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:docDefaults>
+<w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:eastAsia="Calibri" w:cs="Calibri"/><w:sz w:val="22"/></w:rPr></w:rPrDefault>
+<w:pPrDefault><w:pPr><w:spacing w:after="160" w:line="264" w:lineRule="auto"/></w:pPr></w:pPrDefault>
+</w:docDefaults>
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>
+<w:style w:type="character" w:default="1" w:styleId="DefaultParagraphFont"><w:name w:val="Default Paragraph Font"/><w:uiPriority w:val="1"/><w:semiHidden/></w:style>
+<w:style w:type="table" w:default="1" w:styleId="TableNormal"><w:name w:val="Normal Table"/><w:semiHidden/><w:tblPr><w:tblInd w:w="0" w:type="dxa"/><w:tblCellMar><w:top w:w="0" w:type="dxa"/><w:left w:w="108" w:type="dxa"/><w:bottom w:w="0" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="480" w:after="120"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="36"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="360" w:after="120"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="32"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="280" w:after="80"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading4"><w:name w:val="heading 4"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="80"/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:b/><w:i/><w:sz w:val="24"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading5"><w:name w:val="heading 5"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="220" w:after="40"/><w:outlineLvl w:val="4"/></w:pPr><w:rPr><w:b/><w:sz w:val="22"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading6"><w:name w:val="heading 6"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="200" w:after="40"/><w:outlineLvl w:val="5"/></w:pPr><w:rPr><w:i/><w:sz w:val="22"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:jc w:val="center"/></w:pPr><w:rPr><w:sz w:val="56"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Subtitle"><w:name w:val="Subtitle"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:jc w:val="center"/></w:pPr><w:rPr><w:color w:val="595959"/><w:sz w:val="28"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:ind w:left="720" w:right="720"/></w:pPr><w:rPr><w:i/><w:color w:val="404040"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="SourceCode"><w:name w:val="Source Code"/><w:basedOn w:val="Normal"/><w:qFormat/><w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F2F2F2"/><w:spacing w:after="160" w:line="240" w:lineRule="auto"/></w:pPr><w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/><w:sz w:val="20"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Caption"><w:name w:val="caption"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:rPr><w:i/><w:sz w:val="18"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="FootnoteText"><w:name w:val="footnote text"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:rPr><w:sz w:val="20"/></w:rPr></w:style>
+<w:style w:type="character" w:styleId="FootnoteReference"><w:name w:val="footnote reference"/><w:basedOn w:val="DefaultParagraphFont"/><w:rPr><w:vertAlign w:val="superscript"/></w:rPr></w:style>
+<w:style w:type="character" w:styleId="VerbatimChar"><w:name w:val="Verbatim Char"/><w:basedOn w:val="DefaultParagraphFont"/><w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/><w:sz w:val="20"/></w:rPr></w:style>
+<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:basedOn w:val="DefaultParagraphFont"/><w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr></w:style>
+<w:style w:type="table" w:styleId="Table"><w:name w:val="Table"/><w:basedOn w:val="TableNormal"/><w:tblPr><w:tblBorders><w:top w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:left w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:bottom w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:right w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:insideH w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="auto"/></w:tblBorders></w:tblPr></w:style>
+</w:styles>
+`
@@ -0,0 +1,223 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the Docx renderer
+//
+
+package blackfriday
+
+import (
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renderDocx renders input and returns the parts of the resulting package,
+// checking that all the XML parts are well-formed.
+func renderDocx(t *testing.T, input string, params DocxRendererParameters) map[string]string {
+	output := Markdown([]byte(input), DocxRendererWithParameters(0, params),
+		commonExtensions|EXTENSION_FOOTNOTES|EXTENSION_TITLEBLOCK)
+	names, files := readEpub(t, output)
+	for _, name := range names {
+		if strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".rels") {
+			if err := checkWellFormed(files[name]); err != nil {
+				t.Errorf("%s is not well-formed XML: %v\n%s", name, err, files[name])
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml",
+		"word/_rels/document.xml.rels", "word/styles.xml", "word/numbering.xml"} {
+		if _, found := files[name]; !found {
+			t.Errorf("missing part %s in %v", name, names)
+		}
+	}
+	return files
+}
+
+func checkDocxParts(t *testing.T, input string, files map[string]string, expected map[string][]string) {
+	for name, parts := range expected {
+		for _, part := range parts {
+			if !strings.Contains(files[name], part) {
+				t.Errorf("\nInput   [%#v]\n%s: missing [%#v] in\n%s", input, name, part, files[name])
+			}
+		}
+	}
+}
+
+func TestDocxBlocks(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected []string
+	}{
+		{"# Title {#the-title}\n\nSee [here](#the-title).\n", []string{
+			`<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:bookmarkStart w:id="1" w:name="the_title"/>` +
+				`<w:r><w:rPr></w:rPr><w:t xml:space="preserve">Title</w:t></w:r><w:bookmarkEnd w:id="1"/></w:p>`,
+			`<w:hyperlink w:anchor="the_title" w:history="1"><w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr>`,
+		}},
+		{"```\nif a < b {\n\treturn\n}\n```\n", []string{
+			`<w:p><w:pPr><w:pStyle w:val="SourceCode"/></w:pPr>` +
+				`<w:r><w:rPr></w:rPr><w:t xml:space="preserve">if a &lt; b {</w:t></w:r>` +
+				`<w:r><w:rPr></w:rPr><w:br/></w:r><w:r><w:rPr></w:rPr><w:tab/><w:t xml:space="preserve">return</w:t></w:r>`,
+		}},
+		{"> quoted\n", []string{
+			`<w:p><w:pPr><w:pStyle w:val="Quote"/></w:pPr><w:r><w:rPr></w:rPr><w:t xml:space="preserve">quoted</w:t></w:r></w:p>`,
+		}},
+		{"a | b | c\n:--|--:|:-:\n1 | 2 | 3\n", []string{
+			`<w:tblGrid><w:gridCol w:w="3120"/><w:gridCol w:w="3120"/><w:gridCol w:w="3120"/></w:tblGrid>`,
+			`<w:tr><w:trPr><w:tblHeader/></w:trPr><w:tc><w:p><w:pPr><w:jc w:val="left"/></w:pPr>` +
+				`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">a</w:t></w:r></w:p></w:tc>`,
+			`<w:tc><w:p><w:pPr><w:jc w:val="right"/></w:pPr><w:r><w:rPr></w:rPr><w:t xml:space="preserve">2</w:t></w:r></w:p></w:tc>`,
+			`<w:jc w:val="center"/>`,
+		}},
+		{"% The Title\n% Ann; Bob\n% 2020\n", []string{
+			`<w:pStyle w:val="Title"/></w:pPr><w:r><w:rPr></w:rPr><w:t xml:space="preserve">The Title</w:t>`,
+			`<w:pStyle w:val="Subtitle"/></w:pPr><w:r><w:rPr></w:rPr><w:t xml:space="preserve">Ann; Bob</w:t>`,
+		}},
+		{"---\n", []string{`<w:pBdr><w:bottom w:val="single"`}},
+	}
+	for _, test := range tests {
+		files := renderDocx(t, test.input, DocxRendererParameters{})
+		checkDocxParts(t, test.input, files, map[string][]string{"word/document.xml": test.expected})
+	}
+}
+
+func TestDocxInline(t *testing.T) {
+	input := "*a* **b** ***c*** ~~d~~ `e` &copy;\n" +
+		"[link](http://example.com/ \"Tip\") <http://example.com/> <me@example.com>\n"
+	files := renderDocx(t, input, DocxRendererParameters{})
+	checkDocxParts(t, input, files, map[string][]string{
+		"word/document.xml": {
+			`<w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve">a</w:t></w:r>`,
+			`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">b</w:t></w:r>`,
+			`<w:r><w:rPr><w:b/><w:i/></w:rPr><w:t xml:space="preserve">c</w:t></w:r>`,
+			`<w:r><w:rPr><w:strike/></w:rPr><w:t xml:space="preserve">d</w:t></w:r>`,
+			`<w:r><w:rPr><w:rStyle w:val="VerbatimChar"/></w:rPr><w:t xml:space="preserve">e</w:t></w:r>`,
+			`<w:t xml:space="preserve"> © </w:t>`,
+			`<w:hyperlink r:id="rId5" w:tooltip="Tip" w:history="1">`,
+			`<w:hyperlink r:id="rId5" w:history="1">`,
+			`<w:hyperlink r:id="rId6" w:history="1">`,
+		},
+		"word/_rels/document.xml.rels": {
+			`Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"`,
+			`<Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" ` +
+				`Target="http://example.com/" TargetMode="External"/>`,
+			`Target="mailto:me@example.com" TargetMode="External"/>`,
+		},
+	})
+}
+
+func TestDocxLists(t *testing.T) {
+	input := "* one\n* two\n    1. nested\n\n" +
+		"Paragraph.\n\n" +
+		"1. first\n\n    more\n\n2. second\n\n" +
+		"Term\n:   Definition\n"
+	files := renderDocx(t, input, DocxRendererParameters{})
+	checkDocxParts(t, input, files, map[string][]string{
+		"word/document.xml": {
+			`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr>` +
+				`<w:r><w:rPr></w:rPr><w:t xml:space="preserve">one</w:t></w:r></w:p>`,
+			`<w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="2"/></w:numPr></w:pPr>` +
+				`<w:r><w:rPr></w:rPr><w:t xml:space="preserve">nested</w:t></w:r></w:p>`,
+			`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="3"/></w:numPr></w:pPr>` +
+				`<w:r><w:rPr></w:rPr><w:t xml:space="preserve">first</w:t></w:r></w:p>`,
+			`<w:p><w:pPr><w:ind w:left="720"/></w:pPr><w:r><w:rPr></w:rPr><w:t xml:space="preserve">more</w:t></w:r></w:p>`,
+			`<w:p><w:pPr><w:keepNext/></w:pPr><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">Term</w:t></w:r></w:p>`,
+			`<w:p><w:pPr><w:ind w:left="720"/></w:pPr><w:r><w:rPr></w:rPr><w:t xml:space="preserve">Definition</w:t></w:r></w:p>`,
+		},
+		"word/numbering.xml": {
+			`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>`,
+			`<w:num w:numId="2"><w:abstractNumId w:val="1"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="1"/>`,
+			`<w:num w:numId="3"><w:abstractNumId w:val="1"/>`,
+			`<w:numFmt w:val="decimal"/><w:lvlText w:val="%2."/>`,
+		},
+	})
+}
+
+func TestDocxFootnotes(t *testing.T) {
+	input := "Note[^a] and again[^a].\n\n[^a]: The [note](http://example.com/).\n"
+	files := renderDocx(t, input, DocxRendererParameters{})
+	checkDocxParts(t, input, files, map[string][]string{
+		"word/document.xml": {
+			`<w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteReference w:id="1"/></w:r>`,
+			`<w:r><w:rPr><w:vertAlign w:val="superscript"/></w:rPr><w:t xml:space="preserve">1</w:t></w:r>`,
+		},
+		"word/footnotes.xml": {
+			`<w:footnote w:type="separator" w:id="-1">`,
+			`<w:footnote w:id="1"><w:p><w:pPr><w:pStyle w:val="FootnoteText"/></w:pPr>` +
+				`<w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteRef/></w:r>`,
+			`<w:hyperlink r:id="rId5" w:history="1">`,
+		},
+		"word/_rels/footnotes.xml.rels": {`Id="rId5"`},
+	})
+}
+
+func TestDocxImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blackfriday-docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f, err := os.Create(filepath.Join(dir, "pic.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, 1000, 20))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	input := "![a picture](pic.png \"Caption\")\n\nInline ![again](pic.png) ![remote](http://example.com/x.png)\n"
+	files := renderDocx(t, input, DocxRendererParameters{BaseDir: dir})
+	checkDocxParts(t, input, files, map[string][]string{
+		"word/document.xml": {
+			`<wp:extent cx="5943600" cy="118872"/><wp:docPr id="1" name="Picture 1" descr="a picture" title="Caption"/>`,
+			`<a:blip r:embed="rId5"/>`,
+			`<w:p><w:pPr><w:pStyle w:val="Caption"/></w:pPr><w:r><w:rPr></w:rPr><w:t xml:space="preserve">Caption</w:t></w:r></w:p>`,
+			`<wp:docPr id="2" name="Picture 2" descr="again"/>`,
+			`<w:hyperlink r:id="rId6" w:history="1"><w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr>` +
+				`<w:t xml:space="preserve">remote</w:t></w:r></w:hyperlink>`,
+		},
+		"word/_rels/document.xml.rels": {
+			`<Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image1.png"/>`,
+		},
+	})
+	if !strings.HasPrefix(files["word/media/image1.png"], "\x89PNG") {
+		t.Errorf("image was not embedded")
+	}
+	if strings.Contains(files["word/document.xml"], "rId7") {
+		t.Errorf("image embedded twice")
+	}
+
+	// images outside of BaseDir are not read
+	input = "![up](../pic.png) ![up](sub/../../pic.png)\n"
+	files = renderDocx(t, input, DocxRendererParameters{BaseDir: filepath.Join(dir, "sub")})
+	if _, found := files["word/media/image1.png"]; found {
+		t.Errorf("image outside of BaseDir was embedded")
+	}
+}
+
+func TestDocxTruncatedText(t *testing.T) {
+	// the parser takes back text it wrote before images, inline footnotes
+	// and autolinks
+	input := "x ![](http://example.com/a.png) y^[note] see http://example.com/ now\n"
+	output := Markdown([]byte(input), DocxRenderer(0), EXTENSION_AUTOLINK|EXTENSION_FOOTNOTES)
+	_, files := readEpub(t, output)
+	checkDocxParts(t, input, files, map[string][]string{
+		"word/document.xml": {
+			`<w:t xml:space="preserve">x </w:t></w:r><w:hyperlink`,
+			`<w:t xml:space="preserve"> y</w:t></w:r><w:r><w:rPr><w:rStyle w:val="FootnoteReference"/>`,
+			`<w:t xml:space="preserve"> see </w:t></w:r><w:hyperlink`,
+		},
+	})
+}
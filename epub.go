@@ -0,0 +1,492 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// EPUB 3 output
+//
+//
+
+package blackfriday
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EpubMetadata describes the book written by Epub.
+type EpubMetadata struct {
+	Title      string
+	Author     string
+	Language   string    // BCP 47 language tag, "en" if empty
+	Identifier string    // unique identifier, derived from the contents if empty
+	CoverImage string    // path to a local image file used as the cover, optional
+	Modified   time.Time // last modification time, the current time if zero
+
+	// Directory that relative image paths in the chapters are resolved
+	// against. Such images are read from disk and packaged in the book;
+	// paths that lead outside of it are left as they are.
+	BaseDir string
+}
+
+// Epub renders each Markdown chapter with the Html renderer in XHTML mode
+// and writes them to w as an EPUB 3 book, in the order given.
+//
+// The navigation document is built from the headers of the chapters; a
+// chapter without headers is listed by its position. Images with a relative
+// path (and the cover image, if any) are read from the local file system and
+// included in the book. Nothing is fetched over the network: absolute URLs
+// are left as they are.
+//
+// htmlFlags is a set of HTML_* options and extensions a set of EXTENSION_*
+// options, as for Markdown. HTML_USE_XHTML and EXTENSION_AUTO_HEADER_IDS are
+// always set, HTML_COMPLETE_PAGE and HTML_TOC are ignored.
+func Epub(w io.Writer, chapters [][]byte, meta EpubMetadata, htmlFlags, extensions int) error {
+	book := &epubBook{meta: meta, images: make(map[string]string)}
+	if book.meta.Language == "" {
+		book.meta.Language = "en"
+	}
+	if book.meta.Modified.IsZero() {
+		book.meta.Modified = time.Now()
+	}
+	if book.meta.Identifier == "" {
+		book.meta.Identifier = epubIdentifier(meta, chapters)
+	}
+
+	htmlFlags |= HTML_USE_XHTML
+	htmlFlags &^= HTML_COMPLETE_PAGE | HTML_TOC
+	extensions |= EXTENSION_AUTO_HEADER_IDS
+
+	for i, input := range chapters {
+		renderer := &epubHtml{
+			Html: HtmlRenderer(htmlFlags, "", "").(*Html),
+			book: book,
+		}
+		body := Markdown(input, renderer, extensions)
+		if book.err != nil {
+			return book.err
+		}
+		book.chapters = append(book.chapters, epubChapter{
+			name:     fmt.Sprintf("chapter%d.xhtml", i+1),
+			body:     xhtmlEntities(body),
+			headings: renderer.headings,
+		})
+	}
+
+	if meta.CoverImage != "" {
+		name, err := book.addImage(meta.CoverImage)
+		if err != nil {
+			return err
+		}
+		book.cover = name
+	}
+
+	return book.write(w)
+}
+
+type epubChapter struct {
+	name     string
+	body     []byte
+	headings []tocHeading
+}
+
+type epubBook struct {
+	meta     EpubMetadata
+	chapters []epubChapter
+	cover    string            // name of the cover image in the book
+	images   map[string]string // local path -> name in the book
+	order    []string          // local paths in the order they were added
+	err      error             // first error while rendering a chapter
+}
+
+// epubHtml is an Html renderer that packages the local images it sees.
+type epubHtml struct {
+	*Html
+	book *epubBook
+}
+
+func (options *epubHtml) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	file, ok := localFile(options.book.meta.BaseDir, link)
+	if ok && strings.HasPrefix(epubMediaType(file), "image/") && options.book.err == nil {
+		name, err := options.book.addImage(file)
+		if err != nil {
+			options.book.err = err
+		}
+		link = []byte(name)
+	}
+	options.Html.Image(out, link, title, alt)
+}
+
+// isLocalPath reports whether link refers to a file rather than a URL.
+func isLocalPath(link []byte) bool {
+	if len(link) == 0 || link[0] == '#' || bytes.HasPrefix(link, []byte("//")) {
+		return false
+	}
+	end := bytes.IndexAny(link, "/?#")
+	if end < 0 {
+		end = len(link)
+	}
+	return bytes.IndexByte(link[:end], ':') < 0
+}
+
+// localFile returns the file that a local link refers to, resolved against
+// baseDir after dropping any query or fragment and decoding percent escapes.
+// It fails if link is a URL or the file is not inside baseDir.
+func localFile(baseDir string, link []byte) (string, bool) {
+	if !isLocalPath(link) {
+		return "", false
+	}
+	if end := bytes.IndexAny(link, "?#"); end >= 0 {
+		link = link[:end]
+	}
+	name, err := url.PathUnescape(string(link))
+	if err != nil || name == "" {
+		return "", false
+	}
+
+	baseDir = filepath.Clean(baseDir)
+	file := filepath.Join(baseDir, filepath.FromSlash(name))
+	rel, err := filepath.Rel(baseDir, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return file, true
+}
+
+func (book *epubBook) addImage(file string) (string, error) {
+	file = filepath.Clean(file)
+	if name, ok := book.images[file]; ok {
+		return name, nil
+	}
+	if _, err := os.Stat(file); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("images/image%d%s", len(book.order)+1, strings.ToLower(filepath.Ext(file)))
+	book.images[file] = name
+	book.order = append(book.order, file)
+	return name, nil
+}
+
+func (book *epubBook) write(w io.Writer) error {
+	z := zip.NewWriter(w)
+
+	// the mimetype must come first and be stored uncompressed
+	f, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	files := []epubFile{
+		{"META-INF/container.xml", []byte(epubContainer)},
+		{"OEBPS/content.opf", book.packageDocument()},
+		{"OEBPS/nav.xhtml", book.navDocument()},
+	}
+	if book.cover != "" {
+		files = append(files, epubFile{"OEBPS/cover.xhtml", book.coverPage()})
+	}
+	for _, ch := range book.chapters {
+		files = append(files, epubFile{"OEBPS/" + ch.name, book.page(ch)})
+	}
+
+	for _, file := range files {
+		if err := epubWriteFile(z, file.name, file.data); err != nil {
+			return err
+		}
+	}
+	for _, local := range book.order {
+		data, err := ioutil.ReadFile(local)
+		if err != nil {
+			return err
+		}
+		if err := epubWriteFile(z, "OEBPS/"+book.images[local], data); err != nil {
+			return err
+		}
+	}
+
+	return z.Close()
+}
+
+type epubFile struct {
+	name string
+	data []byte
+}
+
+func epubWriteFile(z *zip.Writer, name string, data []byte) error {
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func (book *epubBook) packageDocument() []byte {
+	var out bytes.Buffer
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	out.WriteString("<package xmlns=\"http://www.idpf.org/2007/opf\" version=\"3.0\" unique-identifier=\"book-id\">\n")
+	out.WriteString("  <metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	out.WriteString("    <dc:identifier id=\"book-id\">")
+	xmlEscape(&out, []byte(book.meta.Identifier))
+	out.WriteString("</dc:identifier>\n")
+	out.WriteString("    <dc:title>")
+	xmlEscape(&out, []byte(book.meta.Title))
+	out.WriteString("</dc:title>\n")
+	if book.meta.Author != "" {
+		out.WriteString("    <dc:creator>")
+		xmlEscape(&out, []byte(book.meta.Author))
+		out.WriteString("</dc:creator>\n")
+	}
+	out.WriteString("    <dc:language>")
+	xmlEscape(&out, []byte(book.meta.Language))
+	out.WriteString("</dc:language>\n")
+	out.WriteString("    <meta property=\"dcterms:modified\">")
+	out.WriteString(book.meta.Modified.UTC().Format("2006-01-02T15:04:05Z"))
+	out.WriteString("</meta>\n")
+	for i, local := range book.order {
+		if book.images[local] == book.cover {
+			fmt.Fprintf(&out, "    <meta name=\"cover\" content=\"image%d\"/>\n", i+1)
+		}
+	}
+	out.WriteString("  </metadata>\n")
+
+	out.WriteString("  <manifest>\n")
+	out.WriteString("    <item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	if book.cover != "" {
+		out.WriteString("    <item id=\"cover\" href=\"cover.xhtml\" media-type=\"application/xhtml+xml\"/>\n")
+	}
+	for i, ch := range book.chapters {
+		fmt.Fprintf(&out, "    <item id=\"chapter%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, ch.name)
+	}
+	for i, local := range book.order {
+		name := book.images[local]
+		fmt.Fprintf(&out, "    <item id=\"image%d\" href=\"%s\" media-type=\"%s\"", i+1, name, epubMediaType(name))
+		if name == book.cover {
+			out.WriteString(" properties=\"cover-image\"")
+		}
+		out.WriteString("/>\n")
+	}
+	out.WriteString("  </manifest>\n")
+
+	out.WriteString("  <spine>\n")
+	if book.cover != "" {
+		out.WriteString("    <itemref idref=\"cover\" linear=\"no\"/>\n")
+	}
+	for i := range book.chapters {
+		fmt.Fprintf(&out, "    <itemref idref=\"chapter%d\"/>\n", i+1)
+	}
+	out.WriteString("  </spine>\n")
+	out.WriteString("</package>\n")
+	return out.Bytes()
+}
+
+func epubMediaType(name string) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		if i := strings.IndexByte(t, ';'); i >= 0 {
+			t = t[:i]
+		}
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func (book *epubBook) navDocument() []byte {
+	var out bytes.Buffer
+	book.pageHeader(&out, book.meta.Title)
+	out.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n")
+	out.WriteString("<h1>")
+	xmlEscape(&out, []byte(book.meta.Title))
+	out.WriteString("</h1>\n")
+
+	// open levels of the nested lists, innermost last
+	var levels []int
+	for i, ch := range book.chapters {
+		headings := ch.headings
+		if len(headings) == 0 {
+			headings = []tocHeading{{level: 1, text: []byte(fmt.Sprintf("Chapter %d", i+1))}}
+		}
+		for _, h := range headings {
+			switch {
+			case len(levels) == 0:
+				out.WriteString("<ol>\n")
+				levels = append(levels, h.level)
+			case h.level > levels[len(levels)-1]:
+				out.WriteString("\n<ol>\n")
+				levels = append(levels, h.level)
+			default:
+				out.WriteString("</li>\n")
+				for len(levels) > 1 && h.level <= levels[len(levels)-2] {
+					out.WriteString("</ol>\n</li>\n")
+					levels = levels[:len(levels)-1]
+				}
+			}
+			out.WriteString("<li><a href=\"")
+			out.WriteString(ch.name)
+			if h.id != "" {
+				out.WriteByte('#')
+				attrEscape(&out, []byte(h.id))
+			}
+			out.WriteString("\">")
+			out.Write(xhtmlEntities(h.text))
+			out.WriteString("</a>")
+		}
+	}
+	if len(levels) > 0 {
+		out.WriteString("</li>\n")
+		for len(levels) > 1 {
+			out.WriteString("</ol>\n</li>\n")
+			levels = levels[:len(levels)-1]
+		}
+		out.WriteString("</ol>\n")
+	}
+
+	out.WriteString("</nav>\n")
+	book.pageFooter(&out)
+	return out.Bytes()
+}
+
+func (book *epubBook) coverPage() []byte {
+	var out bytes.Buffer
+	book.pageHeader(&out, book.meta.Title)
+	out.WriteString("<section epub:type=\"cover\">\n<img src=\"")
+	attrEscape(&out, []byte(book.cover))
+	out.WriteString("\" alt=\"")
+	attrEscape(&out, []byte(book.meta.Title))
+	out.WriteString("\" />\n</section>\n")
+	book.pageFooter(&out)
+	return out.Bytes()
+}
+
+func (book *epubBook) page(ch epubChapter) []byte {
+	title := book.meta.Title
+	if len(ch.headings) > 0 {
+		title = html.UnescapeString(string(stripTags(xhtmlEntities(ch.headings[0].text))))
+	}
+
+	var out bytes.Buffer
+	book.pageHeader(&out, title)
+	out.Write(ch.body)
+	book.pageFooter(&out)
+	return out.Bytes()
+}
+
+func (book *epubBook) pageHeader(out *bytes.Buffer, title string) {
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	out.WriteString("<!DOCTYPE html>\n")
+	out.WriteString("<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\" lang=\"")
+	attrEscape(out, []byte(book.meta.Language))
+	out.WriteString("\" xml:lang=\"")
+	attrEscape(out, []byte(book.meta.Language))
+	out.WriteString("\">\n")
+	out.WriteString("<head>\n")
+	out.WriteString("  <meta charset=\"utf-8\" />\n")
+	out.WriteString("  <title>")
+	xmlEscape(out, []byte(title))
+	out.WriteString("</title>\n")
+	out.WriteString("</head>\n")
+	out.WriteString("<body>\n")
+}
+
+func (book *epubBook) pageFooter(out *bytes.Buffer) {
+	out.WriteString("</body>\n")
+	out.WriteString("</html>\n")
+}
+
+// epubIdentifier derives a stable urn:uuid identifier from the book.
+func epubIdentifier(meta EpubMetadata, chapters [][]byte) string {
+	h := sha1.New()
+	io.WriteString(h, meta.Title)
+	io.WriteString(h, "\x00")
+	io.WriteString(h, meta.Author)
+	for _, ch := range chapters {
+		io.WriteString(h, "\x00")
+		h.Write(ch)
+	}
+	sum := h.Sum(nil)
+	sum[6] = sum[6]&0x0f | 0x50 // version 5
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// xhtmlEntities replaces the named HTML entities that XML does not define
+// with the characters they stand for.
+func xhtmlEntities(text []byte) []byte {
+	if bytes.IndexByte(text, '&') < 0 {
+		return text
+	}
+
+	var out bytes.Buffer
+	mark := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] != '&' {
+			continue
+		}
+		end := i + 1
+		for end < len(text) && isalnum(text[end]) {
+			end++
+		}
+		if end == i+1 || end >= len(text) || text[end] != ';' {
+			continue
+		}
+		switch name := string(text[i+1 : end]); name {
+		case "amp", "lt", "gt", "quot", "apos":
+			continue
+		}
+		entity := string(text[i : end+1])
+		char := html.UnescapeString(entity)
+		if char == entity {
+			continue
+		}
+		out.Write(text[mark:i])
+		xmlEscape(&out, []byte(char))
+		mark = end + 1
+		i = end
+	}
+	out.Write(text[mark:])
+	return out.Bytes()
+}
+
+// stripTags removes the markup from a fragment of rendered HTML.
+func stripTags(text []byte) []byte {
+	var out bytes.Buffer
+	inTag := false
+	for _, c := range text {
+		switch {
+		case c == '<':
+			inTag = true
+		case c == '>' && inTag:
+			inTag = false
+		case !inTag:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
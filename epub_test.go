@@ -0,0 +1,200 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for EPUB output
+//
+
+package blackfriday
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readEpub(t *testing.T, book []byte) (names []string, files map[string]string) {
+	z, err := zip.NewReader(bytes.NewReader(book), int64(len(book)))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+	files = make(map[string]string)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		names = append(names, f.Name)
+		files[f.Name] = string(data)
+		if f.Name == "mimetype" && f.Method != zip.Store {
+			t.Errorf("mimetype is compressed")
+		}
+	}
+	return names, files
+}
+
+func TestEpub(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blackfriday-epub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"cover.jpg", "pic.png"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chapters := [][]byte{
+		[]byte("# One &amp; *only*\n\nText &copy; here.  \nNext line.\n\n" +
+			"![pic](pic.png) ![again](./pic.png) ![remote](http://example.com/x.png)\n\n" +
+			"## Sub\n\n### Deep\n\n## Sub\n"),
+		[]byte("No headers here.\n"),
+		[]byte("# Three\n"),
+	}
+	meta := EpubMetadata{
+		Title:      "A <Book>",
+		Author:     "Ann",
+		Language:   "de",
+		CoverImage: filepath.Join(dir, "cover.jpg"),
+		Modified:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		BaseDir:    dir,
+	}
+
+	var buf bytes.Buffer
+	if err := Epub(&buf, chapters, meta, 0, commonExtensions); err != nil {
+		t.Fatal(err)
+	}
+	names, files := readEpub(t, buf.Bytes())
+
+	if names[0] != "mimetype" || files["mimetype"] != "application/epub+zip" {
+		t.Errorf("first entry must be the mimetype, got %q", names[0])
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".opf") || strings.HasSuffix(name, ".xhtml") {
+			if err := checkWellFormed(files[name]); err != nil {
+				t.Errorf("%s is not well-formed XML: %v\n%s", name, err, files[name])
+			}
+		}
+	}
+
+	expected := map[string][]string{
+		"META-INF/container.xml": {`full-path="OEBPS/content.opf"`},
+		"OEBPS/content.opf": {
+			`<dc:title>A &lt;Book&gt;</dc:title>`,
+			`<dc:creator>Ann</dc:creator>`,
+			`<dc:language>de</dc:language>`,
+			`<dc:identifier id="book-id">urn:uuid:`,
+			`<meta property="dcterms:modified">2020-01-02T03:04:05Z</meta>`,
+			`<meta name="cover" content="image2"/>`,
+			`<item id="image1" href="images/image1.png" media-type="image/png"/>`,
+			`<item id="image2" href="images/image2.jpg" media-type="image/jpeg" properties="cover-image"/>`,
+			`<itemref idref="cover" linear="no"/>
+    <itemref idref="chapter1"/>
+    <itemref idref="chapter2"/>
+    <itemref idref="chapter3"/>`,
+		},
+		"OEBPS/nav.xhtml": {
+			`<nav epub:type="toc" id="toc">`,
+			"<ol>\n" +
+				"<li><a href=\"chapter1.xhtml#one-amp-only\">One &amp; <em>only</em></a>\n" +
+				"<ol>\n" +
+				"<li><a href=\"chapter1.xhtml#sub\">Sub</a>\n" +
+				"<ol>\n" +
+				"<li><a href=\"chapter1.xhtml#deep\">Deep</a></li>\n" +
+				"</ol>\n</li>\n" +
+				"<li><a href=\"chapter1.xhtml#sub-1\">Sub</a></li>\n" +
+				"</ol>\n</li>\n" +
+				"<li><a href=\"chapter2.xhtml\">Chapter 2</a></li>\n" +
+				"<li><a href=\"chapter3.xhtml#three\">Three</a></li>\n" +
+				"</ol>\n",
+		},
+		"OEBPS/cover.xhtml": {`<img src="images/image2.jpg" alt="A &lt;Book&gt;" />`},
+		"OEBPS/chapter1.xhtml": {
+			`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="de" xml:lang="de">`,
+			`<title>One &amp; only</title>`,
+			`<p>Text © here.<br />`,
+			`<img src="images/image1.png" alt="pic" /> <img src="images/image1.png" alt="again" /> ` +
+				`<img src="http://example.com/x.png" alt="remote" />`,
+		},
+		"OEBPS/chapter2.xhtml":    {`<title>A &lt;Book&gt;</title>`, "<body>\n<p>No headers here.</p>\n</body>"},
+		"OEBPS/images/image1.png": {"pic.png"},
+		"OEBPS/images/image2.jpg": {"cover.jpg"},
+	}
+	for name, parts := range expected {
+		for _, part := range parts {
+			if !strings.Contains(files[name], part) {
+				t.Errorf("%s: missing %q in\n%s", name, part, files[name])
+			}
+		}
+	}
+	if len(names) != 10 {
+		t.Errorf("expected 10 entries, got %v", names)
+	}
+}
+
+func TestEpubImagePaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blackfriday-epub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "book")
+	for _, name := range []string{"secret.png", "passwd", filepath.Join("book", "my pic.png"), filepath.Join("book", "notes.txt")} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chapters := [][]byte{[]byte("![a](my%20pic.png?v=2) ![b](my%20pic.png#x) " +
+		"![c](../secret.png) ![d](sub/../../passwd) ![e](notes.txt)\n")}
+	var buf bytes.Buffer
+	if err := Epub(&buf, chapters, EpubMetadata{Title: "T", BaseDir: base}, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	names, files := readEpub(t, buf.Bytes())
+	chapter := files["OEBPS/chapter1.xhtml"]
+	for _, part := range []string{
+		`<img src="images/image1.png" alt="a" />`,
+		`<img src="images/image1.png" alt="b" />`,
+		`<img src="../secret.png" alt="c" />`,
+		`<img src="sub/../../passwd" alt="d" />`,
+		`<img src="notes.txt" alt="e" />`,
+	} {
+		if !strings.Contains(chapter, part) {
+			t.Errorf("missing %q in\n%s", part, chapter)
+		}
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, "OEBPS/images/") && name != "OEBPS/images/image1.png" {
+			t.Errorf("unexpected file in the book: %s", name)
+		}
+	}
+}
+
+func TestEpubMissingImage(t *testing.T) {
+	var buf bytes.Buffer
+	err := Epub(&buf, [][]byte{[]byte("![x](does-not-exist.png)\n")}, EpubMetadata{Title: "T"}, 0, 0)
+	if err == nil {
+		t.Errorf("expected an error for a missing image")
+	}
+}
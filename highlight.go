@@ -0,0 +1,128 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Code block highlighting
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CodeHighlighter renders fenced code blocks for the Html renderer, e.g.
+// with a syntax highlighting library.
+type CodeHighlighter interface {
+	// Highlight returns the HTML for a code block, including the
+	// surrounding <pre> element. lang is the language from the info
+	// string, which is empty if none was given, and info is the full info
+	// string. If ok is false, the block is rendered as if there was no
+	// highlighter.
+	Highlight(lang, info string, code []byte) (html []byte, ok bool)
+}
+
+// Options for code blocks in the info string after the language, e.g.
+//
+//	```go {3,5-7} title="main.go" linenos
+var codeInfoOption = regexp.MustCompile(`\{[\s,-]*\d[\d\s,-]*\}|(?:title|filename)=(?:"[^"]*"|\S+)|\b(?:linenos|showLineNumbers)\b`)
+
+// codeInfo holds what the built-in code block rendering understands of an
+// info string.
+type codeInfo struct {
+	lang        string
+	filename    string
+	lineNumbers bool
+	highlight   map[int]bool // lines to highlight, counted from 1
+}
+
+func parseCodeInfo(info string) codeInfo {
+	var ci codeInfo
+	ci.lang = codeLanguage(info)
+	if i := strings.IndexByte(ci.lang, '{'); i >= 0 {
+		// "go{3}"
+		ci.lang = ci.lang[:i]
+	}
+
+	for _, option := range codeInfoOption.FindAllString(info, -1) {
+		switch {
+		case option[0] == '{':
+			ci.highlight = make(map[int]bool)
+			for _, span := range strings.Split(option[1:len(option)-1], ",") {
+				bounds := strings.SplitN(strings.TrimSpace(span), "-", 2)
+				first, err := strconv.Atoi(bounds[0])
+				if err != nil {
+					continue
+				}
+				last := first
+				if len(bounds) == 2 {
+					if last, err = strconv.Atoi(bounds[1]); err != nil {
+						continue
+					}
+				}
+				for line := first; line <= last && line-first < 10000; line++ {
+					ci.highlight[line] = true
+				}
+			}
+		case strings.Contains(option, "="):
+			ci.filename = strings.Trim(option[strings.IndexByte(option, '=')+1:], `"`)
+		default:
+			ci.lineNumbers = true
+		}
+	}
+	return ci
+}
+
+// codeWithOptions writes a code block with the line numbers, highlighted
+// lines and file name asked for in its info string, and reports whether
+// there were any such options.
+func (options *Html) codeWithOptions(out *bytes.Buffer, text []byte, info string) bool {
+	ci := parseCodeInfo(info)
+	if ci.filename == "" && !ci.lineNumbers && ci.highlight == nil {
+		return false
+	}
+
+	if ci.filename != "" {
+		out.WriteString("<figure class=\"code\">\n<figcaption>")
+		attrEscape(out, []byte(ci.filename))
+		out.WriteString("</figcaption>\n")
+	}
+	if ci.lang == "" {
+		out.WriteString("<pre><code>")
+	} else {
+		out.WriteString("<pre><code class=\"language-")
+		attrEscape(out, []byte(ci.lang))
+		out.WriteString("\">")
+	}
+	lines := bytes.Split(bytes.TrimSuffix(text, []byte("\n")), []byte("\n"))
+	for i, line := range lines {
+		if ci.highlight[i+1] {
+			out.WriteString("<span class=\"line highlight\">")
+		} else {
+			out.WriteString("<span class=\"line\">")
+		}
+		if ci.lineNumbers {
+			out.WriteString("<span class=\"line-number\">")
+			out.WriteString(strconv.Itoa(i + 1))
+			out.WriteString("</span>")
+		}
+		attrEscape(out, line)
+		out.WriteString("</span>\n")
+	}
+	out.WriteString("</code></pre>\n")
+	if ci.filename != "" {
+		out.WriteString("</figure>\n")
+	}
+	return true
+}
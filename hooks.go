@@ -0,0 +1,72 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Render hooks for the HTML renderer
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+)
+
+// HtmlHooks replace or wrap the rendering of single elements by the Html
+// renderer, for example to wrap images in <figure> or to add classes to
+// links, without reimplementing the Renderer interface.
+//
+// Each hook receives the renderer, whose Escape, WriteURL and DoubleSpace
+// helpers it may use, the arguments of the Renderer method it stands in for,
+// and next, which writes the default output. A hook may call next once, with
+// changed arguments, several times or not at all. Hooks that are nil leave
+// the default output alone.
+type HtmlHooks struct {
+	BlockCode  func(r *Html, out *bytes.Buffer, text []byte, info string, next func(out *bytes.Buffer, text []byte, info string))
+	BlockQuote func(r *Html, out *bytes.Buffer, text []byte, next func(out *bytes.Buffer, text []byte))
+	Header     func(r *Html, out *bytes.Buffer, text func() bool, level int, id string, next func(out *bytes.Buffer, text func() bool, level int, id string))
+	HRule      func(r *Html, out *bytes.Buffer, next func(out *bytes.Buffer))
+	List       func(r *Html, out *bytes.Buffer, text func() bool, flags int, next func(out *bytes.Buffer, text func() bool, flags int))
+	ListItem   func(r *Html, out *bytes.Buffer, text []byte, flags int, next func(out *bytes.Buffer, text []byte, flags int))
+	Paragraph  func(r *Html, out *bytes.Buffer, text func() bool, next func(out *bytes.Buffer, text func() bool))
+	Table      func(r *Html, out *bytes.Buffer, header []byte, body []byte, columnData []int, next func(out *bytes.Buffer, header []byte, body []byte, columnData []int))
+
+	AutoLink func(r *Html, out *bytes.Buffer, link []byte, kind int, next func(out *bytes.Buffer, link []byte, kind int))
+	CodeSpan func(r *Html, out *bytes.Buffer, text []byte, next func(out *bytes.Buffer, text []byte))
+	Image    func(r *Html, out *bytes.Buffer, link []byte, title []byte, alt []byte, next func(out *bytes.Buffer, link []byte, title []byte, alt []byte))
+	Link     func(r *Html, out *bytes.Buffer, link []byte, title []byte, content []byte, next func(out *bytes.Buffer, link []byte, title []byte, content []byte))
+}
+
+// Escape writes text with the characters that are special in HTML text and
+// attribute values escaped.
+func (options *Html) Escape(out *bytes.Buffer, text []byte) {
+	attrEscape(out, text)
+}
+
+// WriteURL writes link for use in an attribute value, with the
+// AbsolutePrefix parameter added if it is a relative link.
+func (options *Html) WriteURL(out *bytes.Buffer, link []byte) {
+	if len(link) > 0 {
+		options.maybeWriteAbsolutePrefix(out, link)
+	}
+	attrEscape(out, link)
+}
+
+// DoubleSpace separates a block element from the one before it, the way
+// the renderer does for its own block elements.
+func (options *Html) DoubleSpace(out *bytes.Buffer) {
+	doubleSpace(out)
+}
+
+// CloseTag returns how elements without contents are ended, either " />"
+// or ">" depending on HTML_USE_XHTML.
+func (options *Html) CloseTag() string {
+	return options.closeTag
+}
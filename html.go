@@ -18,6 +18,7 @@ package blackfriday
 import (
 	"bytes"
 	"fmt"
+	"html/template"
 	"regexp"
 	"strconv"
 	"strings"
@@ -45,6 +46,13 @@ const (
 	HTML_SMARTYPANTS_ANGLED_QUOTES             // enable angled double quotes (with HTML_USE_SMARTYPANTS) for double quotes rendering
 	HTML_SMARTYPANTS_QUOTES_NBSP               // enable "French guillemets" (with HTML_USE_SMARTYPANTS)
 	HTML_FOOTNOTE_RETURN_LINKS                 // generate a link at the end of a footnote to return to the source
+	HTML_SLIDES                                // split the document into reveal.js slides
+	HTML_TOC_ORDERED                           // use ordered lists in the table of contents
+	HTML_NUMBER_HEADERS                        // number headers as in "2.3.1"
+	HTML_HEADER_PERMALINKS                     // add a link to itself after the text of each header with an ID
+	HTML_PERMALINKS_BEFORE_TEXT                // put the header links before the text (with HTML_HEADER_PERMALINKS)
+	HTML_DEMOTE_DEEP_HEADERS                   // write headers shifted past level 6 as paragraphs of strong text
+	HTML_SECTIONS                              // wrap each header and what follows it in a <section> (not with HTML_SLIDES)
 )
 
 var (
@@ -56,6 +64,9 @@ var (
 
 	// TODO: improve this regexp to catch all possible entities:
 	htmlEntity = regexp.MustCompile(`&[a-z]{2,5};`)
+
+	// marks the start of the speaker notes of a slide
+	noteMarker = regexp.MustCompile(`^Notes?:\s*`)
 )
 
 type HtmlRendererParameters struct {
@@ -72,6 +83,65 @@ type HtmlRendererParameters struct {
 	HeaderIDPrefix string
 	// If set, add this text to the back of each Header ID, to ensure uniqueness.
 	HeaderIDSuffix string
+	// Added to the level of every header, e.g. 2 to write "#" headers as
+	// <h3> when the document is embedded in a page. Levels past 6 are
+	// written as 6 unless HTML_DEMOTE_DEEP_HEADERS is set. The other
+	// options that take header levels refer to the levels before the shift.
+	HeaderLevelShift int
+	// If set, add this text to the front of every ID made by the renderer,
+	// those of headers and footnotes alike, before HeaderIDPrefix and
+	// FootnoteAnchorPrefix. Use it to keep the IDs of a document embedded
+	// in a page apart from those of the page.
+	IDPrefix string
+	// With HTML_SLIDES, headers of this level start a new slide, and
+	// headers above it start a new stack of vertical slides. Horizontal
+	// rules always start a new slide. If zero, only rules do.
+	SlideLevel int
+	// Path or URL of the reveal.js distribution, used with
+	// HTML_SLIDES and HTML_COMPLETE_PAGE. If blank, "reveal.js" is used.
+	SlideAssetPath string
+	// Name of the reveal.js theme. If blank, "white" is used.
+	SlideTheme string
+	// If set, renders fenced code blocks before the built-in rendering,
+	// which only handles line numbers, highlighted lines and file names.
+	CodeHighlighter CodeHighlighter
+	// Callbacks that replace or wrap the rendering of single elements.
+	Hooks HtmlHooks
+	// If set, raw HTML blocks and inline tags are filtered by this
+	// allowlist instead of being passed through as they are.
+	HtmlPolicy *HtmlPolicy
+	// If set, decides which URLs links, images and raw HTML may use,
+	// instead of HTML_SAFELINK.
+	LinkPolicy *LinkPolicy
+	// With HTML_TOC, only headers from TocMinLevel to TocMaxLevel are
+	// listed in the table of contents. Zero means no limit.
+	TocMinLevel int
+	TocMaxLevel int
+	// Element that wraps the table of contents. If blank, "nav" is used.
+	TocElement string
+	// Class attribute of the element that wraps the table of contents.
+	TocClass string
+	// If set, this text is written in an <h2> before the table of contents.
+	TocTitle string
+	// With HTML_NUMBER_HEADERS, headers are numbered from this level on,
+	// so that 2 leaves level 1 headers unnumbered. If zero, 1 is used.
+	HeaderNumberStart int
+	// Written between the numbers of each level. If blank, "." is used.
+	HeaderNumberSeparator string
+	// Show this text inside the <a> tag of header permalinks, if the
+	// HTML_HEADER_PERMALINKS flag is enabled. If blank, "#" is used.
+	PermalinkContents string
+	// The lang and dir attributes of the <html> element of a complete
+	// page, e.g. "en" and "ltr".
+	Lang string
+	Dir  string
+	// URLs of stylesheets and scripts for a complete page, besides the css
+	// given to HtmlRenderer.
+	Stylesheets []string
+	Scripts     []string
+	// If set, HTML_COMPLETE_PAGE writes the page with this template
+	// instead of the built-in layout, passing it an HtmlPage.
+	PageTemplate *template.Template
 }
 
 // Html is a type that implements the Renderer interface for HTML output.
@@ -87,16 +157,72 @@ type Html struct {
 
 	// table of contents data
 	tocMarker    int
+	tocPlacement int // where the document asked for the table of contents, or -1
 	headerCount  int
 	currentLevel int
 	toc          *bytes.Buffer
 
+	// every header rendered so far, in document order
+	headings []tocHeading
+
 	// Track header IDs to prevent ID collision in a single generation.
 	headerIDs map[string]int
 
+	// the attributes of the header being written, and the numbers of the
+	// headers so far
+	headerAttrs   HeaderAttributes
+	headerNumbers headerNumbers
+
+	// the slide being written with HTML_SLIDES
+	slides htmlSlides
+
+	// the sections open with HTML_SECTIONS
+	sections htmlSections
+
+	// filters inline tags with HtmlPolicy, across the tags of a block
+	rawTags htmlSanitizer
+
+	// rewrites footnote targets, from the parser options
+	urlResolver URLResolver
+
+	// the title block, for the head of a complete page
+	meta Metadata
+
+	// the error from PageTemplate for the last document
+	err error
+
 	smartypants *smartypantsRenderer
 }
 
+// htmlSlides tracks the <section> elements of a slide deck. Slides only
+// start at the top level of the document, so a header in a block quote does
+// not break the slide it is in.
+type htmlSlides struct {
+	out   *bytes.Buffer // the document
+	stack bool          // whether a stack of vertical slides is open
+	open  bool          // whether a slide is open
+	start int           // where the open slide starts
+	body  int           // where the contents of the open slide start
+	notes bool          // whether the speaker notes of the slide are open
+}
+
+// htmlSections tracks the <section> elements around the headers at the top
+// level of the document.
+type htmlSections struct {
+	out    *bytes.Buffer // the document
+	levels []int         // levels of the headers of the open sections
+}
+
+// tocHeading records a rendered header for building tables of contents
+// outside the document, such as an EPUB navigation document.
+type tocHeading struct {
+	level  int
+	text   []byte // rendered contents of the header
+	plain  string // text without markup, if not taken from text
+	id     string
+	number string // with HTML_NUMBER_HEADERS
+}
+
 const (
 	xhtmlClose = " />"
 	htmlClose  = ">"
@@ -107,7 +233,7 @@ const (
 //
 // flags is a set of HTML_* options ORed together.
 // title is the title of the document, and css is a URL for the document's
-// stylesheet.
+// stylesheet. If title is blank, the title from the title block is used.
 // title and css are only used when HTML_COMPLETE_PAGE is selected.
 func HtmlRenderer(flags int, title string, css string) Renderer {
 	return HtmlRendererWithParameters(flags, title, css, HtmlRendererParameters{})
@@ -121,9 +247,30 @@ func HtmlRendererWithParameters(flags int, title string,
 		closeTag = xhtmlClose
 	}
 
+	renderParameters.HeaderIDPrefix = renderParameters.IDPrefix + renderParameters.HeaderIDPrefix
+	renderParameters.FootnoteAnchorPrefix = renderParameters.IDPrefix + renderParameters.FootnoteAnchorPrefix
+
 	if renderParameters.FootnoteReturnLinkContents == "" {
 		renderParameters.FootnoteReturnLinkContents = `<sup>[return]</sup>`
 	}
+	if renderParameters.SlideAssetPath == "" {
+		renderParameters.SlideAssetPath = "reveal.js"
+	}
+	if renderParameters.SlideTheme == "" {
+		renderParameters.SlideTheme = "white"
+	}
+	if renderParameters.TocElement == "" {
+		renderParameters.TocElement = "nav"
+	}
+	if renderParameters.HeaderNumberStart < 1 {
+		renderParameters.HeaderNumberStart = 1
+	}
+	if renderParameters.HeaderNumberSeparator == "" {
+		renderParameters.HeaderNumberSeparator = "."
+	}
+	if renderParameters.PermalinkContents == "" {
+		renderParameters.PermalinkContents = "#"
+	}
 
 	return &Html{
 		flags:      flags,
@@ -132,6 +279,7 @@ func HtmlRendererWithParameters(flags int, title string,
 		css:        css,
 		parameters: renderParameters,
 
+		tocPlacement: -1,
 		headerCount:  0,
 		currentLevel: 0,
 		toc:          new(bytes.Buffer),
@@ -194,15 +342,54 @@ func (options *Html) GetFlags() int {
 }
 
 func (options *Html) TitleBlock(out *bytes.Buffer, text []byte) {
+	// the title gets a slide of its own
+	options.slideBreak(out, 0)
 	text = bytes.TrimPrefix(text, []byte("% "))
 	text = bytes.Replace(text, []byte("\n% "), []byte("\n"), -1)
 	out.WriteString("<h1 class=\"title\">")
 	out.Write(text)
 	out.WriteString("\n</h1>")
+	options.slideBreak(out, 0)
 }
 
 func (options *Html) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	options.HeaderWithAttributes(out, text, level, HeaderAttributes{ID: id})
+}
+
+// HeaderWithAttributes writes a header with the classes and numbering asked
+// for in its attributes.
+func (options *Html) HeaderWithAttributes(out *bytes.Buffer, text func() bool, level int, attrs HeaderAttributes) {
+	options.slideBreak(out, level)
+	text = options.closeRawTags(out, text)
+	options.headerAttrs = attrs
+	if hook := options.parameters.Hooks.Header; hook != nil {
+		hook(options, out, text, level, attrs.ID, options.header)
+		return
+	}
+	options.header(out, text, level, attrs.ID)
+}
+
+func (options *Html) header(out *bytes.Buffer, text func() bool, level int, id string) {
+	tag := options.headerTag(level)
+	if tag > 6 {
+		// too deep to be a header
+		options.headerAttrs = HeaderAttributes{}
+		options.paragraph(out, func() bool {
+			out.WriteString("<strong>")
+			ok := text()
+			out.WriteString("</strong>")
+			return ok
+		})
+		return
+	}
+
 	marker := out.Len()
+	sections := options.sections.levels
+	section := options.isSection(out)
+	if section {
+		sections = append([]int(nil), sections...)
+		options.closeSections(out, level)
+	}
 	doubleSpace(out)
 
 	if id == "" && options.flags&HTML_TOC != 0 {
@@ -219,24 +406,97 @@ func (options *Html) Header(out *bytes.Buffer, text func() bool, level int, id s
 		if options.parameters.HeaderIDSuffix != "" {
 			id = id + options.parameters.HeaderIDSuffix
 		}
+	}
 
-		out.WriteString(fmt.Sprintf("<h%d id=\"%s\">", level, id))
+	if section {
+		// the section takes the id of the header
+		if id != "" {
+			out.WriteString(fmt.Sprintf("<section id=\"%s\">\n", id))
+		} else {
+			out.WriteString("<section>\n")
+		}
+		options.sections.levels = append(options.sections.levels, level)
+		out.WriteString(fmt.Sprintf("<h%d", tag))
+	} else if id != "" {
+		out.WriteString(fmt.Sprintf("<h%d id=\"%s\"", tag, id))
 	} else {
-		out.WriteString(fmt.Sprintf("<h%d>", level))
+		out.WriteString(fmt.Sprintf("<h%d", tag))
+	}
+	if classes := options.headerAttrs.Classes; len(classes) > 0 {
+		out.WriteString(" class=\"")
+		attrEscape(out, []byte(strings.Join(classes, " ")))
+		out.WriteString("\"")
+	}
+	out.WriteString(">")
+
+	permalink := id != "" && options.flags&HTML_HEADER_PERMALINKS != 0
+	if permalink && options.flags&HTML_PERMALINKS_BEFORE_TEXT != 0 {
+		options.permalink(out, id)
+		out.WriteByte(' ')
 	}
 
 	tocMarker := out.Len()
+	number := ""
+	if options.flags&HTML_NUMBER_HEADERS != 0 && !options.headerAttrs.HasClass("unnumbered") {
+		number = options.headerNumbers.next(level, options.parameters.HeaderNumberStart,
+			options.parameters.HeaderNumberSeparator)
+	}
+	if number != "" {
+		out.WriteString("<span class=\"header-section-number\">")
+		attrEscape(out, []byte(number))
+		out.WriteString("</span> ")
+	}
+	options.headerAttrs = HeaderAttributes{}
 	if !text() {
 		out.Truncate(marker)
+		options.sections.levels = sections
 		return
 	}
 
+	options.headings = append(options.headings, tocHeading{
+		level:  level,
+		text:   append([]byte(nil), out.Bytes()[tocMarker:]...),
+		id:     id,
+		number: number,
+	})
+
 	// are we building a table of contents?
 	if options.flags&HTML_TOC != 0 {
-		options.TocHeaderWithAnchor(out.Bytes()[tocMarker:], level, id)
+		if options.inToc(level) {
+			options.TocHeaderWithAnchor(out.Bytes()[tocMarker:], level-options.tocMinLevel()+1, id)
+		} else {
+			options.headerCount++
+		}
 	}
 
-	out.WriteString(fmt.Sprintf("</h%d>\n", level))
+	if permalink && options.flags&HTML_PERMALINKS_BEFORE_TEXT == 0 {
+		out.WriteByte(' ')
+		options.permalink(out, id)
+	}
+
+	out.WriteString(fmt.Sprintf("</h%d>\n", tag))
+}
+
+// headerTag returns the level of the <h1> to <h6> element for a header of
+// the given level, which is past 6 if it should be demoted to a paragraph.
+func (options *Html) headerTag(level int) int {
+	level += options.parameters.HeaderLevelShift
+	if level < 1 {
+		return 1
+	}
+	if level > 6 && options.flags&HTML_DEMOTE_DEEP_HEADERS == 0 {
+		return 6
+	}
+	return level
+}
+
+// permalink writes a link to the header with the given id.
+func (options *Html) permalink(out *bytes.Buffer, id string) {
+	out.WriteString("<a class=\"anchor\" href=\"#")
+	attrEscape(out, []byte(id))
+	out.WriteString("\">")
+	out.WriteString(options.parameters.PermalinkContents)
+	out.WriteString("</a>")
 }
 
 func (options *Html) BlockHtml(out *bytes.Buffer, text []byte) {
@@ -245,11 +505,26 @@ func (options *Html) BlockHtml(out *bytes.Buffer, text []byte) {
 	}
 
 	doubleSpace(out)
+	if policy := options.parameters.HtmlPolicy; policy != nil {
+		text = policy.sanitize(text, options.parameters.LinkPolicy)
+	}
 	out.Write(text)
 	out.WriteByte('\n')
 }
 
 func (options *Html) HRule(out *bytes.Buffer) {
+	if options.isSlideBreak(out) {
+		options.slideBreak(out, 0)
+		return
+	}
+	if hook := options.parameters.Hooks.HRule; hook != nil {
+		hook(options, out, options.hrule)
+		return
+	}
+	options.hrule(out)
+}
+
+func (options *Html) hrule(out *bytes.Buffer) {
 	doubleSpace(out)
 	out.WriteString("<hr")
 	out.WriteString(options.closeTag)
@@ -257,8 +532,26 @@ func (options *Html) HRule(out *bytes.Buffer) {
 }
 
 func (options *Html) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	if hook := options.parameters.Hooks.BlockCode; hook != nil {
+		hook(options, out, text, info, options.blockCode)
+		return
+	}
+	options.blockCode(out, text, info)
+}
+
+func (options *Html) blockCode(out *bytes.Buffer, text []byte, info string) {
 	doubleSpace(out)
 
+	if highlighter := options.parameters.CodeHighlighter; highlighter != nil {
+		if html, ok := highlighter.Highlight(parseCodeInfo(info).lang, info, text); ok {
+			out.Write(html)
+			return
+		}
+	}
+	if options.codeWithOptions(out, text, info) {
+		return
+	}
+
 	endOfLang := strings.IndexAny(info, "\t ")
 	if endOfLang < 0 {
 		endOfLang = len(info)
@@ -276,6 +569,14 @@ func (options *Html) BlockCode(out *bytes.Buffer, text []byte, info string) {
 }
 
 func (options *Html) BlockQuote(out *bytes.Buffer, text []byte) {
+	if hook := options.parameters.Hooks.BlockQuote; hook != nil {
+		hook(options, out, text, options.blockQuote)
+		return
+	}
+	options.blockQuote(out, text)
+}
+
+func (options *Html) blockQuote(out *bytes.Buffer, text []byte) {
 	doubleSpace(out)
 	out.WriteString("<blockquote>\n")
 	out.Write(text)
@@ -283,6 +584,14 @@ func (options *Html) BlockQuote(out *bytes.Buffer, text []byte) {
 }
 
 func (options *Html) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	if hook := options.parameters.Hooks.Table; hook != nil {
+		hook(options, out, header, body, columnData, options.table)
+		return
+	}
+	options.table(out, header, body, columnData)
+}
+
+func (options *Html) table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
 	doubleSpace(out)
 	out.WriteString("<table>\n<thead>\n")
 	out.Write(header)
@@ -299,6 +608,7 @@ func (options *Html) TableRow(out *bytes.Buffer, text []byte) {
 }
 
 func (options *Html) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
+	text = options.closeRawTagsIn(text)
 	doubleSpace(out)
 	switch align {
 	case TABLE_ALIGNMENT_LEFT:
@@ -316,6 +626,7 @@ func (options *Html) TableHeaderCell(out *bytes.Buffer, text []byte, align int)
 }
 
 func (options *Html) TableCell(out *bytes.Buffer, text []byte, align int) {
+	text = options.closeRawTagsIn(text)
 	doubleSpace(out)
 	switch align {
 	case TABLE_ALIGNMENT_LEFT:
@@ -333,6 +644,9 @@ func (options *Html) TableCell(out *bytes.Buffer, text []byte, align int) {
 }
 
 func (options *Html) Footnotes(out *bytes.Buffer, text func() bool) {
+	if options.isSection(out) {
+		options.closeSections(out, 0)
+	}
 	out.WriteString("<div class=\"footnotes\">\n")
 	options.HRule(out)
 	options.List(out, text, LIST_TYPE_ORDERED)
@@ -351,10 +665,8 @@ func (options *Html) FootnoteItem(out *bytes.Buffer, name, text []byte, flags in
 	out.WriteString(`">`)
 	out.Write(text)
 	if options.flags&HTML_FOOTNOTE_RETURN_LINKS != 0 {
-		out.WriteString(` <a class="footnote-return" href="#`)
-		out.WriteString(`fnref:`)
-		out.WriteString(options.parameters.FootnoteAnchorPrefix)
-		out.Write(slug)
+		out.WriteString(` <a class="footnote-return" href="`)
+		options.footnoteTarget(out, "fnref:"+options.parameters.FootnoteAnchorPrefix+string(slug))
 		out.WriteString(`">`)
 		out.WriteString(options.parameters.FootnoteReturnLinkContents)
 		out.WriteString(`</a>`)
@@ -363,6 +675,14 @@ func (options *Html) FootnoteItem(out *bytes.Buffer, name, text []byte, flags in
 }
 
 func (options *Html) List(out *bytes.Buffer, text func() bool, flags int) {
+	if hook := options.parameters.Hooks.List; hook != nil {
+		hook(options, out, text, flags, options.list)
+		return
+	}
+	options.list(out, text, flags)
+}
+
+func (options *Html) list(out *bytes.Buffer, text func() bool, flags int) {
 	marker := out.Len()
 	doubleSpace(out)
 
@@ -387,6 +707,15 @@ func (options *Html) List(out *bytes.Buffer, text func() bool, flags int) {
 }
 
 func (options *Html) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	text = options.closeRawTagsIn(text)
+	if hook := options.parameters.Hooks.ListItem; hook != nil {
+		hook(options, out, text, flags, options.listItem)
+		return
+	}
+	options.listItem(out, text, flags)
+}
+
+func (options *Html) listItem(out *bytes.Buffer, text []byte, flags int) {
 	if (flags&LIST_ITEM_CONTAINS_BLOCK != 0 && flags&LIST_TYPE_DEFINITION == 0) ||
 		flags&LIST_ITEM_BEGINNING_OF_LIST != 0 {
 		doubleSpace(out)
@@ -409,6 +738,15 @@ func (options *Html) ListItem(out *bytes.Buffer, text []byte, flags int) {
 }
 
 func (options *Html) Paragraph(out *bytes.Buffer, text func() bool) {
+	text = options.closeRawTags(out, text)
+	if hook := options.parameters.Hooks.Paragraph; hook != nil {
+		hook(options, out, text, options.paragraph)
+		return
+	}
+	options.paragraph(out, text)
+}
+
+func (options *Html) paragraph(out *bytes.Buffer, text func() bool) {
 	marker := out.Len()
 	doubleSpace(out)
 
@@ -417,12 +755,113 @@ func (options *Html) Paragraph(out *bytes.Buffer, text func() bool) {
 		out.Truncate(marker)
 		return
 	}
+
+	// the rest of the slide after "Note:" is for the speaker
+	if options.isSlideBreak(out) && !options.slides.notes {
+		content := out.Bytes()[marker:]
+		start := bytes.Index(content, []byte("<p>")) + len("<p>")
+		if note := noteMarker.FindIndex(content[start:]); note != nil {
+			rest := append([]byte(nil), content[start+note[1]:]...)
+			out.Truncate(marker)
+			doubleSpace(out)
+			out.WriteString("<aside class=\"notes\">\n")
+			options.slides.notes = true
+			if len(rest) == 0 {
+				return
+			}
+			out.WriteString("<p>")
+			out.Write(rest)
+		}
+	}
 	out.WriteString("</p>\n")
 }
 
+// isSlideBreak tells whether a header or rule written to out may start a new
+// slide.
+func (options *Html) isSlideBreak(out *bytes.Buffer) bool {
+	return options.flags&HTML_SLIDES != 0 && out == options.slides.out
+}
+
+// slideBreak starts a new slide if a header of the given level should start
+// one, or unconditionally if level is 0.
+func (options *Html) slideBreak(out *bytes.Buffer, level int) {
+	if !options.isSlideBreak(out) {
+		return
+	}
+	slideLevel := options.parameters.SlideLevel
+	switch {
+	case level == 0, level == slideLevel:
+		options.endSlide(out)
+	case level < slideLevel:
+		options.endSlide(out)
+		if options.slides.stack {
+			out.WriteString("</section>\n")
+		}
+		doubleSpace(out)
+		out.WriteString("<section>\n")
+		options.slides.stack = true
+	default:
+		return
+	}
+	options.beginSlide(out)
+}
+
+func (options *Html) beginSlide(out *bytes.Buffer) {
+	options.slides.open = true
+	options.slides.start = out.Len()
+	doubleSpace(out)
+	out.WriteString("<section>\n")
+	options.slides.body = out.Len()
+}
+
+// endSlide closes the open slide, or drops it if it is empty.
+func (options *Html) endSlide(out *bytes.Buffer) {
+	slides := &options.slides
+	if !slides.open {
+		return
+	}
+	slides.open = false
+	if slides.notes {
+		out.WriteString("</aside>\n")
+		slides.notes = false
+	}
+	if slides.body >= out.Len() || len(bytes.TrimSpace(out.Bytes()[slides.body:])) == 0 {
+		out.Truncate(slides.start)
+		return
+	}
+	if !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	out.WriteString("</section>\n")
+}
+
+// isSection tells whether a header written to out starts a section.
+func (options *Html) isSection(out *bytes.Buffer) bool {
+	return options.flags&HTML_SECTIONS != 0 && options.flags&HTML_SLIDES == 0 && out == options.sections.out
+}
+
+// closeSections closes the open sections of headers of the given level or
+// above, or all of them if level is 0.
+func (options *Html) closeSections(out *bytes.Buffer, level int) {
+	levels := options.sections.levels
+	for len(levels) > 0 && levels[len(levels)-1] >= level {
+		out.WriteString("</section>\n")
+		levels = levels[:len(levels)-1]
+	}
+	options.sections.levels = levels
+}
+
 func (options *Html) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	if hook := options.parameters.Hooks.AutoLink; hook != nil {
+		hook(options, out, link, kind, options.autoLink)
+		return
+	}
+	options.autoLink(out, link, kind)
+}
+
+func (options *Html) autoLink(out *bytes.Buffer, link []byte, kind int) {
 	skipRanges := htmlEntity.FindAllIndex(link, -1)
-	if options.flags&HTML_SAFELINK != 0 && !isSafeLink(link) && kind != LINK_TYPE_EMAIL {
+	if !options.allowsAutoLink(link, kind) {
 		// mark it but don't link it if it is not a safe link: no smartypants
 		out.WriteString("<tt>")
 		entityEscapeWithSkip(out, link, skipRanges)
@@ -476,6 +915,14 @@ func (options *Html) AutoLink(out *bytes.Buffer, link []byte, kind int) {
 }
 
 func (options *Html) CodeSpan(out *bytes.Buffer, text []byte) {
+	if hook := options.parameters.Hooks.CodeSpan; hook != nil {
+		hook(options, out, text, options.codeSpan)
+		return
+	}
+	options.codeSpan(out, text)
+}
+
+func (options *Html) codeSpan(out *bytes.Buffer, text []byte) {
 	out.WriteString("<code>")
 	attrEscape(out, text)
 	out.WriteString("</code>")
@@ -496,6 +943,28 @@ func (options *Html) Emphasis(out *bytes.Buffer, text []byte) {
 	out.WriteString("</em>")
 }
 
+// allowsLink tells whether link may be linked to, or used as the source of
+// an image.
+func (options *Html) allowsLink(link []byte, image bool) bool {
+	if policy := options.parameters.LinkPolicy; policy != nil {
+		return policy.Allows(link, image)
+	}
+	// images were never checked by HTML_SAFELINK
+	return image || options.flags&HTML_SAFELINK == 0 || isSafeLink(link)
+}
+
+func (options *Html) allowsAutoLink(link []byte, kind int) bool {
+	if kind == LINK_TYPE_EMAIL {
+		if options.parameters.LinkPolicy == nil {
+			return true
+		}
+		if !bytes.HasPrefix(link, []byte("mailto:")) {
+			link = append([]byte("mailto:"), link...)
+		}
+	}
+	return options.allowsLink(link, false)
+}
+
 func (options *Html) maybeWriteAbsolutePrefix(out *bytes.Buffer, link []byte) {
 	if options.parameters.AbsolutePrefix != "" && isRelativeLink(link) && link[0] != '.' {
 		out.WriteString(options.parameters.AbsolutePrefix)
@@ -506,9 +975,21 @@ func (options *Html) maybeWriteAbsolutePrefix(out *bytes.Buffer, link []byte) {
 }
 
 func (options *Html) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	if hook := options.parameters.Hooks.Image; hook != nil {
+		hook(options, out, link, title, alt, options.image)
+		return
+	}
+	options.image(out, link, title, alt)
+}
+
+func (options *Html) image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
 	if options.flags&HTML_SKIP_IMAGES != 0 {
 		return
 	}
+	if !options.allowsLink(link, true) {
+		attrEscape(out, alt)
+		return
+	}
 
 	out.WriteString("<img src=\"")
 	options.maybeWriteAbsolutePrefix(out, link)
@@ -533,6 +1014,14 @@ func (options *Html) LineBreak(out *bytes.Buffer) {
 }
 
 func (options *Html) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if hook := options.parameters.Hooks.Link; hook != nil {
+		hook(options, out, link, title, content, options.link)
+		return
+	}
+	options.link(out, link, title, content)
+}
+
+func (options *Html) link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
 	if options.flags&HTML_SKIP_LINKS != 0 {
 		// write the link text out but don't link it, just mark it with typewriter font
 		out.WriteString("<tt>")
@@ -541,7 +1030,7 @@ func (options *Html) Link(out *bytes.Buffer, link []byte, title []byte, content
 		return
 	}
 
-	if options.flags&HTML_SAFELINK != 0 && !isSafeLink(link) {
+	if !options.allowsLink(link, false) {
 		// write the link text out but don't link it, just mark it with typewriter font
 		out.WriteString("<tt>")
 		attrEscape(out, content)
@@ -594,9 +1083,42 @@ func (options *Html) RawHtmlTag(out *bytes.Buffer, text []byte) {
 	if options.flags&HTML_SKIP_IMAGES != 0 && isHtmlTag(text, "img") {
 		return
 	}
+	if policy := options.parameters.HtmlPolicy; policy != nil {
+		options.rawTags.policy = policy
+		options.rawTags.links = options.parameters.LinkPolicy
+		options.rawTags.write(out, text)
+		return
+	}
 	out.Write(text)
 }
 
+// closeRawTags wraps the text of a block so that the inline tags it leaves
+// open are closed at its end.
+func (options *Html) closeRawTags(out *bytes.Buffer, text func() bool) func() bool {
+	if options.parameters.HtmlPolicy == nil {
+		return text
+	}
+	return func() bool {
+		if !text() {
+			return false
+		}
+		options.rawTags.close(out)
+		return true
+	}
+}
+
+// closeRawTagsIn is closeRawTags for blocks whose text has been rendered
+// already.
+func (options *Html) closeRawTagsIn(text []byte) []byte {
+	if len(options.rawTags.open) == 0 {
+		return text
+	}
+	var closed bytes.Buffer
+	closed.Write(text)
+	options.rawTags.close(&closed)
+	return closed.Bytes()
+}
+
 func (options *Html) TripleEmphasis(out *bytes.Buffer, text []byte) {
 	out.WriteString("<strong><em>")
 	out.Write(text)
@@ -615,15 +1137,32 @@ func (options *Html) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
 	out.WriteString(`fnref:`)
 	out.WriteString(options.parameters.FootnoteAnchorPrefix)
 	out.Write(slug)
-	out.WriteString(`"><a href="#`)
-	out.WriteString(`fn:`)
-	out.WriteString(options.parameters.FootnoteAnchorPrefix)
-	out.Write(slug)
+	out.WriteString(`"><a href="`)
+	options.footnoteTarget(out, "fn:"+options.parameters.FootnoteAnchorPrefix+string(slug))
 	out.WriteString(`">`)
 	out.WriteString(strconv.Itoa(id))
 	out.WriteString(`</a></sup>`)
 }
 
+// footnoteTarget writes the link to the footnote anchor with the given ID.
+func (options *Html) footnoteTarget(out *bytes.Buffer, id string) {
+	if options.urlResolver == nil {
+		out.WriteString("#" + id)
+		return
+	}
+	attrEscape(out, options.urlResolver(URL_FOOTNOTE, []byte("#"+id)))
+}
+
+func (options *Html) SetURLResolver(resolver URLResolver) {
+	options.urlResolver = resolver
+}
+
+// SetMetadata takes the title block, which fills in the title, authors and
+// date of a complete page.
+func (options *Html) SetMetadata(meta Metadata) {
+	options.meta = meta
+}
+
 func (options *Html) Entity(out *bytes.Buffer, entity []byte) {
 	out.Write(entity)
 }
@@ -666,23 +1205,53 @@ func (options *Html) Smartypants(out *bytes.Buffer, text []byte) {
 }
 
 func (options *Html) DocumentHeader(out *bytes.Buffer) {
-	if options.flags&HTML_COMPLETE_PAGE == 0 {
-		return
+	options.rawTags = htmlSanitizer{}
+	options.headings = nil
+	options.headerNumbers = headerNumbers{}
+	options.err = nil
+	if options.flags&HTML_COMPLETE_PAGE != 0 && options.parameters.PageTemplate == nil {
+		options.pageHeader(out)
+	}
+	options.tocMarker = out.Len()
+	options.tocPlacement = -1
+	options.sections = htmlSections{out: out}
+	if options.flags&HTML_SLIDES != 0 {
+		options.slides = htmlSlides{out: out}
+		options.beginSlide(out)
 	}
+}
+
+func (options *Html) pageHeader(out *bytes.Buffer) {
 
 	ending := ""
 	if options.flags&HTML_USE_XHTML != 0 {
 		out.WriteString("<!DOCTYPE html PUBLIC \"-//W3C//DTD XHTML 1.0 Transitional//EN\" ")
 		out.WriteString("\"http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd\">\n")
-		out.WriteString("<html xmlns=\"http://www.w3.org/1999/xhtml\">\n")
+		out.WriteString("<html xmlns=\"http://www.w3.org/1999/xhtml\"")
+		if options.parameters.Lang != "" {
+			out.WriteString(" xml:lang=\"")
+			attrEscape(out, []byte(options.parameters.Lang))
+			out.WriteString("\"")
+		}
 		ending = " /"
 	} else {
 		out.WriteString("<!DOCTYPE html>\n")
-		out.WriteString("<html>\n")
+		out.WriteString("<html")
+	}
+	if options.parameters.Lang != "" {
+		out.WriteString(" lang=\"")
+		attrEscape(out, []byte(options.parameters.Lang))
+		out.WriteString("\"")
 	}
+	if options.parameters.Dir != "" {
+		out.WriteString(" dir=\"")
+		attrEscape(out, []byte(options.parameters.Dir))
+		out.WriteString("\"")
+	}
+	out.WriteString(">\n")
 	out.WriteString("<head>\n")
 	out.WriteString("  <title>")
-	options.NormalText(out, []byte(options.title))
+	options.NormalText(out, []byte(options.pageTitle()))
 	out.WriteString("</title>\n")
 	out.WriteString("  <meta name=\"GENERATOR\" content=\"Blackfriday Markdown Processor v")
 	out.WriteString(VERSION)
@@ -692,45 +1261,91 @@ func (options *Html) DocumentHeader(out *bytes.Buffer) {
 	out.WriteString("  <meta charset=\"utf-8\"")
 	out.WriteString(ending)
 	out.WriteString(">\n")
-	if options.css != "" {
+	for _, author := range options.meta.Authors {
+		out.WriteString("  <meta name=\"author\" content=\"")
+		attrEscape(out, []byte(author))
+		out.WriteString("\"")
+		out.WriteString(ending)
+		out.WriteString(">\n")
+	}
+	if options.meta.Date != "" {
+		out.WriteString("  <meta name=\"dcterms.date\" content=\"")
+		attrEscape(out, []byte(options.meta.Date))
+		out.WriteString("\"")
+		out.WriteString(ending)
+		out.WriteString(">\n")
+	}
+	for _, css := range options.stylesheets() {
 		out.WriteString("  <link rel=\"stylesheet\" type=\"text/css\" href=\"")
-		attrEscape(out, []byte(options.css))
+		attrEscape(out, []byte(css))
 		out.WriteString("\"")
 		out.WriteString(ending)
 		out.WriteString(">\n")
 	}
+	if options.flags&HTML_SLIDES != 0 {
+		assets := strings.TrimSuffix(options.parameters.SlideAssetPath, "/")
+		for _, css := range []string{"/dist/reveal.css", "/dist/theme/" + options.parameters.SlideTheme + ".css"} {
+			out.WriteString("  <link rel=\"stylesheet\" href=\"")
+			attrEscape(out, []byte(assets+css))
+			out.WriteString("\"")
+			out.WriteString(ending)
+			out.WriteString(">\n")
+		}
+	}
 	out.WriteString("</head>\n")
 	out.WriteString("<body>\n")
-
-	options.tocMarker = out.Len()
+	if options.flags&HTML_SLIDES != 0 {
+		out.WriteString("<div class=\"reveal\">\n")
+		out.WriteString("<div class=\"slides\">\n")
+	}
 }
 
 func (options *Html) DocumentFooter(out *bytes.Buffer) {
-	// finalize and insert the table of contents
+	if options.isSection(out) {
+		options.closeSections(out, 0)
+	}
+	if options.flags&HTML_SLIDES != 0 {
+		options.endSlide(out)
+		if options.slides.stack {
+			out.WriteString("</section>\n")
+			options.slides.stack = false
+		}
+	}
+
+	// finalize and insert the table of contents, which a page template
+	// places itself unless there is a marker for it
 	if options.flags&HTML_TOC != 0 {
 		options.TocFinalize()
-
+	}
+	if options.flags&HTML_TOC != 0 && !options.tocInPage() {
 		// now we have to insert the table of contents into the document
 		var temp bytes.Buffer
-
-		// start by making a copy of everything after the document header
-		temp.Write(out.Bytes()[options.tocMarker:])
+		placed := options.tocPlacement >= 0 && options.tocPlacement <= out.Len() &&
+			options.flags&HTML_OMIT_CONTENTS == 0
+
+		// start by making a copy of everything after the document header,
+		// or after the marker in the document
+		marker := options.tocMarker
+		if placed {
+			marker = options.tocPlacement
+		}
+		temp.Write(out.Bytes()[marker:])
 
 		// now clear the copied material from the main output buffer
-		out.Truncate(options.tocMarker)
+		out.Truncate(marker)
 
 		// corner case spacing issue
-		if options.flags&HTML_COMPLETE_PAGE != 0 {
+		if placed {
+			doubleSpace(out)
+		} else if options.flags&HTML_COMPLETE_PAGE != 0 {
 			out.WriteByte('\n')
 		}
 
 		// insert the table of contents
-		out.WriteString("<nav>\n")
-		out.Write(options.toc.Bytes())
-		out.WriteString("</nav>\n")
+		options.tocWrapper(out)
 
 		// corner case spacing issue
-		if options.flags&HTML_COMPLETE_PAGE == 0 && options.flags&HTML_OMIT_CONTENTS == 0 {
+		if !placed && options.flags&HTML_COMPLETE_PAGE == 0 && options.flags&HTML_OMIT_CONTENTS == 0 {
 			out.WriteByte('\n')
 		}
 
@@ -740,13 +1355,86 @@ func (options *Html) DocumentFooter(out *bytes.Buffer) {
 		}
 	}
 
+	if options.flags&HTML_COMPLETE_PAGE != 0 && options.parameters.PageTemplate != nil {
+		options.writePage(out)
+		return
+	}
+
 	if options.flags&HTML_COMPLETE_PAGE != 0 {
+		if options.flags&HTML_SLIDES != 0 {
+			assets := strings.TrimSuffix(options.parameters.SlideAssetPath, "/")
+			out.WriteString("</div>\n")
+			out.WriteString("</div>\n")
+			for _, js := range []string{"/dist/reveal.js", "/plugin/notes/notes.js"} {
+				out.WriteString("<script src=\"")
+				attrEscape(out, []byte(assets+js))
+				out.WriteString("\"></script>\n")
+			}
+			out.WriteString("<script>Reveal.initialize({hash: true, plugins: [RevealNotes]});</script>")
+		}
+		for _, js := range options.parameters.Scripts {
+			out.WriteString("\n<script src=\"")
+			attrEscape(out, []byte(js))
+			out.WriteString("\"></script>")
+		}
 		out.WriteString("\n</body>\n")
 		out.WriteString("</html>\n")
 	}
 
 }
 
+// TocMarker marks the place of the table of contents in the document, if it
+// is not at the start. Only the first marker is used. Without HTML_TOC the
+// marker is left as it is.
+func (options *Html) TocMarker(out *bytes.Buffer) bool {
+	if options.flags&HTML_TOC == 0 {
+		return false
+	}
+	if options.tocPlacement < 0 {
+		options.tocPlacement = out.Len()
+	}
+	return true
+}
+
+func (options *Html) tocWrapper(out *bytes.Buffer) {
+	out.WriteString("<" + options.parameters.TocElement)
+	if options.parameters.TocClass != "" {
+		out.WriteString(" class=\"")
+		attrEscape(out, []byte(options.parameters.TocClass))
+		out.WriteString("\"")
+	}
+	out.WriteString(">\n")
+	if options.parameters.TocTitle != "" {
+		out.WriteString("<h2>")
+		attrEscape(out, []byte(options.parameters.TocTitle))
+		out.WriteString("</h2>\n")
+	}
+	out.Write(options.toc.Bytes())
+	out.WriteString("</" + options.parameters.TocElement + ">\n")
+}
+
+// inToc reports whether headers of the given level are listed in the table
+// of contents.
+func (options *Html) inToc(level int) bool {
+	max := options.parameters.TocMaxLevel
+	return level >= options.tocMinLevel() && (max == 0 || level <= max)
+}
+
+func (options *Html) tocMinLevel() int {
+	if options.parameters.TocMinLevel < 1 {
+		return 1
+	}
+	return options.parameters.TocMinLevel
+}
+
+// tocList is the list element of the table of contents.
+func (options *Html) tocList() string {
+	if options.flags&HTML_TOC_ORDERED != 0 {
+		return "ol"
+	}
+	return "ul"
+}
+
 func (options *Html) TocHeaderWithAnchor(text []byte, level int, anchor string) {
 	for level > options.currentLevel {
 		switch {
@@ -761,12 +1449,12 @@ func (options *Html) TocHeaderWithAnchor(text []byte, level int, anchor string)
 		if options.toc.Len() > 0 {
 			options.toc.WriteByte('\n')
 		}
-		options.toc.WriteString("<ul>\n")
+		options.toc.WriteString("<" + options.tocList() + ">\n")
 		options.currentLevel++
 	}
 
 	for level < options.currentLevel {
-		options.toc.WriteString("</ul>")
+		options.toc.WriteString("</" + options.tocList() + ">")
 		if options.currentLevel > 1 {
 			options.toc.WriteString("</li>\n")
 		}
@@ -794,12 +1482,12 @@ func (options *Html) TocHeader(text []byte, level int) {
 
 func (options *Html) TocFinalize() {
 	for options.currentLevel > 1 {
-		options.toc.WriteString("</ul></li>\n")
+		options.toc.WriteString("</" + options.tocList() + "></li>\n")
 		options.currentLevel--
 	}
 
 	if options.currentLevel > 0 {
-		options.toc.WriteString("</ul>\n")
+		options.toc.WriteString("</" + options.tocList() + ">\n")
 	}
 }
 
@@ -926,19 +1614,25 @@ func isRelativeLink(link []byte) (yes bool) {
 }
 
 func (options *Html) ensureUniqueHeaderID(id string) string {
-	for count, found := options.headerIDs[id]; found; count, found = options.headerIDs[id] {
+	return uniqueID(options.headerIDs, id)
+}
+
+// uniqueID returns id, or id with a numeric suffix if it has been seen
+// before, and records the result in ids.
+func uniqueID(ids map[string]int, id string) string {
+	for count, found := ids[id]; found; count, found = ids[id] {
 		tmp := fmt.Sprintf("%s-%d", id, count+1)
 
-		if _, tmpFound := options.headerIDs[tmp]; !tmpFound {
-			options.headerIDs[id] = count + 1
+		if _, tmpFound := ids[tmp]; !tmpFound {
+			ids[id] = count + 1
 			id = tmp
 		} else {
 			id = id + "-1"
 		}
 	}
 
-	if _, found := options.headerIDs[id]; !found {
-		options.headerIDs[id] = 0
+	if _, found := ids[id]; !found {
+		ids[id] = 0
 	}
 
 	return id
@@ -236,6 +236,7 @@ func link(p *parser, out *bytes.Buffer, data []byte, offset int) int {
 	var (
 		i                       = 1
 		noteId                  int
+		noteHasBlock            bool
 		title, link, altContent []byte
 		textHasNl               = false
 	)
@@ -510,6 +511,7 @@ func link(p *parser, out *bytes.Buffer, data []byte, offset int) int {
 			// if inline footnote, title == footnote contents
 			title = lr.title
 			noteId = lr.noteId
+			noteHasBlock = lr.hasBlock
 		}
 
 		// rewind the whitespace
@@ -537,6 +539,11 @@ func link(p *parser, out *bytes.Buffer, data []byte, offset int) int {
 			unescapeText(&uLinkBuf, link)
 			uLink = uLinkBuf.Bytes()
 		}
+		if t == linkImg {
+			uLink = p.resolveURL(URL_IMAGE, uLink)
+		} else {
+			uLink = p.resolveURL(URL_LINK, uLink)
+		}
 
 		// links need something to click on and somewhere to go
 		if len(uLink) == 0 || (t == linkNormal && content.Len() == 0) {
@@ -569,10 +576,14 @@ func link(p *parser, out *bytes.Buffer, data []byte, offset int) int {
 			out.Truncate(outSize - 1)
 		}
 
-		p.r.FootnoteRef(out, link, noteId)
+		p.footnoteRef(out, link, title, noteId, FOOTNOTE_INLINE)
 
 	case linkDeferredFootnote:
-		p.r.FootnoteRef(out, link, noteId)
+		flags := 0
+		if noteHasBlock {
+			flags = LIST_ITEM_CONTAINS_BLOCK
+		}
+		p.footnoteRef(out, link, title, noteId, flags)
 
 	default:
 		return 0
@@ -581,6 +592,33 @@ func link(p *parser, out *bytes.Buffer, data []byte, offset int) int {
 	return i
 }
 
+// resolveURL passes a URL through the URLResolver from the options, if any.
+// Empty URLs are not passed.
+func (p *parser) resolveURL(kind int, url []byte) []byte {
+	if p.urlResolver == nil || len(url) == 0 {
+		return url
+	}
+	return p.urlResolver(kind, url)
+}
+
+// render a footnote reference, handing the contents of the note over as well
+// if the renderer wants them at the reference site
+func (p *parser) footnoteRef(out *bytes.Buffer, ref, text []byte, id int, flags int) {
+	r, ok := p.r.(FootnoteContentRenderer)
+	if !ok {
+		p.r.FootnoteRef(out, ref, id)
+		return
+	}
+
+	var work bytes.Buffer
+	if flags&LIST_ITEM_CONTAINS_BLOCK != 0 {
+		p.block(&work, text)
+	} else {
+		p.inline(&work, text)
+	}
+	r.FootnoteRefContent(out, ref, work.Bytes(), id, flags)
+}
+
 func (p *parser) inlineHTMLComment(out *bytes.Buffer, data []byte) int {
 	if len(data) < 5 {
 		return 0
@@ -612,8 +650,10 @@ func leftAngle(p *parser, out *bytes.Buffer, data []byte, offset int) int {
 		if altype != LINK_TYPE_NOT_AUTOLINK {
 			var uLink bytes.Buffer
 			unescapeText(&uLink, data[1:end+1-2])
-			if uLink.Len() > 0 {
-				p.r.AutoLink(out, uLink.Bytes(), altype)
+			if link := p.resolveURL(URL_AUTOLINK, uLink.Bytes()); len(link) > 0 {
+				p.r.AutoLink(out, link, altype)
+			} else {
+				p.r.NormalText(out, data[:end])
 			}
 		} else {
 			p.r.RawHtmlTag(out, data[:end])
@@ -810,8 +850,10 @@ func autoLink(p *parser, out *bytes.Buffer, data []byte, offset int) int {
 	var uLink bytes.Buffer
 	unescapeText(&uLink, data[:linkEnd])
 
-	if uLink.Len() > 0 {
-		p.r.AutoLink(out, uLink.Bytes(), LINK_TYPE_NORMAL)
+	if link := p.resolveURL(URL_AUTOLINK, uLink.Bytes()); len(link) > 0 {
+		p.r.AutoLink(out, link, LINK_TYPE_NORMAL)
+	} else {
+		p.r.NormalText(out, data[:linkEnd])
 	}
 
 	return linkEnd - rewind
@@ -836,9 +878,10 @@ func isSafeLink(link []byte) bool {
 	}
 
 	for _, prefix := range validUris {
-		// TODO: handle unicode here
-		// case-insensitive prefix test
-		if len(link) > len(prefix) && bytes.Equal(bytes.ToLower(link[:len(prefix)]), prefix) && isalnum(link[len(prefix)]) {
+		// case-insensitive prefix test, followed by a letter, digit or
+		// the start of a UTF-8 encoded character
+		if len(link) > len(prefix) && bytes.Equal(bytes.ToLower(link[:len(prefix)]), prefix) &&
+			(isalnum(link[len(prefix)]) || link[len(prefix)] >= 0x80) {
 			return true
 		}
 	}
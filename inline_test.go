@@ -14,6 +14,7 @@
 package blackfriday
 
 import (
+	"bytes"
 	"regexp"
 	"strings"
 	"testing"
@@ -239,6 +240,57 @@ func TestReferenceOverride(t *testing.T) {
 		}}, 0, HtmlRendererParameters{})
 }
 
+func testURLResolver(kind int, url []byte) []byte {
+	s := string(url)
+	switch {
+	case kind == URL_IMAGE:
+		return []byte(s + "?v=1")
+	case kind == URL_FOOTNOTE:
+		return []byte("/page/" + s)
+	case strings.HasPrefix(s, "./") && strings.Contains(s, ".md"):
+		s = strings.Replace(strings.TrimPrefix(s, "./"), ".md", "/", 1)
+		return []byte("/docs/" + s)
+	case strings.HasPrefix(s, "issue:"):
+		return []byte("https://tracker.example/issues/" + s[len("issue:"):])
+	case strings.HasPrefix(s, "drop:"), strings.HasSuffix(s, "/drop"):
+		return nil
+	}
+	return url
+}
+
+func TestURLResolver(t *testing.T) {
+	var tests = []string{
+		"[other](./other.md#x) and [bug](issue:123)\n",
+		"<p><a href=\"/docs/other/#x\">other</a> and <a href=\"https://tracker.example/issues/123\">bug</a></p>\n",
+
+		"![pic](img/a.png) [gone](drop:x)\n",
+		"<p><img src=\"img/a.png?v=1\" alt=\"pic\" /> [gone](drop:x)</p>\n",
+
+		"see http://example.com/drop here\n",
+		"<p>see http://example.com/drop here</p>\n",
+
+		"see <http://example.com/drop> here\n",
+		"<p>see &lt;http://example.com/drop&gt; here</p>\n",
+
+		"<http://example.com/> and http://example.org/\n",
+		"<p><a href=\"http://example.com/\">http://example.com/</a> and <a href=\"http://example.org/\">http://example.org/</a></p>\n",
+
+		"Note[^a]\n\n[^a]: text\n",
+		"<p>Note<sup class=\"footnote-ref\" id=\"fnref:a\"><a href=\"/page/#fn:a\">1</a></sup></p>\n" +
+			"<div class=\"footnotes\">\n\n<hr />\n\n<ol>\n<li id=\"fn:a\">text\n <a class=\"footnote-return\" href=\"/page/#fnref:a\"><sup>[return]</sup></a></li>\n</ol>\n</div>\n",
+	}
+	doTestsInlineParam(t, tests, Options{
+		Extensions:  EXTENSION_FOOTNOTES,
+		URLResolver: testURLResolver,
+	}, HTML_FOOTNOTE_RETURN_LINKS, HtmlRendererParameters{})
+
+	// other renderers get the rewritten URLs too
+	rst := string(MarkdownOptions([]byte("[bug](issue:7)\n"), RstRenderer(0), Options{URLResolver: testURLResolver}))
+	if !strings.Contains(rst, "https://tracker.example/issues/7") {
+		t.Errorf("URL was not resolved for the Rst renderer:\n%s", rst)
+	}
+}
+
 func TestStrong(t *testing.T) {
 	var tests = []string{
 		"nothing inline\n",
@@ -692,6 +744,9 @@ func TestSafeInlineLink(t *testing.T) {
 		"[foo](https://bar/)\n",
 		"<p><a href=\"https://bar/\">foo</a></p>\n",
 
+		"[foo](https://bücher.example/)\n",
+		"<p><a href=\"https://bücher.example/\">foo</a></p>\n",
+
 		"[foo](ftp://bar/)\n",
 		"<p><a href=\"ftp://bar/\">foo</a></p>\n",
 
@@ -1289,3 +1344,54 @@ func BenchmarkSmartDoubleQuotes(b *testing.B) {
 		runMarkdownInline("this should be normal \"quoted\" text.\n", Options{}, HTML_USE_SMARTYPANTS, HtmlRendererParameters{})
 	}
 }
+
+func TestHtmlHooks(t *testing.T) {
+	hooks := HtmlHooks{
+		Image: func(r *Html, out *bytes.Buffer, link, title, alt []byte,
+			next func(out *bytes.Buffer, link, title, alt []byte)) {
+			out.WriteString("<figure>")
+			next(out, link, nil, alt)
+			if len(title) > 0 {
+				out.WriteString("<figcaption>")
+				r.Escape(out, title)
+				out.WriteString("</figcaption>")
+			}
+			out.WriteString("</figure>")
+		},
+		Link: func(r *Html, out *bytes.Buffer, link, title, content []byte,
+			next func(out *bytes.Buffer, link, title, content []byte)) {
+			out.WriteString("<a class=\"ext\" href=\"")
+			r.WriteURL(out, link)
+			out.WriteString("\">")
+			out.Write(content)
+			out.WriteString("</a>")
+		},
+		HRule: func(r *Html, out *bytes.Buffer, next func(out *bytes.Buffer)) {
+			r.DoubleSpace(out)
+			out.WriteString("<hr class=\"break\"")
+			out.WriteString(r.CloseTag())
+			out.WriteString("\n")
+		},
+		Header: func(r *Html, out *bytes.Buffer, text func() bool, level int, id string,
+			next func(out *bytes.Buffer, text func() bool, level int, id string)) {
+			next(out, text, level+1, id)
+		},
+	}
+	var tests = []string{
+		"![a <cat>](/cat.png \"A & B\")\n",
+		"<p><figure><img src=\"/img/cat.png\" alt=\"a &lt;cat&gt;\" /><figcaption>A &amp; B</figcaption></figure></p>\n",
+
+		"[a *b*](/page?x=1&y=2)\n",
+		"<p><a class=\"ext\" href=\"/img/page?x=1&amp;y=2\">a <em>b</em></a></p>\n",
+
+		"one\n\n***\n",
+		"<p>one</p>\n\n<hr class=\"break\" />\n",
+
+		"# Title\n",
+		"<h2>Title</h2>\n",
+	}
+	doTestsInlineParam(t, tests, Options{}, HTML_USE_XHTML, HtmlRendererParameters{
+		AbsolutePrefix: "/img",
+		Hooks:          hooks,
+	})
+}
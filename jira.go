@@ -0,0 +1,391 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Jira wiki markup rendering backend
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A block quote that starts with a GitHub-style alert ("[!NOTE]") or a
+// bold label ("**Warning:**") becomes one of Jira's panel macros.
+var jiraPanelRe = regexp.MustCompile(
+	`^(?:\\?\[\\?!(?i:(note|tip|important|warning|caution))\\?\]|` +
+		`\*(?i:(note|tip|info|important|warning|caution)):?\*:?)\s*`)
+
+// Jira is a type that implements the Renderer interface for Jira wiki
+// markup, as used in Jira issues and comments and older Confluence
+// versions.
+//
+// Do not create this directly, instead use the JiraRenderer function.
+type Jira struct {
+	flags int
+
+	// markers of the enclosing lists, outermost first, e.g. "*#"
+	listMarkers string
+
+	// whether the table row being rendered is a header row
+	tableIsHead bool
+}
+
+// JiraRenderer creates and configures a Jira object, which satisfies the
+// Renderer interface.
+//
+// flags is a set of JIRA_* options ORed together (currently no such
+// options are defined).
+func JiraRenderer(flags int) Renderer {
+	return &Jira{flags: flags}
+}
+
+func (options *Jira) GetFlags() int {
+	return options.flags
+}
+
+func (options *Jira) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	blankLine(out)
+	// {code} without a language would highlight the block as Java
+	if lang := codeLanguage(info); lang != "" && !strings.ContainsAny(lang, "{}|:") {
+		out.WriteString("{code:")
+		out.WriteString(lang)
+		out.WriteString("}\n")
+		out.Write(text)
+		startLine(out)
+		out.WriteString("{code}\n")
+		return
+	}
+	out.WriteString("{noformat}\n")
+	out.Write(text)
+	startLine(out)
+	out.WriteString("{noformat}\n")
+}
+
+func (options *Jira) TitleBlock(out *bytes.Buffer, text []byte) {
+	title, authors, date := titleBlockFields(text)
+	blankLine(out)
+	out.WriteString("h1. ")
+	options.NormalText(out, []byte(title))
+	out.WriteByte('\n')
+	if len(authors) > 0 {
+		options.NormalText(out, []byte(strings.Join(authors, "; ")))
+		out.WriteByte('\n')
+	}
+	if date != "" {
+		options.NormalText(out, []byte(date))
+		out.WriteByte('\n')
+	}
+}
+
+func (options *Jira) BlockQuote(out *bytes.Buffer, text []byte) {
+	blankLine(out)
+	text = bytes.TrimSpace(text)
+	macro := "quote"
+	if m := jiraPanelRe.FindSubmatch(text); m != nil {
+		macro = panelMacros[strings.ToLower(string(m[1])+string(m[2]))]
+		text = text[len(m[0]):]
+	}
+
+	// quotes do not nest in Jira, so nested quotes are merged into this one
+	text = bytes.Replace(text, []byte("{quote}\n"), nil, -1)
+	text = bytes.Replace(text, []byte("\n{quote}"), nil, -1)
+	out.WriteString("{" + macro + "}\n")
+	out.Write(text)
+	out.WriteString("\n{" + macro + "}\n")
+}
+
+func (options *Jira) BlockHtml(out *bytes.Buffer, text []byte) {
+	options.BlockCode(out, text, "html")
+}
+
+func (options *Jira) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	marker := out.Len()
+	blankLine(out)
+	out.WriteString("h")
+	out.WriteString(strconv.Itoa(level))
+	out.WriteString(". ")
+	if id != "" && !strings.ContainsAny(id, "{}|") {
+		out.WriteString("{anchor:")
+		out.WriteString(id)
+		out.WriteString("}")
+	}
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteByte('\n')
+}
+
+func (options *Jira) HRule(out *bytes.Buffer) {
+	blankLine(out)
+	out.WriteString("----\n")
+}
+
+func (options *Jira) List(out *bytes.Buffer, text func() bool, flags int) {
+	marker := out.Len()
+	if options.listMarkers == "" {
+		blankLine(out)
+	} else {
+		startLine(out)
+	}
+
+	saved := options.listMarkers
+	if flags&LIST_TYPE_ORDERED != 0 {
+		options.listMarkers += "#"
+	} else if flags&LIST_TYPE_DEFINITION == 0 {
+		options.listMarkers += "*"
+	}
+	ok := text()
+	options.listMarkers = saved
+	if !ok {
+		out.Truncate(marker)
+	}
+}
+
+func (options *Jira) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	startLine(out)
+	switch {
+	case flags&LIST_TYPE_TERM != 0:
+		out.WriteByte('*')
+		out.Write(bytes.TrimSpace(text))
+		out.WriteString("*\n")
+	case flags&LIST_TYPE_DEFINITION != 0:
+		jiraItemContent(out, text)
+	default:
+		out.WriteString(options.listMarkers)
+		out.WriteByte(' ')
+		jiraItemContent(out, text)
+	}
+}
+
+// A list item ends at a blank line, so the paragraphs of an item are only
+// separated by line breaks.
+func jiraItemContent(out *bytes.Buffer, text []byte) {
+	empty := true
+	for _, line := range bytes.Split(bytes.TrimSpace(text), []byte("\n")) {
+		line = bytes.TrimRight(line, " ")
+		if len(line) > 0 {
+			out.Write(line)
+			out.WriteByte('\n')
+			empty = false
+		}
+	}
+	if empty {
+		out.WriteByte('\n')
+	}
+}
+
+func (options *Jira) Paragraph(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	blankLine(out)
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteByte('\n')
+}
+
+func (options *Jira) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	blankLine(out)
+	out.Write(header)
+	out.Write(body)
+}
+
+func (options *Jira) TableRow(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+	if options.tableIsHead {
+		out.WriteString("||\n")
+	} else {
+		out.WriteString("|\n")
+	}
+	options.tableIsHead = false
+}
+
+func (options *Jira) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
+	out.WriteString("||")
+	jiraCellText(out, text)
+	options.tableIsHead = true
+}
+
+func (options *Jira) TableCell(out *bytes.Buffer, text []byte, align int) {
+	out.WriteByte('|')
+	jiraCellText(out, text)
+}
+
+func jiraCellText(out *bytes.Buffer, text []byte) {
+	text = bytes.TrimSpace(bytes.Replace(text, []byte("\n"), []byte(" "), -1))
+	if len(text) == 0 {
+		// an empty cell would merge with its neighbour
+		out.WriteByte(' ')
+		return
+	}
+	out.Write(text)
+}
+
+func (options *Jira) Footnotes(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	blankLine(out)
+	out.WriteString("----\n")
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+}
+
+// Footnotes are numbered in order, which an ordered list reproduces.
+func (options *Jira) FootnoteItem(out *bytes.Buffer, name, text []byte, flags int) {
+	startLine(out)
+	out.WriteString("# {anchor:fn-")
+	out.Write(slugify(name))
+	out.WriteString("}")
+	jiraItemContent(out, text)
+}
+
+func (options *Jira) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	out.WriteByte('[')
+	if kind == LINK_TYPE_EMAIL && !bytes.HasPrefix(link, []byte("mailto:")) {
+		out.WriteString("mailto:")
+	}
+	out.Write(jiraLinkTarget(link))
+	out.WriteByte(']')
+}
+
+// Keep the characters that end a link out of its target.
+func jiraLinkTarget(link []byte) []byte {
+	return []byte(strings.NewReplacer("|", "%7C", "]", "%5D", " ", "%20").Replace(string(link)))
+}
+
+func (options *Jira) CodeSpan(out *bytes.Buffer, text []byte) {
+	out.WriteString("{{")
+	options.NormalText(out, text)
+	out.WriteString("}}")
+}
+
+func (options *Jira) DoubleEmphasis(out *bytes.Buffer, text []byte) {
+	out.WriteByte('*')
+	out.Write(text)
+	out.WriteByte('*')
+}
+
+func (options *Jira) Emphasis(out *bytes.Buffer, text []byte) {
+	if len(text) == 0 {
+		return
+	}
+	out.WriteByte('_')
+	out.Write(text)
+	out.WriteByte('_')
+}
+
+func (options *Jira) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	out.WriteByte('!')
+	out.Write(bytes.Replace(link, []byte("!"), []byte("%21"), -1))
+	if len(alt) > 0 {
+		// attributes are separated by commas
+		out.WriteString("|alt=")
+		out.WriteString(strings.NewReplacer(",", " ", "!", " ", "|", " ").Replace(string(alt)))
+	}
+	out.WriteByte('!')
+}
+
+func (options *Jira) LineBreak(out *bytes.Buffer) {
+	out.WriteByte('\n')
+}
+
+func (options *Jira) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	out.WriteByte('[')
+	if len(content) > 0 && !bytes.Equal(content, link) {
+		out.Write(content)
+		out.WriteByte('|')
+	}
+	out.Write(jiraLinkTarget(link))
+	out.WriteByte(']')
+}
+
+// Jira escapes HTML itself, so raw tags are dropped.
+func (options *Jira) RawHtmlTag(out *bytes.Buffer, tag []byte) {
+}
+
+func (options *Jira) TripleEmphasis(out *bytes.Buffer, text []byte) {
+	out.WriteString("*_")
+	out.Write(text)
+	out.WriteString("_*")
+}
+
+func (options *Jira) StrikeThrough(out *bytes.Buffer, text []byte) {
+	out.WriteByte('-')
+	out.Write(text)
+	out.WriteByte('-')
+}
+
+func (options *Jira) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
+	out.WriteString("[^")
+	out.WriteString(strconv.Itoa(id))
+	out.WriteString("^|#fn-")
+	out.Write(slugify(ref))
+	out.WriteByte(']')
+}
+
+func (options *Jira) Entity(out *bytes.Buffer, entity []byte) {
+	options.NormalText(out, []byte(html.UnescapeString(string(entity))))
+}
+
+func (options *Jira) NormalText(out *bytes.Buffer, text []byte) {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if (c == '!' || c == '^') && i+1 == len(text) {
+			// may start an image or inline footnote, which the parser
+			// strips again
+			out.WriteByte(c)
+			continue
+		}
+		prev := byte('\n')
+		if i > 0 {
+			prev = text[i-1]
+		} else if out.Len() > 0 {
+			prev = out.Bytes()[out.Len()-1]
+		}
+		switch c {
+		case '\n':
+			// a newline is a line break in Jira
+			c = ' '
+		case '\\':
+			// two backslashes would be a line break
+			out.WriteString("&#92;")
+			continue
+		case '{', '}', '[', ']', '|', '!':
+			out.WriteByte('\\')
+		case '*', '_', '?', '-', '+', '^', '~':
+			// text effects only start or end at word boundaries
+			if !isalnum(prev) || i+1 == len(text) || !isalnum(text[i+1]) {
+				out.WriteByte('\\')
+			}
+		case '#':
+			if prev == '\n' {
+				out.WriteByte('\\')
+			}
+		}
+		out.WriteByte(c)
+	}
+}
+
+func (options *Jira) DocumentHeader(out *bytes.Buffer) {
+	options.listMarkers = ""
+	options.tableIsHead = false
+}
+
+func (options *Jira) DocumentFooter(out *bytes.Buffer) {
+}
@@ -0,0 +1,38 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the Jira renderer
+//
+
+package blackfriday
+
+import (
+	"testing"
+)
+
+// Reference inputs that also have Jira and Confluence renderings in testdata.
+var atlassianReferenceFiles = []string{
+	"Amps and angle encoding",
+	"Backslash escapes",
+	"Blockquotes with code blocks",
+	"Code Spans",
+	"Links, inline style",
+	"Nested blockquotes",
+	"Ordered and unordered lists",
+	"Strong and em together",
+	"Tables, code and admonitions",
+}
+
+func TestJiraReference(t *testing.T) {
+	doTestsReferenceWithRenderer(t, atlassianReferenceFiles, ".jira", commonExtensions|EXTENSION_FOOTNOTES,
+		func() Renderer {
+			return JiraRenderer(0)
+		})
+}
@@ -17,37 +17,139 @@ package blackfriday
 
 import (
 	"bytes"
+	"html"
+	"strconv"
 	"strings"
 )
 
+// Latex renderer configuration options.
+const (
+	LATEX_FRAGMENT    = 1 << iota // only render the body, without preamble or document environment
+	LATEX_TITLE_PAGE              // put the title from the title block on a page of its own
+	LATEX_BEAMER                  // render a Beamer slide deck
+	LATEX_INCREMENTAL             // reveal list items one by one in Beamer slides
+)
+
+type LatexRendererParameters struct {
+	// Document class, "article" if empty, or "beamer" with LATEX_BEAMER.
+	// Level 1 headers become chapters with the report and book classes.
+	DocumentClass string
+	// Options for the document class, e.g. "11pt,twoside".
+	ClassOptions string
+	// Paper size for the geometry package, e.g. "a4paper". If empty, the
+	// document class default is used.
+	PaperSize string
+	// Page margins for the geometry package, "1in" if empty.
+	Margin string
+	// Colours of internal links, URLs, links to files and citations as
+	// understood by hyperref (e.g. "blue"), black if empty.
+	LinkColor string
+	URLColor  string
+	FileColor string
+	CiteColor string
+	// Width of images, e.g. "0.8\linewidth". If empty, images are
+	// included at their natural size.
+	ImageWidth string
+	// Headers are numbered from this level on, so that 2 leaves level 1
+	// headers unnumbered. Headers with the class "unnumbered" are never
+	// numbered. If zero, 1 is used.
+	HeaderNumberStart int
+	// Written between the numbers of each level. If empty, "." is used.
+	HeaderNumberSeparator string
+	// Added to the level of every header before it is mapped to a
+	// sectioning command or, with LATEX_BEAMER, to a section or frame, e.g.
+	// 1 to make "#" headers subsections. Headers past the deepest command
+	// are written in bold. HeaderNumberStart refers to the levels before
+	// the shift.
+	HeaderLevelShift int
+	// Added to the end of the preamble, right before \begin{document}.
+	Preamble string
+}
+
 // Latex is a type that implements the Renderer interface for LaTeX output.
 //
 // Do not create this directly, instead use the LatexRenderer function.
 type Latex struct {
+	flags      int // LATEX_* options
+	parameters LatexRendererParameters
+
+	// footnotes that have been written, by reference name
+	footnotes map[string]bool
+
+	// the last image that was written, which becomes a figure if it is
+	// all there is to its paragraph
+	image latexImage
+
+	// the slide being written with LATEX_BEAMER
+	frame latexFrame
+}
+
+// In Beamer mode, level 1 headers start a section, and level 2 headers and
+// horizontal rules start a frame. Anything before them goes into a frame
+// without a title.
+type latexFrame struct {
+	out     *bytes.Buffer // the document, as frames only start at its top level
+	open    bool
+	start   int  // where \begin{frame} was written
+	body    int  // where the contents of the frame start
+	fragile bool // whether it contains verbatim text
+}
+
+type latexImage struct {
+	out        *bytes.Buffer
+	start, end int
+	link       []byte
+	title      []byte
 }
 
 // LatexRenderer creates and configures a Latex object, which
 // satisfies the Renderer interface.
 //
-// flags is a set of LATEX_* options ORed together (currently no such options
-// are defined).
+// flags is a set of LATEX_* options ORed together.
 func LatexRenderer(flags int) Renderer {
-	return &Latex{}
+	return LatexRendererWithParameters(flags, LatexRendererParameters{})
+}
+
+// LatexRendererWithParameters is like LatexRenderer, but takes the document
+// class, page layout and other settings of the preamble through
+// renderParameters. Empty fields get their default values.
+func LatexRendererWithParameters(flags int, renderParameters LatexRendererParameters) Renderer {
+	if renderParameters.DocumentClass == "" && flags&LATEX_BEAMER != 0 {
+		renderParameters.DocumentClass = "beamer"
+	} else if renderParameters.DocumentClass == "" {
+		renderParameters.DocumentClass = "article"
+	}
+	if renderParameters.Margin == "" {
+		renderParameters.Margin = "1in"
+	}
+	for _, color := range []*string{&renderParameters.LinkColor, &renderParameters.URLColor,
+		&renderParameters.FileColor, &renderParameters.CiteColor} {
+		if *color == "" {
+			*color = "black"
+		}
+	}
+	return &Latex{
+		flags:      flags,
+		parameters: renderParameters,
+		footnotes:  make(map[string]bool),
+	}
 }
 
 func (options *Latex) GetFlags() int {
-	return 0
+	return options.flags
 }
 
 // render code chunks using verbatim, or listings if we have a language
 func (options *Latex) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	options.frame.fragile = true
 	if info == "" {
 		out.WriteString("\n\\begin{verbatim}\n")
-	} else {
-		lang := strings.Fields(info)[0]
+	} else if lang, ok := latexListingsLanguages[strings.ToLower(codeLanguage(info))]; ok {
 		out.WriteString("\n\\begin{lstlisting}[language=")
 		out.WriteString(lang)
 		out.WriteString("]\n")
+	} else {
+		out.WriteString("\n\\begin{lstlisting}\n")
 	}
 	out.Write(text)
 	if info == "" {
@@ -58,7 +160,28 @@ func (options *Latex) BlockCode(out *bytes.Buffer, text []byte, info string) {
 }
 
 func (options *Latex) TitleBlock(out *bytes.Buffer, text []byte) {
-
+	title, authors, date := titleBlockFields(text)
+	if options.isSlideBreak(out) {
+		options.endFrame(out)
+	}
+	out.WriteString("\n\\title{")
+	escapeSpecialChars(out, []byte(title))
+	out.WriteString("}\n\\author{")
+	for i, author := range authors {
+		if i > 0 {
+			out.WriteString(" \\and ")
+		}
+		escapeSpecialChars(out, []byte(author))
+	}
+	// an empty date keeps LaTeX from printing today's
+	out.WriteString("}\n\\date{")
+	escapeSpecialChars(out, []byte(date))
+	if options.isSlideBreak(out) {
+		out.WriteString("}\n\\frame{\\titlepage}\n")
+		options.beginFrame(out, nil)
+		return
+	}
+	out.WriteString("}\n\\maketitle\n")
 }
 
 func (options *Latex) BlockQuote(out *bytes.Buffer, text []byte) {
@@ -69,46 +192,196 @@ func (options *Latex) BlockQuote(out *bytes.Buffer, text []byte) {
 
 func (options *Latex) BlockHtml(out *bytes.Buffer, text []byte) {
 	// a pretty lame thing to do...
+	options.frame.fragile = true
 	out.WriteString("\n\\begin{verbatim}\n")
 	out.Write(text)
 	out.WriteString("\n\\end{verbatim}\n")
 }
 
+// The sectioning commands for each header level, counting from chapters.
+var latexSections = []string{"chapter", "section", "subsection", "subsubsection", "paragraph", "subparagraph"}
+
 func (options *Latex) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	options.HeaderWithAttributes(out, text, level, HeaderAttributes{ID: id})
+}
+
+// HeaderWithAttributes writes a header, which is left unnumbered if it has
+// the class "unnumbered".
+func (options *Latex) HeaderWithAttributes(out *bytes.Buffer, text func() bool, level int, attrs HeaderAttributes) {
+	id := attrs.ID
 	marker := out.Len()
+	if shifted := options.shiftLevel(level); options.isSlideBreak(out) && shifted <= 2 {
+		frame := options.frame
+		options.endFrame(out)
+		ok := true
+		if shifted == 1 {
+			out.WriteString("\n\\section{")
+			ok = text()
+			out.WriteString("}\n")
+			options.beginFrame(out, nil)
+		} else {
+			ok = options.beginFrame(out, text)
+		}
+		if !ok {
+			out.Truncate(marker)
+			options.frame = frame
+		}
+		return
+	}
 
-	switch level {
-	case 1:
-		out.WriteString("\n\\section{")
-	case 2:
-		out.WriteString("\n\\subsection{")
-	case 3:
-		out.WriteString("\n\\subsubsection{")
-	case 4:
-		out.WriteString("\n\\paragraph{")
-	case 5:
-		out.WriteString("\n\\subparagraph{")
-	case 6:
+	numbered := level >= options.headerNumberStart() && !attrs.HasClass("unnumbered")
+	if section := options.sectionName(level); section == "" {
 		out.WriteString("\n\\textbf{")
+	} else if numbered {
+		out.WriteString("\n\\" + section + "{")
+	} else {
+		out.WriteString("\n\\" + section + "*{")
 	}
 	if !text() {
 		out.Truncate(marker)
 		return
 	}
-	out.WriteString("}\n")
+	out.WriteString("}")
+	if id != "" {
+		out.WriteString("\\label{")
+		out.WriteString(latexLabel(id))
+		out.WriteString("}")
+	}
+	out.WriteString("\n")
+}
+
+// isSlideBreak tells whether a header or rule written to out starts a new
+// slide.
+func (options *Latex) isSlideBreak(out *bytes.Buffer) bool {
+	return options.flags&LATEX_BEAMER != 0 && out == options.frame.out
+}
+
+// beginFrame starts a frame, with the title written by title if it is not
+// nil, and reports whether the title could be written.
+func (options *Latex) beginFrame(out *bytes.Buffer, title func() bool) bool {
+	options.frame.open = true
+	options.frame.start = out.Len()
+	options.frame.fragile = false
+	out.WriteString("\n\\begin{frame}")
+	if title != nil {
+		out.WriteString("{")
+		if !title() {
+			return false
+		}
+		out.WriteString("}")
+	}
+	out.WriteString("\n")
+	options.frame.body = out.Len()
+	return true
+}
+
+// endFrame ends the current frame, or drops it if it is empty. Frames with
+// verbatim text in them have to be marked as fragile.
+func (options *Latex) endFrame(out *bytes.Buffer) {
+	frame := &options.frame
+	if !frame.open {
+		return
+	}
+	frame.open = false
+	untitled := frame.body-frame.start == len("\n\\begin{frame}\n")
+	if untitled && (frame.body >= out.Len() || len(bytes.TrimSpace(out.Bytes()[frame.body:])) == 0) {
+		out.Truncate(frame.start)
+		return
+	}
+	if frame.fragile {
+		contents := append([]byte(nil), out.Bytes()[frame.start+len("\n\\begin{frame}"):]...)
+		out.Truncate(frame.start)
+		out.WriteString("\n\\begin{frame}[fragile]")
+		out.Write(contents)
+	}
+	out.WriteString("\n\\end{frame}\n")
+}
+
+// sectionName is the name of the sectioning command for a header level,
+// which is empty for levels that have none.
+func (options *Latex) sectionName(level int) string {
+	level = options.shiftLevel(level)
+	// chapters come first in reports and books
+	if !options.hasChapters() {
+		level++
+	}
+	if level > len(latexSections) {
+		return ""
+	}
+	return latexSections[level-1]
+}
+
+// shiftLevel applies HeaderLevelShift to the level of a header.
+func (options *Latex) shiftLevel(level int) int {
+	level += options.parameters.HeaderLevelShift
+	if level < 1 {
+		return 1
+	}
+	return level
+}
+
+func (options *Latex) headerNumberStart() int {
+	if options.parameters.HeaderNumberStart < 1 {
+		return 1
+	}
+	return options.parameters.HeaderNumberStart
+}
+
+// sectionNumbers redefines the section numbers when they start at another
+// level or use another separator.
+func (options *Latex) sectionNumbers(out *bytes.Buffer) {
+	start, sep := options.headerNumberStart(), options.parameters.HeaderNumberSeparator
+	if start == 1 && (sep == "" || sep == ".") {
+		return
+	}
+	if sep == "" {
+		sep = "."
+	}
+
+	// number paragraphs too, like the other renderers
+	out.WriteString("\\setcounter{secnumdepth}{5}\n")
+	parent := ""
+	for level := start; options.sectionName(level) != ""; level++ {
+		section := options.sectionName(level)
+		out.WriteString("\\renewcommand{\\the" + section + "}{")
+		if parent != "" {
+			out.WriteString("\\the" + parent)
+			escapeSpecialChars(out, []byte(sep))
+		}
+		out.WriteString("\\arabic{" + section + "}}\n")
+		parent = section
+	}
+}
+
+func (options *Latex) hasChapters() bool {
+	return options.parameters.DocumentClass == "report" || options.parameters.DocumentClass == "book"
 }
 
 func (options *Latex) HRule(out *bytes.Buffer) {
+	if options.isSlideBreak(out) {
+		options.endFrame(out)
+		options.beginFrame(out, nil)
+		return
+	}
+	if options.flags&LATEX_FRAGMENT != 0 {
+		// \HRule is defined in the preamble
+		out.WriteString("\n\\rule{\\linewidth}{0.5mm}\n")
+		return
+	}
 	out.WriteString("\n\\HRule\n")
 }
 
 func (options *Latex) List(out *bytes.Buffer, text func() bool, flags int) {
 	marker := out.Len()
 	if flags&LIST_TYPE_ORDERED != 0 {
-		out.WriteString("\n\\begin{enumerate}\n")
+		out.WriteString("\n\\begin{enumerate}")
 	} else {
-		out.WriteString("\n\\begin{itemize}\n")
+		out.WriteString("\n\\begin{itemize}")
+	}
+	if options.flags&LATEX_BEAMER != 0 && options.flags&LATEX_INCREMENTAL != 0 {
+		out.WriteString("[<+->]")
 	}
+	out.WriteString("\n")
 	if !text() {
 		out.Truncate(marker)
 		return
@@ -132,11 +405,29 @@ func (options *Latex) Paragraph(out *bytes.Buffer, text func() bool) {
 		out.Truncate(marker)
 		return
 	}
+
+	// an image on its own becomes a figure
+	image := options.image
+	if image.out == out && image.start == marker+1 && image.end == out.Len() {
+		out.Truncate(marker)
+		out.WriteString("\n\\begin{figure}[htbp]\n\\centering\n")
+		options.includeGraphics(out, image.link)
+		out.WriteString("\n")
+		if len(image.title) > 0 {
+			out.WriteString("\\caption{")
+			escapeSpecialChars(out, image.title)
+			out.WriteString("}\n")
+		}
+		out.WriteString("\\end{figure}\n")
+		return
+	}
 	out.WriteString("\n")
 }
 
+// Tables are long tables, which may break across pages and repeat their
+// header on each of them, with booktabs rules.
 func (options *Latex) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
-	out.WriteString("\n\\begin{tabular}{")
+	out.WriteString("\n\\begin{longtable}{")
 	for _, elt := range columnData {
 		switch elt {
 		case TABLE_ALIGNMENT_LEFT:
@@ -147,51 +438,45 @@ func (options *Latex) Table(out *bytes.Buffer, header []byte, body []byte, colum
 			out.WriteByte('c')
 		}
 	}
-	out.WriteString("}\n")
+	out.WriteString("}\n\\toprule\n")
 	out.Write(header)
-	out.WriteString(" \\\\\n\\hline\n")
+	out.WriteString("\\midrule\n\\endhead\n")
 	out.Write(body)
-	out.WriteString("\n\\end{tabular}\n")
+	out.WriteString("\\bottomrule\n\\end{longtable}\n")
 }
 
 func (options *Latex) TableRow(out *bytes.Buffer, text []byte) {
-	if out.Len() > 0 {
-		out.WriteString(" \\\\\n")
-	}
 	out.Write(text)
+	out.WriteString(" \\\\\n")
 }
 
 func (options *Latex) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
-	if out.Len() > 0 {
-		out.WriteString(" & ")
-	}
-	out.Write(text)
+	options.TableCell(out, text, align)
 }
 
 func (options *Latex) TableCell(out *bytes.Buffer, text []byte, align int) {
 	if out.Len() > 0 {
 		out.WriteString(" & ")
 	}
-	out.Write(text)
+	// a blank line would end the row
+	out.Write(bytes.TrimSpace(bytes.Replace(text, []byte("\n"), []byte(" "), -1)))
 }
 
-// TODO: this
+// Footnotes are written where they are referenced, see FootnoteRefContent.
 func (options *Latex) Footnotes(out *bytes.Buffer, text func() bool) {
-
 }
 
 func (options *Latex) FootnoteItem(out *bytes.Buffer, name, text []byte, flags int) {
-
 }
 
 func (options *Latex) AutoLink(out *bytes.Buffer, link []byte, kind int) {
 	out.WriteString("\\href{")
-	if kind == LINK_TYPE_EMAIL {
+	if kind == LINK_TYPE_EMAIL && !bytes.HasPrefix(link, []byte("mailto:")) {
 		out.WriteString("mailto:")
 	}
-	out.Write(link)
+	latexURL(out, link)
 	out.WriteString("}{")
-	out.Write(link)
+	escapeSpecialChars(out, bytes.TrimPrefix(link, []byte("mailto:")))
 	out.WriteString("}")
 }
 
@@ -217,15 +502,31 @@ func (options *Latex) Image(out *bytes.Buffer, link []byte, title []byte, alt []
 	if bytes.HasPrefix(link, []byte("http://")) || bytes.HasPrefix(link, []byte("https://")) {
 		// treat it like a link
 		out.WriteString("\\href{")
-		out.Write(link)
+		latexURL(out, link)
 		out.WriteString("}{")
-		out.Write(alt)
+		escapeSpecialChars(out, alt)
 		out.WriteString("}")
+	} else if bytes.ContainsAny(link, "%#\\{}") {
+		// there is no way to write these in a file name
+		escapeSpecialChars(out, alt)
 	} else {
-		out.WriteString("\\includegraphics{")
-		out.Write(link)
-		out.WriteString("}")
+		start := out.Len()
+		options.includeGraphics(out, link)
+		options.image = latexImage{out: out, start: start, end: out.Len(), link: link, title: title}
+	}
+}
+
+func (options *Latex) includeGraphics(out *bytes.Buffer, link []byte) {
+	out.WriteString("\\includegraphics")
+	if options.parameters.ImageWidth != "" {
+		out.WriteString("[width=")
+		out.WriteString(options.parameters.ImageWidth)
+		out.WriteString("]")
 	}
+	// \detokenize keeps characters like _ from being interpreted
+	out.WriteString("{\\detokenize{")
+	out.Write(link)
+	out.WriteString("}}")
 }
 
 func (options *Latex) LineBreak(out *bytes.Buffer) {
@@ -233,8 +534,16 @@ func (options *Latex) LineBreak(out *bytes.Buffer) {
 }
 
 func (options *Latex) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if len(link) > 1 && link[0] == '#' {
+		out.WriteString("\\hyperref[")
+		out.WriteString(latexLabel(string(link[1:])))
+		out.WriteString("]{")
+		out.Write(content)
+		out.WriteString("}")
+		return
+	}
 	out.WriteString("\\href{")
-	out.Write(link)
+	latexURL(out, link)
 	out.WriteString("}{")
 	out.Write(content)
 	out.WriteString("}")
@@ -255,18 +564,90 @@ func (options *Latex) StrikeThrough(out *bytes.Buffer, text []byte) {
 	out.WriteString("}")
 }
 
-// TODO: this
+// A footnote that is referenced again only gets its mark repeated.
 func (options *Latex) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
-
+	out.WriteString("\\footnotemark[")
+	out.WriteString(strconv.Itoa(id))
+	out.WriteString("]")
 }
 
-func needsBackslash(c byte) bool {
-	for _, r := range []byte("_{}%$&\\~#") {
-		if c == r {
-			return true
-		}
+func (options *Latex) FootnoteRefContent(out *bytes.Buffer, ref []byte, text []byte, id int, flags int) {
+	if options.footnotes[string(ref)] {
+		options.FootnoteRef(out, ref, id)
+		return
+	}
+	options.footnotes[string(ref)] = true
+
+	// the parser cannot remove the ^ that starts an inline footnote once
+	// it has been escaped
+	caret := []byte("\\textasciicircum{}")
+	if flags&FOOTNOTE_INLINE != 0 && bytes.HasSuffix(out.Bytes(), caret) {
+		out.Truncate(out.Len() - len(caret))
 	}
-	return false
+	out.WriteString("\\footnote{")
+	out.Write(bytes.TrimSpace(text))
+	out.WriteString("}")
+}
+
+// Replacements for the characters that are special to LaTeX, or that the
+// default OT1 font encoding prints as something else.
+var latexEscapes = map[byte]string{
+	'&':  "\\&",
+	'%':  "\\%",
+	'$':  "\\$",
+	'#':  "\\#",
+	'_':  "\\_",
+	'{':  "\\{",
+	'}':  "\\}",
+	'~':  "\\textasciitilde{}",
+	'^':  "\\textasciicircum{}",
+	'\\': "\\textbackslash{}",
+	'<':  "\\textless{}",
+	'>':  "\\textgreater{}",
+	'|':  "\\textbar{}",
+}
+
+// LaTeX commands for HTML entities that inputenc does not handle well;
+// other entities are written as Unicode characters.
+var latexEntities = map[string]string{
+	"&nbsp;":   "~",
+	"&shy;":    "\\-",
+	"&ndash;":  "\\textendash{}",
+	"&mdash;":  "\\textemdash{}",
+	"&hellip;": "\\ldots{}",
+	"&lsquo;":  "`",
+	"&rsquo;":  "'",
+	"&ldquo;":  "``",
+	"&rdquo;":  "''",
+	"&laquo;":  "\\guillemotleft{}",
+	"&raquo;":  "\\guillemotright{}",
+	"&copy;":   "\\textcopyright{}",
+	"&reg;":    "\\textregistered{}",
+	"&trade;":  "\\texttrademark{}",
+	"&deg;":    "\\textdegree{}",
+	"&euro;":   "\\texteuro{}",
+	"&sect;":   "\\S{}",
+	"&para;":   "\\P{}",
+	"&dagger;": "\\dag{}",
+	"&bull;":   "\\textbullet{}",
+	"&middot;": "\\textperiodcentered{}",
+	"&times;":  "\\ensuremath{\\times}",
+	"&minus;":  "\\ensuremath{-}",
+}
+
+// Names of listings languages by the names used in fenced code blocks.
+// Code in other languages is listed without highlighting.
+var latexListingsLanguages = map[string]string{
+	"ada": "Ada", "awk": "Awk", "bash": "bash", "c": "C", "c++": "C++", "cpp": "C++",
+	"cobol": "Cobol", "csh": "csh", "delphi": "Delphi", "eiffel": "Eiffel",
+	"erlang": "erlang", "fortran": "Fortran", "gnuplot": "Gnuplot", "haskell": "Haskell",
+	"html": "HTML", "java": "Java", "ksh": "ksh", "lisp": "Lisp", "make": "make",
+	"makefile": "make", "mathematica": "Mathematica", "matlab": "Matlab", "ml": "ML",
+	"ocaml": "Caml", "octave": "Octave", "pascal": "Pascal", "perl": "Perl", "php": "PHP",
+	"prolog": "Prolog", "python": "Python", "py": "Python", "r": "R", "ruby": "Ruby",
+	"rb": "Ruby", "scilab": "Scilab", "sh": "sh", "shell": "sh", "sql": "SQL",
+	"tcl": "tcl", "tex": "TeX", "latex": "TeX", "vbscript": "VBScript",
+	"verilog": "Verilog", "vhdl": "VHDL", "xml": "XML", "xslt": "XSLT",
 }
 
 func escapeSpecialChars(out *bytes.Buffer, text []byte) {
@@ -274,7 +655,7 @@ func escapeSpecialChars(out *bytes.Buffer, text []byte) {
 		// directly copy normal characters
 		org := i
 
-		for i < len(text) && !needsBackslash(text[i]) {
+		for i < len(text) && latexEscapes[text[i]] == "" {
 			i++
 		}
 		if i > org {
@@ -285,14 +666,46 @@ func escapeSpecialChars(out *bytes.Buffer, text []byte) {
 		if i >= len(text) {
 			break
 		}
-		out.WriteByte('\\')
-		out.WriteByte(text[i])
+		out.WriteString(latexEscapes[text[i]])
+	}
+}
+
+// latexURL writes link for use in \href. hyperref takes URLs almost
+// verbatim, but % and # must be escaped, and braces, backslashes and a few
+// other characters are safer percent-encoded, as the URL may already have
+// been read as the argument of another command.
+func latexURL(out *bytes.Buffer, link []byte) {
+	for _, c := range link {
+		switch c {
+		case '%', '#':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case '\\', '{', '}', '~', '^', ' ', '\n':
+			out.WriteString("\\%")
+			out.WriteString(strings.ToUpper(strconv.FormatInt(int64(c)|0x100, 16)[1:]))
+		default:
+			out.WriteByte(c)
+		}
 	}
 }
 
+// latexLabel turns an arbitrary string into a name for \label and
+// \hyperref.
+func latexLabel(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 128 && (isalnum(byte(r)) || strings.ContainsRune("-_:.", r)) {
+			return r
+		}
+		return '-'
+	}, id)
+}
+
 func (options *Latex) Entity(out *bytes.Buffer, entity []byte) {
-	// TODO: convert this into a unicode character or something
-	out.Write(entity)
+	if command, ok := latexEntities[string(entity)]; ok {
+		out.WriteString(command)
+		return
+	}
+	escapeSpecialChars(out, []byte(html.UnescapeString(string(entity))))
 }
 
 func (options *Latex) NormalText(out *bytes.Buffer, text []byte) {
@@ -301,21 +714,54 @@ func (options *Latex) NormalText(out *bytes.Buffer, text []byte) {
 
 // header and footer
 func (options *Latex) DocumentHeader(out *bytes.Buffer) {
-	out.WriteString("\\documentclass{article}\n")
+	options.footnotes = make(map[string]bool)
+	options.image = latexImage{}
+	options.frame = latexFrame{out: out}
+	if options.flags&LATEX_FRAGMENT == 0 {
+		options.preamble(out)
+	}
+	if options.flags&LATEX_BEAMER != 0 {
+		options.beginFrame(out, nil)
+	}
+}
+
+func (options *Latex) preamble(out *bytes.Buffer) {
+	params := &options.parameters
+	classOptions := params.ClassOptions
+	if options.flags&LATEX_TITLE_PAGE != 0 {
+		if classOptions != "" {
+			classOptions += ","
+		}
+		classOptions += "titlepage"
+	}
+	out.WriteString("\\documentclass")
+	if classOptions != "" {
+		out.WriteString("[" + classOptions + "]")
+	}
+	out.WriteString("{" + params.DocumentClass + "}\n")
 	out.WriteString("\n")
 	out.WriteString("\\usepackage{graphicx}\n")
+	out.WriteString("\\usepackage{longtable}\n")
+	out.WriteString("\\usepackage{booktabs}\n")
 	out.WriteString("\\usepackage{listings}\n")
-	out.WriteString("\\usepackage[margin=1in]{geometry}\n")
+	if options.flags&LATEX_BEAMER == 0 {
+		// Beamer sets its own page size
+		out.WriteString("\\usepackage[")
+		if params.PaperSize != "" {
+			out.WriteString(params.PaperSize + ",")
+		}
+		out.WriteString("margin=" + params.Margin + "]{geometry}\n")
+	}
 	out.WriteString("\\usepackage[utf8]{inputenc}\n")
 	out.WriteString("\\usepackage{verbatim}\n")
 	out.WriteString("\\usepackage[normalem]{ulem}\n")
 	out.WriteString("\\usepackage{hyperref}\n")
 	out.WriteString("\n")
 	out.WriteString("\\hypersetup{colorlinks,%\n")
-	out.WriteString("  citecolor=black,%\n")
-	out.WriteString("  filecolor=black,%\n")
-	out.WriteString("  linkcolor=black,%\n")
-	out.WriteString("  urlcolor=black,%\n")
+	out.WriteString("  citecolor=" + params.CiteColor + ",%\n")
+	out.WriteString("  filecolor=" + params.FileColor + ",%\n")
+	out.WriteString("  linkcolor=" + params.LinkColor + ",%\n")
+	out.WriteString("  urlcolor=" + params.URLColor + ",%\n")
 	out.WriteString("  pdfstartview=FitH,%\n")
 	out.WriteString("  breaklinks=true,%\n")
 	out.WriteString("  pdfauthor={Blackfriday Markdown Processor v")
@@ -325,10 +771,22 @@ func (options *Latex) DocumentHeader(out *bytes.Buffer) {
 	out.WriteString("\\newcommand{\\HRule}{\\rule{\\linewidth}{0.5mm}}\n")
 	out.WriteString("\\addtolength{\\parskip}{0.5\\baselineskip}\n")
 	out.WriteString("\\parindent=0pt\n")
+	options.sectionNumbers(out)
+	if params.Preamble != "" {
+		out.WriteString("\n")
+		out.WriteString(params.Preamble)
+		if !strings.HasSuffix(params.Preamble, "\n") {
+			out.WriteString("\n")
+		}
+	}
 	out.WriteString("\n")
 	out.WriteString("\\begin{document}\n")
 }
 
 func (options *Latex) DocumentFooter(out *bytes.Buffer) {
+	options.endFrame(out)
+	if options.flags&LATEX_FRAGMENT != 0 {
+		return
+	}
 	out.WriteString("\n\\end{document}\n")
 }
@@ -0,0 +1,272 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the LaTeX renderer
+//
+
+package blackfriday
+
+import (
+	"strings"
+	"testing"
+)
+
+func doTestsLatex(t *testing.T, tests []string, extensions int, flags int, params LatexRendererParameters) {
+	doTestsWithRenderer(t, tests, extensions, func() Renderer {
+		return LatexRendererWithParameters(flags|LATEX_FRAGMENT, params)
+	})
+}
+
+func TestLatexFragment(t *testing.T) {
+	var tests = []string{
+		"Hello *world*\n",
+		"\nHello \\textit{world}\n",
+
+		"one\n\n***\n",
+		"\none\n\n\\rule{\\linewidth}{0.5mm}\n",
+	}
+	doTestsLatex(t, tests, 0, 0, LatexRendererParameters{})
+}
+
+func TestLatexTitleBlock(t *testing.T) {
+	var tests = []string{
+		"% Title & more\n% Ann; Bob\n% 2020\n\nText\n",
+		"\n\\title{Title \\& more}\n\\author{Ann \\and Bob}\n\\date{2020}\n\\maketitle\n\nText\n",
+
+		"% Title\n\nText\n",
+		"\n\\title{Title}\n\\author{}\n\\date{}\n\\maketitle\n\nText\n",
+	}
+	doTestsLatex(t, tests, EXTENSION_TITLEBLOCK, 0, LatexRendererParameters{})
+}
+
+func TestLatexChapters(t *testing.T) {
+	var tests = []string{
+		"# One\n\n## Two\n",
+		"\n\\chapter{One}\n\n\\section{Two}\n",
+	}
+	doTestsLatex(t, tests, 0, 0, LatexRendererParameters{DocumentClass: "report"})
+
+	tests = []string{
+		"# One\n\n## Two\n",
+		"\n\\section{One}\n\n\\subsection{Two}\n",
+	}
+	doTestsLatex(t, tests, 0, 0, LatexRendererParameters{})
+}
+
+func TestLatexHeaderNumbering(t *testing.T) {
+	var tests = []string{
+		"# One {-}\n\n## Two {#two .unnumbered}\n\n### Three\n",
+		"\n\\section*{One}\n\n\\subsection*{Two}\\label{two}\n\n\\subsubsection{Three}\n",
+	}
+	doTestsLatex(t, tests, EXTENSION_HEADER_IDS, 0, LatexRendererParameters{})
+
+	tests = []string{
+		"# One\n\n## Two\n",
+		"\n\\chapter*{One}\n\n\\section{Two}\n",
+	}
+	doTestsLatex(t, tests, 0, 0, LatexRendererParameters{DocumentClass: "report", HeaderNumberStart: 2})
+
+	doc := string(Markdown([]byte("# One\n"), LatexRendererWithParameters(0,
+		LatexRendererParameters{HeaderNumberStart: 2, HeaderNumberSeparator: "-"}), 0))
+	want := "\\setcounter{secnumdepth}{5}\n" +
+		"\\renewcommand{\\thesubsection}{\\arabic{subsection}}\n" +
+		"\\renewcommand{\\thesubsubsection}{\\thesubsection-\\arabic{subsubsection}}\n"
+	if !strings.Contains(doc, want) {
+		t.Errorf("preamble does not contain %q:\n%s", want, doc)
+	}
+	if doc := string(Markdown([]byte("# One\n"), LatexRenderer(0), 0)); strings.Contains(doc, "renewcommand") {
+		t.Errorf("unexpected section numbers in preamble:\n%s", doc)
+	}
+}
+
+func TestLatexHeaderLevelShift(t *testing.T) {
+	var tests = []string{
+		"# One\n\n## Two\n\n###### Six\n",
+		"\n\\subsection{One}\n\n\\subsubsection{Two}\n\n\\textbf{Six}\n",
+	}
+	doTestsLatex(t, tests, 0, 0, LatexRendererParameters{HeaderLevelShift: 1})
+
+	tests = []string{
+		"# One\n\n## Two\n",
+		"\n\\section*{One}\n\n\\subsection{Two}\n",
+	}
+	doTestsLatex(t, tests, 0, 0, LatexRendererParameters{DocumentClass: "book", HeaderLevelShift: 1, HeaderNumberStart: 2})
+}
+
+func TestLatexToc(t *testing.T) {
+	input := "# Intro {#intro}\n\n## *Getting* started\n\nUsage\n-----\n\n# See [Foo](http://x)\n"
+	_, toc := MarkdownToc([]byte(input), LatexRenderer(0), Options{
+		Extensions: EXTENSION_HEADER_IDS | EXTENSION_AUTO_HEADER_IDS, Slugger: SanitizedAnchorName})
+	if len(toc) != 2 || len(toc[0].Children) != 2 || len(toc[1].Children) != 0 {
+		t.Fatalf("wrong shape of tree: %+v", toc)
+	}
+	if toc[0].ID != "intro" || toc[0].Level != 1 {
+		t.Errorf("wrong entry: %+v", toc[0])
+	}
+	started := toc[0].Children[0]
+	if started.Level != 2 || started.Text != "\\textit{Getting} started" ||
+		started.PlainText != "Getting started" || started.ID != "getting-started" {
+		t.Errorf("wrong entry: %+v", started)
+	}
+	if usage := toc[0].Children[1]; usage.PlainText != "Usage" || usage.ID != "usage" {
+		t.Errorf("wrong entry: %+v", usage)
+	}
+	if toc[1].PlainText != "See Foo" || toc[1].ID != "see-foo" {
+		t.Errorf("wrong entry: %+v", toc[1])
+	}
+}
+
+func TestLatexPreamble(t *testing.T) {
+	renderer := LatexRendererWithParameters(LATEX_TITLE_PAGE, LatexRendererParameters{
+		DocumentClass: "report",
+		ClassOptions:  "11pt",
+		PaperSize:     "a4paper",
+		Margin:        "2cm",
+		LinkColor:     "blue",
+		Preamble:      "\\usepackage{amsmath}",
+	})
+	if renderer.GetFlags() != LATEX_TITLE_PAGE {
+		t.Errorf("GetFlags() = %d, want %d", renderer.GetFlags(), LATEX_TITLE_PAGE)
+	}
+	doc := string(Markdown([]byte("Text\n"), renderer, 0))
+	for _, want := range []string{
+		"\\documentclass[11pt,titlepage]{report}\n",
+		"\\usepackage[a4paper,margin=2cm]{geometry}\n",
+		"\\usepackage{longtable}\n\\usepackage{booktabs}\n",
+		"  linkcolor=blue,%\n",
+		"  urlcolor=black,%\n",
+		"\\usepackage{amsmath}\n\n\\begin{document}\n",
+		"\nText\n\n\\end{document}\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document does not contain %q:\n%s", want, doc)
+		}
+	}
+
+	doc = string(Markdown([]byte("Text\n"), LatexRenderer(0), 0))
+	for _, want := range []string{
+		"\\documentclass{article}\n",
+		"\\usepackage[margin=1in]{geometry}\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document does not contain %q:\n%s", want, doc)
+		}
+	}
+}
+
+func TestLatexEscaping(t *testing.T) {
+	var tests = []string{
+		"[a](http://x.com/a%20b#frag_1~x)\n",
+		"\n\\href{http://x.com/a\\%20b\\#frag_1\\%7Ex}{a}\n",
+
+		"<http://x.com/a_b?q=1&r=2#s>\n",
+		"\n\\href{http://x.com/a_b?q=1&r=2\\#s}{http://x.com/a\\_b?q=1\\&r=2\\#s}\n",
+
+		"Copy &copy; &amp; &eacute; &nbsp;x\n",
+		"\nCopy \\textcopyright{} \\& é ~x\n",
+
+		"`a|b\\c^d`\n",
+		"\n\\texttt{a\\textbar{}b\\textbackslash{}c\\textasciicircum{}d}\n",
+
+		"![alt_1](img/my_pic.png) ![x](a%b.png) ![r & d](https://x.com/a.png)\n",
+		"\n\\includegraphics{\\detokenize{img/my_pic.png}} x \\href{https://x.com/a.png}{r \\& d}\n",
+
+		"# Head {#my-id}\n\n[see](#my-id)\n",
+		"\n\\section{Head}\\label{my-id}\n\n\\hyperref[my-id]{see}\n",
+
+		"```py\nx\n```\n\n```brainfuck\ny\n```\n",
+		"\n\\begin{lstlisting}[language=Python]\nx\n\n\\end{lstlisting}\n\n\\begin{lstlisting}\ny\n\n\\end{lstlisting}\n",
+	}
+	doTestsLatex(t, tests, EXTENSION_AUTOLINK|EXTENSION_FENCED_CODE|EXTENSION_HEADER_IDS, 0, LatexRendererParameters{})
+}
+
+func TestLatexFootnotes(t *testing.T) {
+	var tests = []string{
+		"Text[^a] and again[^a].\n\n[^a]: The *note* & more.\n",
+		"\nText\\footnote{The \\textit{note} \\& more.} and again\\footnotemark[1].\n",
+
+		"Inline^[note here].\n",
+		"\nInline\\footnote{note here}.\n",
+
+		"x^[^1] y^^[inline]\n\n[^1]: Note.\n",
+		"\nx\\textasciicircum{}\\footnote{Note.} y\\textasciicircum{}\\footnote{inline}\n",
+	}
+	doTestsLatex(t, tests, EXTENSION_FOOTNOTES, 0, LatexRendererParameters{})
+}
+
+func TestLatexTables(t *testing.T) {
+	var tests = []string{
+		"| A | B | C |\n|:--|--:|:-:|\n| 1 | 2 | 3 |\n| *4* | 5 | |\n",
+		"\n\\begin{longtable}{lrc}\n\\toprule\nA & B & C \\\\\n\\midrule\n\\endhead\n" +
+			"1 & 2 & 3 \\\\\n\\textit{4} & 5 &  \\\\\n\\bottomrule\n\\end{longtable}\n",
+	}
+	doTestsLatex(t, tests, EXTENSION_TABLES, 0, LatexRendererParameters{})
+}
+
+func TestLatexFigures(t *testing.T) {
+	var tests = []string{
+		"![A cat](cat.png \"A sleepy cat\")\n",
+		"\n\\begin{figure}[htbp]\n\\centering\n\\includegraphics[width=0.5\\linewidth]{\\detokenize{cat.png}}\n" +
+			"\\caption{A sleepy cat}\n\\end{figure}\n",
+
+		"![x](x.png)\n",
+		"\n\\begin{figure}[htbp]\n\\centering\n\\includegraphics[width=0.5\\linewidth]{\\detokenize{x.png}}\n\\end{figure}\n",
+
+		"Look ![i](i.png) here\n",
+		"\nLook \\includegraphics[width=0.5\\linewidth]{\\detokenize{i.png}} here\n",
+
+		"[![i](i.png)](http://example.com/)\n",
+		"\n\\href{http://example.com/}{\\includegraphics[width=0.5\\linewidth]{\\detokenize{i.png}}}\n",
+	}
+	doTestsLatex(t, tests, 0, 0, LatexRendererParameters{ImageWidth: "0.5\\linewidth"})
+}
+
+func TestLatexBeamer(t *testing.T) {
+	var tests = []string{
+		"Intro\n\n# Part\n\n## First\n\nText\n\n---\n\nMore\n",
+		"\n\\begin{frame}\n\nIntro\n\n\\end{frame}\n\n\\section{Part}\n\n\\begin{frame}{First}\n\nText\n\n\\end{frame}\n" +
+			"\n\\begin{frame}\n\nMore\n\n\\end{frame}\n",
+
+		"## Code\n\n```\nx := 1\n```\n",
+		"\n\\begin{frame}[fragile]{Code}\n\n\\begin{verbatim}\nx := 1\n\n\\end{verbatim}\n\n\\end{frame}\n",
+
+		"% Deck\n% Me\n\n## One\n",
+		"\n\\title{Deck}\n\\author{Me}\n\\date{}\n\\frame{\\titlepage}\n\n\\begin{frame}{One}\n\n\\end{frame}\n",
+
+		"> ## Quoted\n",
+		"\n\\begin{frame}\n\n\\begin{quotation}\n\n\\subsection{Quoted}\n\n\\end{quotation}\n\n\\end{frame}\n",
+	}
+	doTestsLatex(t, tests, EXTENSION_FENCED_CODE|EXTENSION_TITLEBLOCK, LATEX_BEAMER, LatexRendererParameters{})
+
+	tests = []string{
+		"* a\n* b\n\n1. c\n",
+		"\n\\begin{frame}\n\n\\begin{itemize}[<+->]\n\n\\item a\n\\item b\n\\end{itemize}\n\n" +
+			"\\begin{enumerate}[<+->]\n\n\\item c\n\\end{enumerate}\n\n\\end{frame}\n",
+	}
+	doTestsLatex(t, tests, 0, LATEX_BEAMER|LATEX_INCREMENTAL, LatexRendererParameters{})
+
+	// frames follow the shifted levels
+	tests = []string{
+		"# First\n\nText\n\n## Sub\n",
+		"\n\\begin{frame}{First}\n\nText\n\n\\subsubsection{Sub}\n\n\\end{frame}\n",
+	}
+	doTestsLatex(t, tests, 0, LATEX_BEAMER, LatexRendererParameters{HeaderLevelShift: 1})
+
+	tests = []string{
+		"## Part\n\n### Slide\n\nText\n",
+		"\n\\section{Part}\n\n\\begin{frame}{Slide}\n\nText\n\n\\end{frame}\n",
+	}
+	doTestsLatex(t, tests, 0, LATEX_BEAMER, LatexRendererParameters{HeaderLevelShift: -1})
+
+	doc := string(Markdown([]byte("Text\n"), LatexRenderer(LATEX_BEAMER), 0))
+	if !strings.HasPrefix(doc, "\\documentclass{beamer}\n") || strings.Contains(doc, "geometry") {
+		t.Errorf("unexpected Beamer preamble:\n%s", doc)
+	}
+}
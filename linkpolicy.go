@@ -0,0 +1,115 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// URL policy for links and images
+//
+//
+
+package blackfriday
+
+import (
+	"strings"
+)
+
+// LinkPolicy decides which URLs the Html renderer links to and embeds,
+// when it is set in HtmlRendererParameters. It applies to links, autolinks,
+// images and the URLs in raw HTML that an HtmlPolicy lets through. Links
+// that are not allowed are written as plain text, and images as their alt
+// text.
+type LinkPolicy struct {
+	// Schemes allowed in links and images, without the colon, e.g.
+	// "https" or "tel".
+	Schemes []string
+	// Schemes allowed in images only. An entry with a colon allows data
+	// URLs of one media type, e.g. "data:image/png".
+	ImageSchemes []string
+	// Whether URLs without a scheme, such as "../index.html", "#top" and
+	// "?page=2", are allowed.
+	AllowRelative bool
+	// If set, Decide is called for every URL with the decision of the
+	// rules above, and returns the final one.
+	Decide func(link []byte, image bool, allowed bool) bool
+}
+
+// DefaultLinkPolicy returns a policy that allows relative URLs and the http,
+// https, ftp and mailto schemes.
+func DefaultLinkPolicy() *LinkPolicy {
+	return &LinkPolicy{
+		Schemes:       []string{"http", "https", "ftp", "mailto"},
+		AllowRelative: true,
+	}
+}
+
+// Allows reports whether link may be used as the target of a link, or as
+// the source of an image if image is true.
+func (policy *LinkPolicy) Allows(link []byte, image bool) bool {
+	allowed := policy.allows(link, image)
+	if policy.Decide != nil {
+		allowed = policy.Decide(link, image, allowed)
+	}
+	return allowed
+}
+
+func (policy *LinkPolicy) allows(link []byte, image bool) bool {
+	url := normalizeURL(link)
+	scheme := urlScheme(url)
+	if scheme == "" {
+		return policy.AllowRelative
+	}
+	if stringIn(scheme, policy.Schemes) {
+		return true
+	}
+	if !image {
+		return false
+	}
+	for _, allowed := range policy.ImageSchemes {
+		if !strings.Contains(allowed, ":") {
+			if scheme == allowed {
+				return true
+			}
+			continue
+		}
+		// a media type, which is followed by parameters or the data
+		prefix := strings.ToLower(allowed)
+		if len(url) > len(prefix) && strings.ToLower(url[:len(prefix)]) == prefix &&
+			(url[len(prefix)] == ';' || url[len(prefix)] == ',') {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeURL removes the whitespace and control characters that browsers
+// ignore in URLs, as in "java\tscript:".
+func normalizeURL(link []byte) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, string(link))
+}
+
+// urlScheme returns the lower case scheme of url, or an empty string if it
+// is relative.
+func urlScheme(url string) string {
+	colon := strings.IndexByte(url, ':')
+	if colon <= 0 || !isletter(url[0]) {
+		return ""
+	}
+	for i := 1; i < colon; i++ {
+		if c := url[i]; !isalnum(c) && c != '+' && c != '-' && c != '.' {
+			// the colon is in a path, query or fragment
+			return ""
+		}
+	}
+	return strings.ToLower(url[:colon])
+}
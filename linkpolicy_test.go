@@ -0,0 +1,84 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for link policies
+//
+
+package blackfriday
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkPolicyAllows(t *testing.T) {
+	policy := &LinkPolicy{
+		Schemes:       []string{"https", "tel"},
+		ImageSchemes:  []string{"data:image/png"},
+		AllowRelative: true,
+	}
+	var tests = []struct {
+		link  string
+		image bool
+		want  bool
+	}{
+		{"https://bücher.example/", false, true},
+		{"HTTPS://example.com", false, true},
+		{"tel:+1-555-0100", false, true},
+		{"http://example.com", false, false},
+		{"java\tscript:alert(1)", false, false},
+		{" javascript:alert(1)", false, false},
+		{"../index.html", false, true},
+		{"#top", false, true},
+		{"?page=2", false, true},
+		{"docs/a:b", false, true},
+		{"data:image/png;base64,iVBOR", true, true},
+		{"data:image/png;base64,iVBOR", false, false},
+		{"data:image/svg+xml,<svg/>", true, false},
+		{"data:image/pngx,", true, false},
+	}
+	for _, test := range tests {
+		if got := policy.Allows([]byte(test.link), test.image); got != test.want {
+			t.Errorf("Allows(%q, %v) = %v, want %v", test.link, test.image, got, test.want)
+		}
+	}
+
+	policy.AllowRelative = false
+	policy.Decide = func(link []byte, image bool, allowed bool) bool {
+		return allowed || strings.HasPrefix(string(link), "/wiki/")
+	}
+	if policy.Allows([]byte("../x"), false) || !policy.Allows([]byte("/wiki/Go"), false) {
+		t.Errorf("Decide was not applied")
+	}
+}
+
+func TestLinkPolicyRendering(t *testing.T) {
+	var tests = []string{
+		"[call](tel:555) [bad](javascript:alert(1)) [ok](https://example.com/)\n",
+		"<p><a href=\"tel:555\">call</a> <tt>bad</tt> <a href=\"https://example.com/\">ok</a></p>\n",
+
+		"![dot](data:image/png;base64,AAAA) ![svg](data:image/svg+xml,x)\n",
+		"<p><img src=\"data:image/png;base64,AAAA\" alt=\"dot\" /> svg</p>\n",
+
+		"<ftp://example.com/> and <me@example.com>\n",
+		"<p><tt>ftp://example.com/</tt> and <a href=\"mailto:me@example.com\">me@example.com</a></p>\n",
+
+		"<a href=\"tel:1\">a</a> <a href=\"ftp://x\">b</a> <img src=\"data:image/png;base64,AA\">\n",
+		"<p><a href=\"tel:1\">a</a> <a>b</a> <img src=\"data:image/png;base64,AA\"></p>\n",
+	}
+	doTestsInlineParam(t, tests, Options{}, 0, HtmlRendererParameters{
+		HtmlPolicy: DefaultHtmlPolicy(),
+		LinkPolicy: &LinkPolicy{
+			Schemes:       []string{"https", "tel", "mailto"},
+			ImageSchemes:  []string{"data:image/png"},
+			AllowRelative: true,
+		},
+	})
+}
@@ -44,6 +44,7 @@ const (
 	EXTENSION_BACKSLASH_LINE_BREAK                   // translate trailing backslashes into line breaks
 	EXTENSION_DEFINITION_LISTS                       // render definition lists
 	EXTENSION_JOIN_LINES                             // delete newline and join lines
+	EXTENSION_TOC_MARKER                             // [TOC] or [[_TOC_]] marks where the table of contents goes
 
 	commonHtmlFlags = 0 |
 		HTML_USE_XHTML |
@@ -83,6 +84,10 @@ const (
 	LIST_ITEM_CONTAINS_BLOCK
 	LIST_ITEM_BEGINNING_OF_LIST
 	LIST_ITEM_END_OF_LIST
+
+	// passed to FootnoteRefContent for an inline footnote, ^[...], whose ^
+	// the renderer has already been given as text
+	FOOTNOTE_INLINE
 )
 
 // These are the possible flag values for the table cell renderer.
@@ -158,7 +163,8 @@ var blockTags = map[string]struct{}{
 // If the callback returns false, the rendering function should reset the
 // output buffer as though it had never been called.
 //
-// Currently Html and Latex implementations are provided
+// Currently Html, Latex, Rst, AsciiDoc, DocBook, Docx, Odt, Chat, Jira and
+// Confluence implementations are provided
 type Renderer interface {
 	// block-level callbacks
 	BlockCode(out *bytes.Buffer, text []byte, infoString string)
@@ -201,6 +207,86 @@ type Renderer interface {
 	GetFlags() int
 }
 
+// FootnoteContentRenderer is an optional interface for renderers that place
+// the contents of a footnote at the point where it is referenced (e.g.
+// AsciiDoc's footnote:[...]) rather than in a list at the end of the document.
+//
+// When the renderer implements it, FootnoteRefContent is called instead of
+// FootnoteRef. text holds the rendered contents of the note, and flags has
+// LIST_ITEM_CONTAINS_BLOCK set if they were rendered as block-level elements
+// and FOOTNOTE_INLINE set for inline footnotes.
+// The Footnotes callback is still invoked at the end of the document.
+type FootnoteContentRenderer interface {
+	FootnoteRefContent(out *bytes.Buffer, ref []byte, text []byte, id int, flags int)
+}
+
+// URLResolverRenderer is an optional interface for renderers that make up
+// link targets of their own, such as the targets of footnote references.
+// MarkdownOptions hands them the URLResolver from its options before
+// rendering, which may be nil.
+type URLResolverRenderer interface {
+	SetURLResolver(resolver URLResolver)
+}
+
+// HeaderAttributes are the attributes given to a header in braces after its
+// text with EXTENSION_HEADER_IDS, as in "# Header {#id .class -}". A "-"
+// stands for the class "unnumbered".
+type HeaderAttributes struct {
+	ID      string
+	Classes []string
+}
+
+// HasClass tells whether the header has the given class.
+func (attrs HeaderAttributes) HasClass(class string) bool {
+	return stringIn(class, attrs.Classes)
+}
+
+// HeaderAttributesRenderer is an optional interface for renderers that use
+// the attributes of headers. When the renderer implements it,
+// HeaderWithAttributes is called instead of Header.
+type HeaderAttributesRenderer interface {
+	HeaderWithAttributes(out *bytes.Buffer, text func() bool, level int, attrs HeaderAttributes)
+}
+
+// Metadata holds the fields of a Pandoc-style title block at the start of a
+// document, with EXTENSION_TITLEBLOCK:
+//
+//	% title
+//	% author(s) (separated by semicolons)
+//	% date
+type Metadata struct {
+	Title   string
+	Authors []string
+	Date    string
+}
+
+// MetadataRenderer is an optional interface for renderers that use the title
+// block before it is rendered, e.g. in the head of an HTML page.
+// MarkdownOptions hands them the metadata of the document before calling
+// DocumentHeader, which is empty if there is no title block or
+// EXTENSION_TITLEBLOCK is not set.
+type MetadataRenderer interface {
+	SetMetadata(meta Metadata)
+}
+
+// TocMarkerRenderer is an optional interface for renderers that can place a
+// table of contents in the document. With EXTENSION_TOC_MARKER, a top-level
+// paragraph holding only [TOC] or [[_TOC_]] is handed to TocMarker instead
+// of being rendered. TocMarker returns false if the renderer makes no table
+// of contents, in which case the marker is rendered as an ordinary
+// paragraph, as it is for other renderers.
+type TocMarkerRenderer interface {
+	TocMarker(out *bytes.Buffer) bool
+}
+
+// TocRenderer is implemented by renderers that keep the outline of the last
+// document they rendered, such as Html, whose header IDs and numbers are only
+// known once rendered. MarkdownToc returns their outline instead of the one
+// the parser collects.
+type TocRenderer interface {
+	Toc() []*TocEntry
+}
+
 // Callback functions for inline parsing. One such function is defined
 // for each character that triggers a response when parsing inline data.
 type inlineParser func(p *parser, out *bytes.Buffer, data []byte, offset int) int
@@ -210,6 +296,8 @@ type inlineParser func(p *parser, out *bytes.Buffer, data []byte, offset int) in
 type parser struct {
 	r              Renderer
 	refOverride    ReferenceOverrideFunc
+	urlResolver    URLResolver
+	slugger        Slugger
 	refs           map[string]*reference
 	inlineCallback [256]inlineParser
 	flags          int
@@ -222,6 +310,9 @@ type parser struct {
 	// in notes. Slice is nil if footnotes not enabled.
 	notes       []*reference
 	notesRecord map[string]struct{}
+
+	// the headers of the document in order, for MarkdownToc
+	headings []tocHeading
 }
 
 func (p *parser) getRef(refid string) (ref *reference, found bool) {
@@ -273,6 +364,20 @@ type Reference struct {
 // See the documentation in Options for more details on use-case.
 type ReferenceOverrideFunc func(reference string) (ref *Reference, overridden bool)
 
+// Kinds of URLs passed to a URLResolver.
+const (
+	URL_LINK     = iota // the destination of a link
+	URL_AUTOLINK        // an autolink, which is also its text
+	URL_IMAGE           // the source of an image
+	URL_FOOTNOTE        // the target of a footnote reference or return link
+)
+
+// URLResolver is called with each URL in the document and one of the URL_*
+// kinds, and returns the URL to use instead, or url itself to keep it. For
+// links, images and autolinks, returning an empty URL leaves the text as it
+// was written.
+type URLResolver func(kind int, url []byte) []byte
+
 // Options represents configurable overrides and callbacks (in addition to the
 // extension flag set) for configuring a Markdown parse.
 type Options struct {
@@ -295,6 +400,17 @@ type Options struct {
 	// the override function indicates an override did not occur, the refids at
 	// the bottom will be used to fill in the link details.
 	ReferenceOverride ReferenceOverrideFunc
+
+	// URLResolver is an optional function callback that rewrites the
+	// destinations of links, autolinks and images, whichever renderer is
+	// used, and the footnote targets of renderers that implement
+	// URLResolverRenderer.
+	URLResolver URLResolver
+
+	// Slugger makes the IDs of headers with EXTENSION_AUTO_HEADER_IDS. If
+	// nil, SanitizedAnchorName is used on the text of the header as it was
+	// written, markup included, which keeps the IDs of earlier versions.
+	Slugger Slugger
 }
 
 // MarkdownBasic is a convenience function for simple rendering.
@@ -349,9 +465,19 @@ func Markdown(input []byte, renderer Renderer, extensions int) []byte {
 // MarkdownOptions is just like Markdown but takes additional options through
 // the Options struct.
 func MarkdownOptions(input []byte, renderer Renderer, opts Options) []byte {
+	output, _ := MarkdownToc(input, renderer, opts)
+	return output
+}
+
+// MarkdownToc is like MarkdownOptions, but also returns the headers of the
+// document as a tree, in which each header is a child of the closest header
+// of a higher level before it. This works with any renderer; the text of each
+// entry is as the renderer wrote it, and its ID is the one from header
+// attributes or EXTENSION_AUTO_HEADER_IDS.
+func MarkdownToc(input []byte, renderer Renderer, opts Options) ([]byte, []*TocEntry) {
 	// no point in parsing if we can't render
 	if renderer == nil {
-		return nil
+		return nil, nil
 	}
 
 	extensions := opts.Extensions
@@ -361,6 +487,18 @@ func MarkdownOptions(input []byte, renderer Renderer, opts Options) []byte {
 	p.r = renderer
 	p.flags = extensions
 	p.refOverride = opts.ReferenceOverride
+	p.urlResolver = opts.URLResolver
+	p.slugger = opts.Slugger
+	if r, ok := renderer.(URLResolverRenderer); ok {
+		r.SetURLResolver(opts.URLResolver)
+	}
+	if r, ok := renderer.(MetadataRenderer); ok {
+		var meta Metadata
+		if extensions&EXTENSION_TITLEBLOCK != 0 {
+			meta, _ = ParseTitleBlock(input)
+		}
+		r.SetMetadata(meta)
+	}
 	p.refs = make(map[string]*reference)
 	p.maxNesting = 16
 	p.insideLink = false
@@ -389,7 +527,10 @@ func MarkdownOptions(input []byte, renderer Renderer, opts Options) []byte {
 
 	first := firstPass(p, input)
 	second := secondPass(p, first)
-	return second
+	if r, ok := renderer.(TocRenderer); ok {
+		return second, r.Toc()
+	}
+	return second, buildToc(p.headings)
 }
 
 // first pass:
@@ -908,6 +1049,20 @@ func isIndented(data []byte, indentSize int) int {
 	return indentSize
 }
 
+// Extract the language from the info string of a fenced code block.
+// Returns an empty string if none was given.
+func codeLanguage(info string) string {
+	endOfLang := strings.IndexAny(info, "\t ")
+	if endOfLang < 0 {
+		endOfLang = len(info)
+	}
+	lang := info[:endOfLang]
+	if lang == "." {
+		return ""
+	}
+	return lang
+}
+
 // Create a url-safe slug for fragments
 func slugify(in []byte) []byte {
 	if len(in) == 0 {
@@ -52,6 +52,39 @@ func doTests(t *testing.T, tests []string) {
 	}
 }
 
+// doTestsWithRenderer runs input/expected pairs through a fresh renderer
+// from newRenderer for every document.
+func doTestsWithRenderer(t *testing.T, tests []string, extensions int, newRenderer func() Renderer) {
+	// catch and report panics
+	var candidate string
+	defer func() {
+		if err := recover(); err != nil {
+			t.Errorf("\npanic while processing [%#v]: %s\n", candidate, err)
+		}
+	}()
+
+	for i := 0; i+1 < len(tests); i += 2 {
+		input := tests[i]
+		candidate = input
+		expected := tests[i+1]
+		actual := string(Markdown([]byte(candidate), newRenderer(), extensions))
+		if actual != expected {
+			t.Errorf("\nInput   [%#v]\nExpected[%#v]\nActual  [%#v]",
+				candidate, expected, actual)
+		}
+
+		// now test every substring to stress test bounds checking
+		if !testing.Short() {
+			for start := 0; start < len(input); start++ {
+				for end := start + 1; end <= len(input); end++ {
+					candidate = input[start:end]
+					_ = Markdown([]byte(candidate), newRenderer(), extensions)
+				}
+			}
+		}
+	}
+}
+
 func TestDocument(t *testing.T) {
 	var tests = []string{
 		// Empty document.
@@ -0,0 +1,660 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// OpenDocument Text (.odt) rendering backend
+//
+//
+
+package blackfriday
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// OdtRendererParameters holds the options of the Odt renderer that are not
+// flags.
+type OdtRendererParameters struct {
+	// Directory that relative image paths are resolved against. Local PNG,
+	// JPEG and GIF images inside it are embedded in the document; other
+	// images are written as links.
+	BaseDir string
+}
+
+// Odt is a type that implements the Renderer interface for OpenDocument
+// Text output. The result of Markdown is the complete .odt package, so an
+// Odt object must not be reused for several documents at the same time.
+//
+// Do not create this directly, instead use the OdtRenderer function.
+type Odt struct {
+	flags      int
+	parameters OdtRendererParameters
+
+	start int // position of content.xml in the output
+
+	// Track IDs to keep bookmark names unique within the document.
+	ids map[string]int
+
+	// footnotes already written out, by reference
+	notes map[string]int
+
+	// embedded images, in order, and their names in the package by file
+	pictures []odtPicture
+	names    map[string]string
+	frames   int
+
+	tables int
+
+	// the most recent image, in case it turns out to be a figure
+	lastImage []byte
+	lastTitle []byte
+}
+
+type odtPicture struct {
+	name string
+	data []byte
+}
+
+const (
+	odtMaxWidth = 17.0 // text width in cm (A4 with 2cm margins)
+
+	odtNamespaces = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+		`xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0" ` +
+		`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" ` +
+		`xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" ` +
+		`xmlns:draw="urn:oasis:names:tc:opendocument:xmlns:drawing:1.0" ` +
+		`xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0" ` +
+		`xmlns:xlink="http://www.w3.org/1999/xlink" ` +
+		`xmlns:svg="urn:oasis:names:tc:opendocument:xmlns:svg-compatible:1.0" ` +
+		`office:version="1.2"`
+)
+
+// OdtRenderer creates and configures an Odt object, which
+// satisfies the Renderer interface.
+//
+// flags is a set of ODT_* options ORed together (currently no such
+// options are defined).
+func OdtRenderer(flags int) Renderer {
+	return OdtRendererWithParameters(flags, OdtRendererParameters{})
+}
+
+func OdtRendererWithParameters(flags int, renderParameters OdtRendererParameters) Renderer {
+	return &Odt{
+		flags:      flags,
+		parameters: renderParameters,
+	}
+}
+
+func (options *Odt) GetFlags() int {
+	return options.flags
+}
+
+func (options *Odt) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	out.WriteString("<text:p text:style-name=\"Preformatted_20_Text\">")
+	odtPreformatted(out, bytes.TrimSuffix(text, []byte("\n")))
+	out.WriteString("</text:p>\n")
+}
+
+// odtPreformatted writes text keeping its white space, which is otherwise
+// collapsed.
+func odtPreformatted(out *bytes.Buffer, text []byte) {
+	spaces := 0
+	flush := func() {
+		if spaces > 0 {
+			out.WriteString("<text:s text:c=\"")
+			out.WriteString(strconv.Itoa(spaces))
+			out.WriteString("\"/>")
+			spaces = 0
+		}
+	}
+	mark := 0
+	for i, c := range text {
+		if c != ' ' && c != '\t' && c != '\n' {
+			continue
+		}
+		xmlEscape(out, text[mark:i])
+		mark = i + 1
+		switch {
+		case c == '\t':
+			flush()
+			out.WriteString("<text:tab/>")
+		case c == '\n':
+			flush()
+			out.WriteString("<text:line-break/>")
+		case i > 0 && text[i-1] != ' ' && text[i-1] != '\t' && text[i-1] != '\n':
+			// a single space between words needs no markup
+			out.WriteByte(' ')
+		default:
+			spaces++
+			if i+1 == len(text) || text[i+1] != ' ' {
+				flush()
+			}
+		}
+	}
+	xmlEscape(out, text[mark:])
+}
+
+func (options *Odt) TitleBlock(out *bytes.Buffer, text []byte) {
+	title, authors, date := titleBlockFields(text)
+	odtParagraph(out, "Title", []byte(title))
+	if len(authors) > 0 {
+		odtParagraph(out, "Subtitle", []byte(strings.Join(authors, "; ")))
+	}
+	if date != "" {
+		odtParagraph(out, "Subtitle", []byte(date))
+	}
+}
+
+// odtParagraph writes text as a paragraph with the given style.
+func odtParagraph(out *bytes.Buffer, style string, text []byte) {
+	out.WriteString("<text:p text:style-name=\"")
+	out.WriteString(style)
+	out.WriteString("\">")
+	xmlEscape(out, text)
+	out.WriteString("</text:p>\n")
+}
+
+func (options *Odt) BlockQuote(out *bytes.Buffer, text []byte) {
+	var content bytes.Buffer
+	odtBlockContent(&content, text, "Quotations")
+	out.Write(odtRestyle(content.Bytes(), "Quotations"))
+}
+
+// odtRestyle gives the body text paragraphs in text another style.
+func odtRestyle(text []byte, style string) []byte {
+	return bytes.Replace(text, []byte("<text:p text:style-name=\"Text_20_body\">"),
+		[]byte("<text:p text:style-name=\""+style+"\">"), -1)
+}
+
+// Raw HTML cannot be represented in OpenDocument, so show it as code instead.
+func (options *Odt) BlockHtml(out *bytes.Buffer, text []byte) {
+	options.BlockCode(out, text, "html")
+}
+
+func (options *Odt) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	marker := out.Len()
+	fmt.Fprintf(out, "<text:h text:style-name=\"Heading_20_%d\" text:outline-level=\"%d\">", level, level)
+	if id != "" {
+		out.WriteString("<text:bookmark text:name=\"")
+		xmlEscape(out, []byte(uniqueID(options.ids, id)))
+		out.WriteString("\"/>")
+	}
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteString("</text:h>\n")
+}
+
+func (options *Odt) HRule(out *bytes.Buffer) {
+	out.WriteString("<text:p text:style-name=\"Horizontal_20_Line\"/>\n")
+}
+
+func (options *Odt) List(out *bytes.Buffer, text func() bool, flags int) {
+	marker := out.Len()
+	if flags&LIST_TYPE_DEFINITION != 0 {
+		if !text() {
+			out.Truncate(marker)
+		}
+		return
+	}
+
+	if flags&LIST_TYPE_ORDERED != 0 {
+		out.WriteString("<text:list text:style-name=\"Numbering_20_123\">\n")
+	} else {
+		out.WriteString("<text:list text:style-name=\"List_20_1\">\n")
+	}
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteString("</text:list>\n")
+}
+
+func (options *Odt) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	switch {
+	case flags&LIST_TYPE_TERM != 0:
+		out.WriteString("<text:p text:style-name=\"Definition_20_Term\">")
+		out.Write(bytes.TrimSpace(text))
+		out.WriteString("</text:p>\n")
+
+	case flags&LIST_TYPE_DEFINITION != 0:
+		var content bytes.Buffer
+		odtBlockContent(&content, text, "Definition_20_Definition")
+		out.Write(odtRestyle(content.Bytes(), "Definition_20_Definition"))
+
+	default:
+		out.WriteString("<text:list-item>")
+		odtBlockContent(out, text, "List_20_Contents")
+		out.WriteString("</text:list-item>\n")
+	}
+}
+
+// Write block-level content, wrapping any leading inline content in a
+// paragraph with the given style.
+func odtBlockContent(out *bytes.Buffer, text []byte, style string) {
+	inline := odtBlockStart(text)
+	if lead := bytes.TrimSpace(text[:inline]); len(lead) > 0 || inline == len(text) {
+		out.WriteString("<text:p text:style-name=\"")
+		out.WriteString(style)
+		out.WriteString("\">")
+		out.Write(lead)
+		out.WriteString("</text:p>\n")
+	}
+	out.Write(text[inline:])
+	startLine(out)
+}
+
+// odtBlockStart returns the position of the first block-level element in
+// text, skipping the paragraphs inside notes.
+func odtBlockStart(text []byte) int {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '<' {
+			continue
+		}
+		rest := text[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<text:note ")):
+			end := bytes.Index(rest, []byte("</text:note>"))
+			if end < 0 {
+				return len(text)
+			}
+			i += end
+		case bytes.HasPrefix(rest, []byte("<text:p ")), bytes.HasPrefix(rest, []byte("<text:h ")),
+			bytes.HasPrefix(rest, []byte("<text:list ")), bytes.HasPrefix(rest, []byte("<table:table ")):
+			return i
+		}
+	}
+	return len(text)
+}
+
+func (options *Odt) Paragraph(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+
+	out.WriteString("<text:p text:style-name=\"Text_20_body\">")
+	start := out.Len()
+	options.lastImage = nil
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+
+	// an image alone in a paragraph is a figure, captioned with its title
+	if options.lastImage != nil && len(options.lastTitle) > 0 &&
+		bytes.Equal(out.Bytes()[start:], options.lastImage) {
+		out.Truncate(marker)
+		out.WriteString("<text:p text:style-name=\"Figure\">")
+		out.Write(options.lastImage)
+		out.WriteString("</text:p>\n")
+		odtParagraph(out, "Caption", options.lastTitle)
+		return
+	}
+	out.WriteString("</text:p>\n")
+}
+
+func (options *Odt) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	options.tables++
+	fmt.Fprintf(out, "<table:table table:name=\"Table%d\" table:style-name=\"Table\">\n", options.tables)
+	fmt.Fprintf(out, "<table:table-column table:number-columns-repeated=\"%d\"/>\n", len(columnData))
+	out.WriteString("<table:table-header-rows>\n")
+	out.Write(header)
+	out.WriteString("</table:table-header-rows>\n")
+	out.Write(body)
+	out.WriteString("</table:table>\n")
+}
+
+func (options *Odt) TableRow(out *bytes.Buffer, text []byte) {
+	out.WriteString("<table:table-row>")
+	out.Write(text)
+	out.WriteString("</table:table-row>\n")
+}
+
+func (options *Odt) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
+	odtTableCell(out, text, "Table_20_Heading", align)
+}
+
+func (options *Odt) TableCell(out *bytes.Buffer, text []byte, align int) {
+	odtTableCell(out, text, "Table_20_Contents", align)
+}
+
+func odtTableCell(out *bytes.Buffer, text []byte, style string, align int) {
+	out.WriteString("<table:table-cell table:style-name=\"Table_Cell\" office:value-type=\"string\">")
+	out.WriteString("<text:p text:style-name=\"")
+	out.WriteString(style)
+	if align != 0 {
+		out.WriteString("_20_")
+		out.WriteString(odtAlignName(alignments[align-1]))
+	}
+	out.WriteString("\">")
+	out.Write(text)
+	out.WriteString("</text:p></table:table-cell>")
+}
+
+// Footnotes are written where they are referenced, see FootnoteRefContent.
+func (options *Odt) Footnotes(out *bytes.Buffer, text func() bool) {
+}
+
+func (options *Odt) FootnoteItem(out *bytes.Buffer, name, text []byte, flags int) {
+}
+
+func (options *Odt) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	var content bytes.Buffer
+	options.NormalText(&content, link)
+	if kind == LINK_TYPE_EMAIL && !bytes.HasPrefix(link, []byte("mailto:")) {
+		link = append([]byte("mailto:"), link...)
+	}
+	options.Link(out, link, nil, content.Bytes())
+}
+
+func (options *Odt) CodeSpan(out *bytes.Buffer, text []byte) {
+	out.WriteString("<text:span text:style-name=\"Source_20_Text\">")
+	odtPreformatted(out, text)
+	out.WriteString("</text:span>")
+}
+
+func (options *Odt) DoubleEmphasis(out *bytes.Buffer, text []byte) {
+	odtSpan(out, "Strong_20_Emphasis", text)
+}
+
+func (options *Odt) Emphasis(out *bytes.Buffer, text []byte) {
+	odtSpan(out, "Emphasis", text)
+}
+
+func odtSpan(out *bytes.Buffer, style string, text []byte) {
+	out.WriteString("<text:span text:style-name=\"")
+	out.WriteString(style)
+	out.WriteString("\">")
+	out.Write(text)
+	out.WriteString("</text:span>")
+}
+
+func (options *Odt) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	img, ok := readLocalImage(options.parameters.BaseDir, link)
+	if !ok {
+		var content bytes.Buffer
+		if len(alt) > 0 {
+			options.NormalText(&content, alt)
+		} else {
+			options.NormalText(&content, link)
+		}
+		options.Link(out, link, title, content.Bytes())
+		return
+	}
+
+	name, found := options.names[img.file]
+	if !found {
+		name = fmt.Sprintf("Pictures/image%d.%s", len(options.pictures)+1, img.format)
+		options.names[img.file] = name
+		options.pictures = append(options.pictures, odtPicture{name, img.data})
+	}
+
+	// assume 96 dpi
+	width, height := float64(img.width)*2.54/96, float64(img.height)*2.54/96
+	if width > odtMaxWidth {
+		width, height = odtMaxWidth, height*odtMaxWidth/width
+	}
+
+	options.frames++
+	start := out.Len()
+	fmt.Fprintf(out, "<draw:frame draw:name=\"Image%d\" draw:style-name=\"Image\" text:anchor-type=\"as-char\" "+
+		"svg:width=\"%.3fcm\" svg:height=\"%.3fcm\">", options.frames, width, height)
+	out.WriteString("<draw:image xlink:href=\"")
+	out.WriteString(name)
+	out.WriteString("\" xlink:type=\"simple\" xlink:show=\"embed\" xlink:actuate=\"onLoad\"/>")
+	if len(title) > 0 {
+		out.WriteString("<svg:title>")
+		xmlEscape(out, title)
+		out.WriteString("</svg:title>")
+	}
+	if len(alt) > 0 {
+		out.WriteString("<svg:desc>")
+		xmlEscape(out, alt)
+		out.WriteString("</svg:desc>")
+	}
+	out.WriteString("</draw:frame>")
+
+	options.lastImage = append([]byte(nil), out.Bytes()[start:]...)
+	options.lastTitle = append([]byte(nil), title...)
+}
+
+func (options *Odt) LineBreak(out *bytes.Buffer) {
+	out.WriteString("<text:line-break/>")
+}
+
+func (options *Odt) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	out.WriteString("<text:a xlink:type=\"simple\" xlink:href=\"")
+	xmlEscape(out, link)
+	if len(title) > 0 {
+		out.WriteString("\" office:title=\"")
+		xmlEscape(out, title)
+	}
+	out.WriteString("\" text:style-name=\"Internet_20_link\">")
+	out.Write(content)
+	out.WriteString("</text:a>")
+}
+
+// Raw HTML cannot be represented in OpenDocument, so it is dropped.
+func (options *Odt) RawHtmlTag(out *bytes.Buffer, tag []byte) {
+}
+
+func (options *Odt) TripleEmphasis(out *bytes.Buffer, text []byte) {
+	var strong bytes.Buffer
+	odtSpan(&strong, "Strong_20_Emphasis", text)
+	odtSpan(out, "Emphasis", strong.Bytes())
+}
+
+func (options *Odt) StrikeThrough(out *bytes.Buffer, text []byte) {
+	odtSpan(out, "Strikethrough", text)
+}
+
+func (options *Odt) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
+	// a note can only appear once, later references point to it
+	note := options.notes[string(ref)]
+	out.WriteString("<text:span text:style-name=\"Footnote_20_anchor\">")
+	fmt.Fprintf(out, "<text:note-ref text:note-class=\"footnote\" text:reference-format=\"text\" text:ref-name=\"ftn%d\">%d</text:note-ref>",
+		note, note)
+	out.WriteString("</text:span>")
+}
+
+func (options *Odt) FootnoteRefContent(out *bytes.Buffer, ref []byte, text []byte, id int, flags int) {
+	if _, found := options.notes[string(ref)]; found {
+		options.FootnoteRef(out, ref, id)
+		return
+	}
+	note := len(options.notes) + 1
+	options.notes[string(ref)] = note
+
+	fmt.Fprintf(out, "<text:note text:id=\"ftn%d\" text:note-class=\"footnote\">", note)
+	fmt.Fprintf(out, "<text:note-citation>%d</text:note-citation><text:note-body>", note)
+	var content bytes.Buffer
+	odtBlockContent(&content, text, "Footnote")
+	out.Write(bytes.TrimSpace(odtRestyle(content.Bytes(), "Footnote")))
+	out.WriteString("</text:note-body></text:note>")
+}
+
+func (options *Odt) Entity(out *bytes.Buffer, entity []byte) {
+	options.NormalText(out, []byte(html.UnescapeString(string(entity))))
+}
+
+func (options *Odt) NormalText(out *bytes.Buffer, text []byte) {
+	xmlEscape(out, text)
+}
+
+func (options *Odt) DocumentHeader(out *bytes.Buffer) {
+	options.start = out.Len()
+	options.ids = make(map[string]int)
+	options.notes = make(map[string]int)
+	options.pictures = nil
+	options.names = make(map[string]string)
+	options.frames = 0
+	options.tables = 0
+
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	out.WriteString("<office:document-content " + odtNamespaces + ">\n")
+	out.WriteString(odtAutomaticStyles)
+	out.WriteString("<office:body>\n<office:text>\n")
+}
+
+// DocumentFooter finishes content.xml and replaces it in the output with
+// the complete .odt package.
+func (options *Odt) DocumentFooter(out *bytes.Buffer) {
+	out.WriteString("</office:text>\n</office:body>\n</office:document-content>\n")
+
+	content := append([]byte(nil), out.Bytes()[options.start:]...)
+	out.Truncate(options.start)
+
+	// writing to a bytes.Buffer cannot fail, so errors are ignored
+	z := zip.NewWriter(out)
+
+	// the mimetype must come first and be stored uncompressed
+	f, _ := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	f.Write([]byte("application/vnd.oasis.opendocument.text"))
+
+	write := func(name string, data []byte) {
+		f, _ := z.Create(name)
+		f.Write(data)
+	}
+	write("META-INF/manifest.xml", options.manifest())
+	write("content.xml", content)
+	write("styles.xml", []byte(odtStyles()))
+	for _, picture := range options.pictures {
+		write(picture.name, picture.data)
+	}
+	z.Close()
+}
+
+func (options *Odt) manifest() []byte {
+	var out bytes.Buffer
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	out.WriteString("<manifest:manifest xmlns:manifest=\"urn:oasis:names:tc:opendocument:xmlns:manifest:1.0\" manifest:version=\"1.2\">\n")
+	out.WriteString(" <manifest:file-entry manifest:full-path=\"/\" manifest:version=\"1.2\" ")
+	out.WriteString("manifest:media-type=\"application/vnd.oasis.opendocument.text\"/>\n")
+	out.WriteString(" <manifest:file-entry manifest:full-path=\"content.xml\" manifest:media-type=\"text/xml\"/>\n")
+	out.WriteString(" <manifest:file-entry manifest:full-path=\"styles.xml\" manifest:media-type=\"text/xml\"/>\n")
+	for _, picture := range options.pictures {
+		format := picture.name[strings.LastIndexByte(picture.name, '.')+1:]
+		fmt.Fprintf(&out, " <manifest:file-entry manifest:full-path=\"%s\" manifest:media-type=\"image/%s\"/>\n",
+			picture.name, format)
+	}
+	out.WriteString("</manifest:manifest>\n")
+	return out.Bytes()
+}
+
+const odtAutomaticStyles = `<office:automatic-styles>
+<style:style style:name="Table" style:family="table"><style:table-properties style:width="17cm" table:align="margins"/></style:style>
+<style:style style:name="Table_Cell" style:family="table-cell"><style:table-cell-properties fo:padding="0.1cm" fo:border="0.5pt solid #000000"/></style:style>
+<style:style style:name="Image" style:family="graphic"><style:graphic-properties style:vertical-pos="top" style:vertical-rel="baseline"/></style:style>
+</office:automatic-styles>
+`
+
+// odtStyles returns styles.xml, which holds the common styles used in
+// content.xml.
+func odtStyles() string {
+	var out bytes.Buffer
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	out.WriteString("<office:document-styles " + odtNamespaces + ">\n")
+	out.WriteString("<office:styles>\n")
+	out.WriteString(`<style:default-style style:family="paragraph"><style:text-properties fo:font-size="11pt"/></style:default-style>
+<style:style style:name="Standard" style:family="paragraph" style:class="text"/>
+<style:style style:name="Text_20_body" style:display-name="Text body" style:family="paragraph" style:parent-style-name="Standard" style:class="text"><style:paragraph-properties fo:margin-top="0cm" fo:margin-bottom="0.25cm"/></style:style>
+<style:style style:name="Heading" style:family="paragraph" style:parent-style-name="Standard" style:next-style-name="Text_20_body" style:class="text"><style:paragraph-properties fo:margin-top="0.42cm" fo:margin-bottom="0.21cm" fo:keep-with-next="always"/><style:text-properties fo:font-weight="bold"/></style:style>
+`)
+	for level, size := range []string{"18pt", "16pt", "14pt", "12pt", "11pt", "10pt"} {
+		fmt.Fprintf(&out, "<style:style style:name=\"Heading_20_%d\" style:display-name=\"Heading %d\" style:family=\"paragraph\" "+
+			"style:parent-style-name=\"Heading\" style:next-style-name=\"Text_20_body\" style:default-outline-level=\"%d\" style:class=\"text\">"+
+			"<style:text-properties fo:font-size=\"%s\"/></style:style>\n", level+1, level+1, level+1, size)
+	}
+	out.WriteString(`<style:style style:name="Title" style:family="paragraph" style:parent-style-name="Heading" style:class="chapter"><style:paragraph-properties fo:text-align="center"/><style:text-properties fo:font-size="24pt"/></style:style>
+<style:style style:name="Subtitle" style:family="paragraph" style:parent-style-name="Standard" style:class="chapter"><style:paragraph-properties fo:text-align="center" fo:margin-bottom="0.21cm"/><style:text-properties fo:font-size="14pt"/></style:style>
+<style:style style:name="Quotations" style:family="paragraph" style:parent-style-name="Text_20_body" style:class="html"><style:paragraph-properties fo:margin-left="1cm" fo:margin-right="1cm"/></style:style>
+<style:style style:name="Preformatted_20_Text" style:display-name="Preformatted Text" style:family="paragraph" style:parent-style-name="Standard" style:class="html"><style:paragraph-properties fo:margin-bottom="0.25cm" fo:background-color="#f2f2f2"/><style:text-properties style:font-name="Liberation Mono" fo:font-family="'Liberation Mono', 'Courier New', monospace" fo:font-size="10pt"/></style:style>
+<style:style style:name="List_20_Contents" style:display-name="List Contents" style:family="paragraph" style:parent-style-name="Text_20_body" style:class="list"/>
+<style:style style:name="Definition_20_Term" style:display-name="Definition Term" style:family="paragraph" style:parent-style-name="Standard" style:class="list"><style:paragraph-properties fo:keep-with-next="always"/><style:text-properties fo:font-weight="bold"/></style:style>
+<style:style style:name="Definition_20_Definition" style:display-name="Definition Definition" style:family="paragraph" style:parent-style-name="Text_20_body" style:class="list"><style:paragraph-properties fo:margin-left="1cm"/></style:style>
+<style:style style:name="Horizontal_20_Line" style:display-name="Horizontal Line" style:family="paragraph" style:parent-style-name="Standard" style:class="html"><style:paragraph-properties fo:margin-bottom="0.25cm" fo:border-bottom="0.5pt solid #808080"/></style:style>
+<style:style style:name="Figure" style:family="paragraph" style:parent-style-name="Standard" style:class="extra"><style:paragraph-properties fo:text-align="center"/></style:style>
+<style:style style:name="Caption" style:family="paragraph" style:parent-style-name="Standard" style:class="extra"><style:paragraph-properties fo:text-align="center" fo:margin-bottom="0.25cm"/><style:text-properties fo:font-style="italic" fo:font-size="10pt"/></style:style>
+<style:style style:name="Footnote" style:family="paragraph" style:parent-style-name="Standard" style:class="extra"><style:text-properties fo:font-size="10pt"/></style:style>
+`)
+	for _, style := range []string{"Table_20_Contents", "Table_20_Heading"} {
+		display := strings.Replace(style, "_20_", " ", -1)
+		weight := ""
+		if style == "Table_20_Heading" {
+			weight = "<style:text-properties fo:font-weight=\"bold\"/>"
+		}
+		fmt.Fprintf(&out, "<style:style style:name=\"%s\" style:display-name=\"%s\" style:family=\"paragraph\" "+
+			"style:parent-style-name=\"Standard\" style:class=\"extra\">%s</style:style>\n", style, display, weight)
+		for _, align := range []string{"left", "right", "center"} {
+			fmt.Fprintf(&out, "<style:style style:name=\"%s_20_%s\" style:display-name=\"%s %s\" style:family=\"paragraph\" "+
+				"style:parent-style-name=\"%s\" style:class=\"extra\"><style:paragraph-properties fo:text-align=\"%s\"/></style:style>\n",
+				style, odtAlignName(align), display, odtAlignName(align), style, odtTextAlign(align))
+		}
+	}
+	out.WriteString(`<style:style style:name="Emphasis" style:family="text"><style:text-properties fo:font-style="italic"/></style:style>
+<style:style style:name="Strong_20_Emphasis" style:display-name="Strong Emphasis" style:family="text"><style:text-properties fo:font-weight="bold"/></style:style>
+<style:style style:name="Strikethrough" style:family="text"><style:text-properties style:text-line-through-style="solid"/></style:style>
+<style:style style:name="Source_20_Text" style:display-name="Source Text" style:family="text"><style:text-properties style:font-name="Liberation Mono" fo:font-family="'Liberation Mono', 'Courier New', monospace"/></style:style>
+<style:style style:name="Internet_20_link" style:display-name="Internet link" style:family="text"><style:text-properties fo:color="#000080" style:text-underline-style="solid" style:text-underline-width="auto" style:text-underline-color="font-color"/></style:style>
+<style:style style:name="Footnote_20_anchor" style:display-name="Footnote anchor" style:family="text"><style:text-properties style:text-position="super 58%"/></style:style>
+<style:style style:name="Footnote_20_Symbol" style:display-name="Footnote Symbol" style:family="text"/>
+<text:notes-configuration text:note-class="footnote" text:citation-style-name="Footnote_20_Symbol" text:citation-body-style-name="Footnote_20_anchor" style:num-format="1" text:start-value="0" text:footnotes-position="page" text:start-numbering-at="document"/>
+`)
+	out.WriteString("<text:list-style style:name=\"List_20_1\" style:display-name=\"List 1\">\n")
+	for level := 1; level <= 10; level++ {
+		bullet := []string{"•", "◦", "▪"}[(level-1)%3]
+		fmt.Fprintf(&out, "<text:list-level-style-bullet text:level=\"%d\" text:bullet-char=\"%s\">"+
+			"<style:list-level-properties text:list-level-position-and-space-mode=\"label-alignment\">"+
+			"<style:list-level-label-alignment text:label-followed-by=\"listtab\" text:list-tab-stop-position=\"%.2fcm\" "+
+			"fo:text-indent=\"-0.63cm\" fo:margin-left=\"%.2fcm\"/></style:list-level-properties></text:list-level-style-bullet>\n",
+			level, bullet, 0.63*float64(level+1), 0.63*float64(level+1))
+	}
+	out.WriteString("</text:list-style>\n")
+	out.WriteString("<text:list-style style:name=\"Numbering_20_123\" style:display-name=\"Numbering 123\">\n")
+	for level := 1; level <= 10; level++ {
+		fmt.Fprintf(&out, "<text:list-level-style-number text:level=\"%d\" style:num-suffix=\".\" style:num-format=\"1\">"+
+			"<style:list-level-properties text:list-level-position-and-space-mode=\"label-alignment\">"+
+			"<style:list-level-label-alignment text:label-followed-by=\"listtab\" text:list-tab-stop-position=\"%.2fcm\" "+
+			"fo:text-indent=\"-0.63cm\" fo:margin-left=\"%.2fcm\"/></style:list-level-properties></text:list-level-style-number>\n",
+			level, 0.63*float64(level+1), 0.63*float64(level+1))
+	}
+	out.WriteString("</text:list-style>\n")
+	out.WriteString("</office:styles>\n")
+
+	out.WriteString(`<office:automatic-styles>
+<style:page-layout style:name="Page"><style:page-layout-properties fo:page-width="21cm" fo:page-height="29.7cm" fo:margin-top="2cm" fo:margin-bottom="2cm" fo:margin-left="2cm" fo:margin-right="2cm"/></style:page-layout>
+</office:automatic-styles>
+<office:master-styles>
+<style:master-page style:name="Standard" style:page-layout-name="Page"/>
+</office:master-styles>
+</office:document-styles>
+`)
+	return out.String()
+}
+
+// odtAlignName is the alignment as used in style names.
+func odtAlignName(align string) string {
+	return strings.ToUpper(align[:1]) + align[1:]
+}
+
+// odtTextAlign maps the alignments to the values of fo:text-align.
+func odtTextAlign(align string) string {
+	switch align {
+	case "left":
+		return "start"
+	case "right":
+		return "end"
+	}
+	return align
+}
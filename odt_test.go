@@ -0,0 +1,199 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the Odt renderer
+//
+
+package blackfriday
+
+import (
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renderOdt renders input and returns the parts of the resulting package,
+// checking that all the XML parts are well-formed.
+func renderOdt(t *testing.T, input string, extensions int, params OdtRendererParameters) map[string]string {
+	output := MarkdownOptions([]byte(input), OdtRendererWithParameters(0, params), Options{Extensions: extensions})
+	names, files := readEpub(t, output)
+	if len(names) == 0 || names[0] != "mimetype" || files["mimetype"] != "application/vnd.oasis.opendocument.text" {
+		t.Errorf("the first entry must be the mimetype, got %v", names)
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, ".xml") {
+			if err := checkWellFormed(files[name]); err != nil {
+				t.Errorf("%s is not well-formed XML: %v\n%s", name, err, files[name])
+			}
+		}
+	}
+	for _, name := range []string{"META-INF/manifest.xml", "content.xml", "styles.xml"} {
+		if _, found := files[name]; !found {
+			t.Errorf("missing part %s in %v", name, names)
+		}
+	}
+	return files
+}
+
+func doTestsOdt(t *testing.T, tests []string, extensions int) {
+	for i := 0; i+1 < len(tests); i += 2 {
+		files := renderOdt(t, tests[i], extensions, OdtRendererParameters{})
+		content := files["content.xml"]
+		start := strings.Index(content, "<office:text>\n") + len("<office:text>\n")
+		end := strings.Index(content, "</office:text>")
+		if actual := content[start:end]; actual != tests[i+1] {
+			t.Errorf("\nInput   [%#v]\nExpected[%#v]\nActual  [%#v]", tests[i], tests[i+1], actual)
+		}
+	}
+}
+
+func TestOdtBlocks(t *testing.T) {
+	var tests = []string{
+		"# Title {#title}\n\nText.\n",
+		"<text:h text:style-name=\"Heading_20_1\" text:outline-level=\"1\"><text:bookmark text:name=\"title\"/>Title</text:h>\n" +
+			"<text:p text:style-name=\"Text_20_body\">Text.</text:p>\n",
+
+		"```\nif a < b {\n\t  x  y\n}\n```\n",
+		"<text:p text:style-name=\"Preformatted_20_Text\">if a &lt; b {<text:line-break/>" +
+			"<text:tab/><text:s text:c=\"2\"/>x <text:s text:c=\"1\"/>y<text:line-break/>}</text:p>\n",
+
+		"> quoted\n>\n> * item\n",
+		"<text:p text:style-name=\"Quotations\">quoted</text:p>\n" +
+			"<text:list text:style-name=\"List_20_1\">\n<text:list-item><text:p text:style-name=\"List_20_Contents\">item</text:p>\n" +
+			"</text:list-item>\n</text:list>\n",
+
+		"* one\n* two\n    1. nested\n\n---\n",
+		"<text:list text:style-name=\"List_20_1\">\n" +
+			"<text:list-item><text:p text:style-name=\"List_20_Contents\">one</text:p>\n</text:list-item>\n" +
+			"<text:list-item><text:p text:style-name=\"List_20_Contents\">two</text:p>\n" +
+			"<text:list text:style-name=\"Numbering_20_123\">\n" +
+			"<text:list-item><text:p text:style-name=\"List_20_Contents\">nested</text:p>\n</text:list-item>\n" +
+			"</text:list>\n</text:list-item>\n</text:list>\n" +
+			"<text:p text:style-name=\"Horizontal_20_Line\"/>\n",
+
+		"Term\n:   Definition\n\n:   Second\n",
+		"<text:p text:style-name=\"Definition_20_Term\">Term</text:p>\n" +
+			"<text:p text:style-name=\"Definition_20_Definition\">Definition</text:p>\n" +
+			"<text:p text:style-name=\"Definition_20_Definition\">Second</text:p>\n",
+
+		"a | b | c\n:--|--:|:-:\n1 | 2 | 3\n",
+		"<table:table table:name=\"Table1\" table:style-name=\"Table\">\n" +
+			"<table:table-column table:number-columns-repeated=\"3\"/>\n" +
+			"<table:table-header-rows>\n<table:table-row>" +
+			"<table:table-cell table:style-name=\"Table_Cell\" office:value-type=\"string\"><text:p text:style-name=\"Table_20_Heading_20_Left\">a</text:p></table:table-cell>" +
+			"<table:table-cell table:style-name=\"Table_Cell\" office:value-type=\"string\"><text:p text:style-name=\"Table_20_Heading_20_Right\">b</text:p></table:table-cell>" +
+			"<table:table-cell table:style-name=\"Table_Cell\" office:value-type=\"string\"><text:p text:style-name=\"Table_20_Heading_20_Center\">c</text:p></table:table-cell>" +
+			"</table:table-row>\n</table:table-header-rows>\n<table:table-row>" +
+			"<table:table-cell table:style-name=\"Table_Cell\" office:value-type=\"string\"><text:p text:style-name=\"Table_20_Contents_20_Left\">1</text:p></table:table-cell>" +
+			"<table:table-cell table:style-name=\"Table_Cell\" office:value-type=\"string\"><text:p text:style-name=\"Table_20_Contents_20_Right\">2</text:p></table:table-cell>" +
+			"<table:table-cell table:style-name=\"Table_Cell\" office:value-type=\"string\"><text:p text:style-name=\"Table_20_Contents_20_Center\">3</text:p></table:table-cell>" +
+			"</table:table-row>\n</table:table>\n",
+
+		"% The Title\n% Ann\n",
+		"<text:p text:style-name=\"Title\">The Title</text:p>\n<text:p text:style-name=\"Subtitle\">Ann</text:p>\n",
+	}
+	doTestsOdt(t, tests, commonExtensions|EXTENSION_TITLEBLOCK)
+}
+
+func TestOdtInline(t *testing.T) {
+	var tests = []string{
+		"*a* **b** ***c*** ~~d~~ `e  f` &copy;\n",
+		"<text:p text:style-name=\"Text_20_body\"><text:span text:style-name=\"Emphasis\">a</text:span> " +
+			"<text:span text:style-name=\"Strong_20_Emphasis\">b</text:span> " +
+			"<text:span text:style-name=\"Emphasis\"><text:span text:style-name=\"Strong_20_Emphasis\">c</text:span></text:span> " +
+			"<text:span text:style-name=\"Strikethrough\">d</text:span> " +
+			"<text:span text:style-name=\"Source_20_Text\">e <text:s text:c=\"1\"/>f</text:span> ©</text:p>\n",
+
+		"[x](http://a.b/?c&d \"T\") <me@a.b> line  \nbreak\n",
+		"<text:p text:style-name=\"Text_20_body\"><text:a xlink:type=\"simple\" xlink:href=\"http://a.b/?c&amp;d\" " +
+			"office:title=\"T\" text:style-name=\"Internet_20_link\">x</text:a> " +
+			"<text:a xlink:type=\"simple\" xlink:href=\"mailto:me@a.b\" text:style-name=\"Internet_20_link\">me@a.b</text:a> " +
+			"line<text:line-break/>break</text:p>\n",
+	}
+	doTestsOdt(t, tests, commonExtensions)
+}
+
+func TestOdtFootnotes(t *testing.T) {
+	var tests = []string{
+		"Note[^a] again[^a] inline^[*here*].\n\n[^a]: The note.\n\n    Second paragraph.\n",
+		"<text:p text:style-name=\"Text_20_body\">Note" +
+			"<text:note text:id=\"ftn1\" text:note-class=\"footnote\"><text:note-citation>1</text:note-citation><text:note-body>" +
+			"<text:p text:style-name=\"Footnote\">The note.</text:p>\n<text:p text:style-name=\"Footnote\">Second paragraph.</text:p>" +
+			"</text:note-body></text:note> again" +
+			"<text:span text:style-name=\"Footnote_20_anchor\"><text:note-ref text:note-class=\"footnote\" " +
+			"text:reference-format=\"text\" text:ref-name=\"ftn1\">1</text:note-ref></text:span> inline" +
+			"<text:note text:id=\"ftn2\" text:note-class=\"footnote\"><text:note-citation>2</text:note-citation><text:note-body>" +
+			"<text:p text:style-name=\"Footnote\"><text:span text:style-name=\"Emphasis\">here</text:span></text:p>" +
+			"</text:note-body></text:note>.</text:p>\n",
+
+		"* item[^b]\n\n[^b]: Note.\n",
+		"<text:list text:style-name=\"List_20_1\">\n<text:list-item><text:p text:style-name=\"List_20_Contents\">item" +
+			"<text:note text:id=\"ftn1\" text:note-class=\"footnote\"><text:note-citation>1</text:note-citation><text:note-body>" +
+			"<text:p text:style-name=\"Footnote\">Note.</text:p></text:note-body></text:note></text:p>\n" +
+			"</text:list-item>\n</text:list>\n",
+	}
+	doTestsOdt(t, tests, EXTENSION_FOOTNOTES)
+}
+
+func TestOdtImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blackfriday-odt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f, err := os.Create(filepath.Join(dir, "pic.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, 96, 48))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	input := "![a picture](pic.png \"Caption\")\n\nInline ![again](pic.png) ![remote](http://example.com/x.png)\n"
+	files := renderOdt(t, input, 0, OdtRendererParameters{BaseDir: dir})
+	for name, parts := range map[string][]string{
+		"content.xml": {
+			"<text:p text:style-name=\"Figure\"><draw:frame draw:name=\"Image1\" draw:style-name=\"Image\" " +
+				"text:anchor-type=\"as-char\" svg:width=\"2.540cm\" svg:height=\"1.270cm\">" +
+				"<draw:image xlink:href=\"Pictures/image1.png\" xlink:type=\"simple\" xlink:show=\"embed\" xlink:actuate=\"onLoad\"/>" +
+				"<svg:title>Caption</svg:title><svg:desc>a picture</svg:desc></draw:frame></text:p>\n" +
+				"<text:p text:style-name=\"Caption\">Caption</text:p>\n",
+			"<draw:frame draw:name=\"Image2\"",
+			"<text:a xlink:type=\"simple\" xlink:href=\"http://example.com/x.png\" text:style-name=\"Internet_20_link\">remote</text:a>",
+		},
+		"META-INF/manifest.xml": {
+			`<manifest:file-entry manifest:full-path="Pictures/image1.png" manifest:media-type="image/png"/>`,
+		},
+	} {
+		for _, part := range parts {
+			if !strings.Contains(files[name], part) {
+				t.Errorf("%s: missing [%#v] in\n%s", name, part, files[name])
+			}
+		}
+	}
+	if !strings.HasPrefix(files["Pictures/image1.png"], "\x89PNG") {
+		t.Errorf("image was not embedded")
+	}
+	if strings.Contains(files["META-INF/manifest.xml"], "image2") {
+		t.Errorf("image embedded twice")
+	}
+
+	// images outside of BaseDir are not read
+	input = "![up](../pic.png) ![up](sub/../../pic.png)\n"
+	files = renderOdt(t, input, 0, OdtRendererParameters{BaseDir: filepath.Join(dir, "sub")})
+	if _, found := files["Pictures/image1.png"]; found {
+		t.Errorf("image outside of BaseDir was embedded")
+	}
+}
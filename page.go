@@ -0,0 +1,109 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Complete HTML pages from templates
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// HtmlPage is the data that a PageTemplate is executed with, e.g.
+//
+//	<!DOCTYPE html>
+//	<html lang="{{.Lang}}">
+//	<head>
+//	  <meta name="viewport" content="width=device-width, initial-scale=1">
+//	  <title>{{.Title}}</title>
+//	  {{range .Stylesheets}}<link rel="stylesheet" href="{{.}}">{{end}}
+//	</head>
+//	<body>{{.Toc}}<main>{{.Body}}</main></body>
+//	</html>
+type HtmlPage struct {
+	// The title given to HtmlRenderer, or else the one from the title block.
+	Title string
+	// Lang and Dir from HtmlRendererParameters.
+	Lang string
+	Dir  string
+	// The css given to HtmlRenderer followed by Stylesheets, and Scripts.
+	Stylesheets []string
+	Scripts     []string
+	// The title block of the document.
+	Metadata Metadata
+	// The table of contents with HTML_TOC, as HTML and as a tree. Body
+	// only holds it too if a [TOC] marker placed it there.
+	Toc     template.HTML
+	Outline []*TocEntry
+	// The rendered document.
+	Body template.HTML
+	// The name and version of this package.
+	Generator string
+}
+
+// Err returns the error from executing the PageTemplate for the last
+// document, which is rendered without it in that case.
+func (options *Html) Err() error {
+	return options.err
+}
+
+func (options *Html) pageTitle() string {
+	if options.title != "" {
+		return options.title
+	}
+	return options.meta.Title
+}
+
+func (options *Html) stylesheets() []string {
+	var urls []string
+	if options.css != "" {
+		urls = append(urls, options.css)
+	}
+	return append(urls, options.parameters.Stylesheets...)
+}
+
+// tocInPage tells whether the table of contents is left to the page template
+// rather than written into the document.
+func (options *Html) tocInPage() bool {
+	return options.flags&HTML_COMPLETE_PAGE != 0 && options.parameters.PageTemplate != nil &&
+		options.tocPlacement < 0 && options.flags&HTML_OMIT_CONTENTS == 0
+}
+
+// writePage replaces the document in out with the page made from it by the
+// page template.
+func (options *Html) writePage(out *bytes.Buffer) {
+	page := HtmlPage{
+		Title:       options.pageTitle(),
+		Lang:        options.parameters.Lang,
+		Dir:         options.parameters.Dir,
+		Stylesheets: options.stylesheets(),
+		Scripts:     options.parameters.Scripts,
+		Metadata:    options.meta,
+		Outline:     options.Toc(),
+		Body:        template.HTML(out.Bytes()[options.tocMarker:]),
+		Generator:   "Blackfriday Markdown Processor v" + VERSION,
+	}
+	if options.flags&HTML_TOC != 0 {
+		var toc bytes.Buffer
+		options.tocWrapper(&toc)
+		page.Toc = template.HTML(toc.String())
+	}
+
+	out.Truncate(options.tocMarker)
+	if err := options.parameters.PageTemplate.Execute(out, page); err != nil {
+		options.err = err
+		out.Truncate(options.tocMarker)
+		out.WriteString(string(page.Body))
+	}
+}
@@ -0,0 +1,85 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for complete HTML pages
+//
+
+package blackfriday
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestPageTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("page").Parse(`<html lang="{{.Lang}}"><title>{{.Title}}</title>` +
+		`{{range .Stylesheets}}<link href="{{.}}">{{end}}{{range .Metadata.Authors}}<meta name="author" content="{{.}}">{{end}}` +
+		`<aside>{{.Toc}}</aside>{{range .Outline}}[{{.PlainText}}]{{end}}<main>{{.Body}}</main></html>`))
+	renderer := HtmlRendererWithParameters(HTML_COMPLETE_PAGE|HTML_TOC, "", "main.css", HtmlRendererParameters{
+		Lang:         "en",
+		Stylesheets:  []string{"brand.css"},
+		PageTemplate: tmpl,
+	})
+	input := "% Guide & more\n% Ann\n\n# Intro\n\nText\n"
+	got := string(Markdown([]byte(input), renderer, EXTENSION_TITLEBLOCK))
+	want := `<html lang="en"><title>Guide &amp; more</title><link href="main.css"><link href="brand.css">` +
+		`<meta name="author" content="Ann"><aside><nav>` + "\n<ul>\n" + `<li><a href="#toc_0">Intro</a></li>` + "\n</ul>\n</nav>\n</aside>" +
+		`[Intro]<main><h1 class="title">Guide & more` + "\nAnn\n</h1>\n" + `<h1 id="toc_0">Intro</h1>` + "\n\n<p>Text</p>\n</main></html>"
+	if got != want {
+		t.Errorf("\nExpected[%#v]\nActual  [%#v]", want, got)
+	}
+	if err := renderer.(*Html).Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// a marker keeps the table of contents in the body
+	got = string(Markdown([]byte("Intro\n\n[TOC]\n\n# One\n"), renderer, EXTENSION_TOC_MARKER))
+	if !strings.Contains(got, "<main><p>Intro</p>\n\n<nav>") {
+		t.Errorf("table of contents not at marker:\n%s", got)
+	}
+}
+
+func TestPageTemplateError(t *testing.T) {
+	tmpl := template.Must(template.New("page").Parse(`<body>{{.Missing}}</body>`))
+	renderer := HtmlRendererWithParameters(HTML_COMPLETE_PAGE, "", "", HtmlRendererParameters{PageTemplate: tmpl})
+	got := string(Markdown([]byte("Text\n"), renderer, 0))
+	if got != "<p>Text</p>\n" {
+		t.Errorf("wrong fallback output: %q", got)
+	}
+	if renderer.(*Html).Err() == nil {
+		t.Errorf("expected an error from the template")
+	}
+}
+
+func TestPageParameters(t *testing.T) {
+	renderer := HtmlRendererWithParameters(HTML_COMPLETE_PAGE, "Page", "main.css", HtmlRendererParameters{
+		Lang:        "ar",
+		Dir:         "rtl",
+		Stylesheets: []string{"brand.css"},
+		Scripts:     []string{"app.js"},
+	})
+	doc := string(Markdown([]byte("Text\n"), renderer, 0))
+	for _, want := range []string{
+		"<!DOCTYPE html>\n<html lang=\"ar\" dir=\"rtl\">\n<head>\n",
+		"  <link rel=\"stylesheet\" type=\"text/css\" href=\"main.css\">\n  <link rel=\"stylesheet\" type=\"text/css\" href=\"brand.css\">\n",
+		"<p>Text</p>\n\n<script src=\"app.js\"></script>\n</body>\n</html>\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("page does not contain %q:\n%s", want, doc)
+		}
+	}
+
+	renderer = HtmlRendererWithParameters(HTML_COMPLETE_PAGE|HTML_USE_XHTML, "Page", "", HtmlRendererParameters{Lang: "en"})
+	doc = string(Markdown([]byte("Text\n"), renderer, 0))
+	if !strings.Contains(doc, "<html xmlns=\"http://www.w3.org/1999/xhtml\" xml:lang=\"en\" lang=\"en\">\n") {
+		t.Errorf("wrong html element:\n%s", doc)
+	}
+}
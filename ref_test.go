@@ -19,12 +19,15 @@ import (
 	"testing"
 )
 
-func runMarkdownReference(input string, flag int) string {
-	renderer := HtmlRenderer(0, "", "")
-	return string(Markdown([]byte(input), renderer, flag))
+func doTestsReference(t *testing.T, files []string, flag int) {
+	doTestsReferenceWithRenderer(t, files, ".html", flag, func() Renderer {
+		return HtmlRenderer(0, "", "")
+	})
 }
 
-func doTestsReference(t *testing.T, files []string, flag int) {
+// doTestsReferenceWithRenderer compares the rendering of each testdata file
+// with the file of the same name and the given extension (e.g. ".html").
+func doTestsReferenceWithRenderer(t *testing.T, files []string, ext string, flag int, newRenderer func() Renderer) {
 	// catch and report panics
 	var candidate string
 	defer func() {
@@ -42,7 +45,7 @@ func doTestsReference(t *testing.T, files []string, flag int) {
 		}
 		input := string(inputBytes)
 
-		filename = filepath.Join("testdata", basename+".html")
+		filename = filepath.Join("testdata", basename+ext)
 		expectedBytes, err := ioutil.ReadFile(filename)
 		if err != nil {
 			t.Errorf("Couldn't open '%s', error: %v\n", filename, err)
@@ -51,7 +54,7 @@ func doTestsReference(t *testing.T, files []string, flag int) {
 		expected := string(expectedBytes)
 
 		// fmt.Fprintf(os.Stderr, "processing %s ...", filename)
-		actual := string(runMarkdownReference(input, flag))
+		actual := string(Markdown([]byte(input), newRenderer(), flag))
 		if actual != expected {
 			t.Errorf("\n    [%#v]\nExpected[%#v]\nActual  [%#v]",
 				basename+".text", expected, actual)
@@ -65,7 +68,7 @@ func doTestsReference(t *testing.T, files []string, flag int) {
 			for end := start + 1; end <= max; end++ {
 				candidate = input[start:end]
 				// fmt.Fprintf(os.Stderr, "  %s %d:%d/%d\n", filename, start, end, max)
-				_ = runMarkdownReference(candidate, flag)
+				_ = Markdown([]byte(candidate), newRenderer(), flag)
 			}
 		}
 	}
@@ -0,0 +1,604 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// reStructuredText rendering backend
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Underline characters for each header level, in the order Sphinx suggests.
+const rstHeaderChars = "=-~^\"'"
+
+// Role definitions that are only written out if the document needs them.
+const (
+	rstRoleDel     = ".. role:: del\n"
+	rstRoleRawHtml = ".. role:: raw-html(raw)\n   :format: html\n"
+)
+
+// Rst is a type that implements the Renderer interface for reStructuredText
+// output, as consumed by docutils and Sphinx.
+//
+// Do not create this directly, instead use the RstRenderer function.
+type Rst struct {
+	flags int
+
+	// position right after the document header, where role definitions go
+	headerMarker int
+	roles        []string
+
+	// images are written as substitutions, defined at the end of the document
+	images []rstImage
+
+	// where the last inline markup ended, as text directly following it
+	// must be separated by an escaped space
+	markupBuf *bytes.Buffer
+	markupEnd int
+
+	// table cells are collected here until the whole table can be laid out
+	tableCells  []string
+	tableIsHead bool
+	tableHeader [][]string
+	tableBody   [][]string
+}
+
+type rstImage struct {
+	name, link, title, alt string
+	block                  bool // rendered as a figure, no substitution needed
+}
+
+// RstRenderer creates and configures a Rst object, which
+// satisfies the Renderer interface.
+//
+// flags is a set of RST_* options ORed together (currently no such options
+// are defined).
+func RstRenderer(flags int) Renderer {
+	return &Rst{flags: flags}
+}
+
+func (options *Rst) GetFlags() int {
+	return options.flags
+}
+
+func (options *Rst) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	blankLine(out)
+	if lang := codeLanguage(info); lang != "" {
+		out.WriteString(".. code-block:: ")
+		out.WriteString(lang)
+		out.WriteString("\n\n")
+	} else {
+		out.WriteString("::\n\n")
+	}
+	writeIndented(out, text, "   ", "   ")
+}
+
+func (options *Rst) TitleBlock(out *bytes.Buffer, text []byte) {
+	title, authors, date := titleBlockFields(text)
+	blankLine(out)
+	if title != "" {
+		var work bytes.Buffer
+		options.NormalText(&work, []byte(title))
+		rule := strings.Repeat("=", utf8.RuneCount(work.Bytes()))
+		out.WriteString(rule)
+		out.WriteByte('\n')
+		out.Write(work.Bytes())
+		out.WriteByte('\n')
+		out.WriteString(rule)
+		out.WriteByte('\n')
+	}
+	if len(authors) > 0 || date != "" {
+		blankLine(out)
+	}
+	if len(authors) == 1 {
+		out.WriteString(":Author: ")
+		options.NormalText(out, []byte(authors[0]))
+		out.WriteByte('\n')
+	} else if len(authors) > 1 {
+		out.WriteString(":Authors: ")
+		options.NormalText(out, []byte(strings.Join(authors, "; ")))
+		out.WriteByte('\n')
+	}
+	if date != "" {
+		out.WriteString(":Date: ")
+		options.NormalText(out, []byte(date))
+		out.WriteByte('\n')
+	}
+}
+
+func (options *Rst) BlockQuote(out *bytes.Buffer, text []byte) {
+	blankLine(out)
+	if lastLineIndented(out.Bytes()) {
+		// an empty comment keeps the quote from continuing the previous block
+		out.WriteString("..\n\n")
+	}
+	writeIndented(out, text, "    ", "    ")
+}
+
+func (options *Rst) BlockHtml(out *bytes.Buffer, text []byte) {
+	blankLine(out)
+	out.WriteString(".. raw:: html\n\n")
+	writeIndented(out, text, "   ", "   ")
+}
+
+func (options *Rst) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	marker := out.Len()
+	blankLine(out)
+
+	if id != "" {
+		out.WriteString(".. _")
+		out.WriteString(id)
+		out.WriteString(":\n\n")
+	}
+
+	start := out.Len()
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	width := utf8.RuneCount(out.Bytes()[start:])
+	if width == 0 {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteByte('\n')
+	out.WriteString(strings.Repeat(rstHeaderChars[level-1:level], width))
+	out.WriteByte('\n')
+}
+
+func (options *Rst) HRule(out *bytes.Buffer) {
+	blankLine(out)
+	out.WriteString("--------\n")
+}
+
+func (options *Rst) List(out *bytes.Buffer, text func() bool, flags int) {
+	marker := out.Len()
+	blankLine(out)
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+}
+
+func (options *Rst) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	switch {
+	case flags&LIST_TYPE_TERM != 0:
+		if flags&LIST_ITEM_BEGINNING_OF_LIST == 0 {
+			blankLine(out)
+		}
+		out.Write(bytes.Replace(text, []byte("\n"), []byte(" "), -1))
+		out.WriteByte('\n')
+
+	case flags&LIST_TYPE_DEFINITION != 0:
+		// a second definition for the same term continues the first one
+		if lastLineIndented(out.Bytes()) {
+			blankLine(out)
+		}
+		writeIndented(out, text, "    ", "    ")
+
+	case flags&LIST_TYPE_ORDERED != 0:
+		rstItemSpacing(out, text, flags)
+		writeIndented(out, text, "#. ", "   ")
+
+	default:
+		rstItemSpacing(out, text, flags)
+		writeIndented(out, text, "* ", "  ")
+	}
+}
+
+// Items spanning several lines have to be separated from their neighbours
+// by blank lines.
+func rstItemSpacing(out *bytes.Buffer, text []byte, flags int) {
+	if flags&LIST_ITEM_BEGINNING_OF_LIST != 0 {
+		return
+	}
+	if flags&LIST_ITEM_CONTAINS_BLOCK != 0 || bytes.IndexByte(text, '\n') >= 0 ||
+		lastLineIndented(out.Bytes()) {
+		blankLine(out)
+	}
+}
+
+func (options *Rst) Paragraph(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	blankLine(out)
+
+	start := out.Len()
+	imageCount := len(options.images)
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+
+	// a paragraph holding nothing but an image becomes a figure
+	if len(options.images) == imageCount+1 {
+		img := &options.images[imageCount]
+		if string(out.Bytes()[start:]) == "|"+img.name+"|" {
+			out.Truncate(start)
+			options.figure(out, img)
+			return
+		}
+	}
+	out.WriteByte('\n')
+}
+
+func (options *Rst) figure(out *bytes.Buffer, img *rstImage) {
+	img.block = true
+	if img.title == "" {
+		out.WriteString(".. image:: ")
+	} else {
+		out.WriteString(".. figure:: ")
+	}
+	out.WriteString(img.link)
+	out.WriteByte('\n')
+	if img.alt != "" {
+		out.WriteString("   :alt: ")
+		out.WriteString(img.alt)
+		out.WriteByte('\n')
+	}
+	if img.title != "" {
+		out.WriteString("\n   ")
+		options.NormalText(out, []byte(img.title))
+		out.WriteByte('\n')
+	}
+}
+
+func (options *Rst) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	widths := make([]int, len(columnData))
+	for _, row := range append(options.tableHeader, options.tableBody...) {
+		for i, cell := range row {
+			if w := utf8.RuneCountInString(cell); i < len(widths) && w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	blankLine(out)
+	rstTableRule(out, widths, '-')
+	for _, row := range options.tableHeader {
+		rstTableRow(out, row, widths, columnData)
+	}
+	if len(options.tableHeader) > 0 {
+		rstTableRule(out, widths, '=')
+	}
+	for _, row := range options.tableBody {
+		rstTableRow(out, row, widths, columnData)
+		rstTableRule(out, widths, '-')
+	}
+	if len(options.tableBody) == 0 && len(options.tableHeader) > 0 {
+		// docutils wants at least one body row
+		rstTableRow(out, nil, widths, columnData)
+		rstTableRule(out, widths, '-')
+	}
+
+	options.tableHeader = nil
+	options.tableBody = nil
+}
+
+func rstTableRule(out *bytes.Buffer, widths []int, c byte) {
+	out.WriteByte('+')
+	for _, w := range widths {
+		out.WriteString(strings.Repeat(string(c), w+2))
+		out.WriteByte('+')
+	}
+	out.WriteByte('\n')
+}
+
+func rstTableRow(out *bytes.Buffer, row []string, widths []int, columnData []int) {
+	out.WriteByte('|')
+	for i, w := range widths {
+		cell := ""
+		if i < len(row) {
+			cell = row[i]
+		}
+		out.WriteByte(' ')
+		padCell(out, cell, w, columnData[i])
+		out.WriteString(" |")
+	}
+	out.WriteByte('\n')
+}
+
+// Write text padded with spaces to width runes, honouring the column
+// alignment.
+func padCell(out *bytes.Buffer, text string, width int, align int) {
+	pad := width - utf8.RuneCountInString(text)
+	if pad < 0 {
+		pad = 0
+	}
+	left := 0
+	switch align {
+	case TABLE_ALIGNMENT_RIGHT:
+		left = pad
+	case TABLE_ALIGNMENT_CENTER:
+		left = pad / 2
+	}
+	out.WriteString(strings.Repeat(" ", left))
+	out.WriteString(text)
+	out.WriteString(strings.Repeat(" ", pad-left))
+}
+
+func (options *Rst) TableRow(out *bytes.Buffer, text []byte) {
+	if options.tableIsHead {
+		options.tableHeader = append(options.tableHeader, options.tableCells)
+	} else {
+		options.tableBody = append(options.tableBody, options.tableCells)
+	}
+	options.tableCells = nil
+	options.tableIsHead = false
+}
+
+func (options *Rst) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
+	options.tableIsHead = true
+	options.tableCells = append(options.tableCells, tableCellText(text))
+}
+
+func (options *Rst) TableCell(out *bytes.Buffer, text []byte, align int) {
+	options.tableCells = append(options.tableCells, tableCellText(text))
+}
+
+// Table cells in line-oriented formats have to fit on a single line.
+func tableCellText(text []byte) string {
+	return strings.TrimSpace(strings.Replace(string(text), "\n", " ", -1))
+}
+
+func (options *Rst) Footnotes(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	blankLine(out)
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+}
+
+func (options *Rst) FootnoteItem(out *bytes.Buffer, name, text []byte, flags int) {
+	blankLine(out)
+	out.WriteString(".. [#")
+	out.Write(slugify(name))
+	out.WriteString("] ")
+	writeIndented(out, text, "", "   ")
+}
+
+func (options *Rst) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	rstInlineStart(out)
+	out.WriteByte('`')
+	if kind == LINK_TYPE_EMAIL {
+		rstEscape(out, link)
+		out.WriteString(" <mailto:")
+	} else {
+		out.WriteByte('<')
+	}
+	rstEscape(out, link)
+	out.WriteString(">`__")
+	options.endMarkup(out)
+}
+
+func (options *Rst) CodeSpan(out *bytes.Buffer, text []byte) {
+	rstInlineStart(out)
+	out.WriteString("``")
+	out.Write(text)
+	out.WriteString("``")
+	options.endMarkup(out)
+}
+
+func (options *Rst) DoubleEmphasis(out *bytes.Buffer, text []byte) {
+	rstInlineStart(out)
+	out.WriteString("**")
+	out.Write(text)
+	out.WriteString("**")
+	options.endMarkup(out)
+}
+
+func (options *Rst) Emphasis(out *bytes.Buffer, text []byte) {
+	if len(text) == 0 {
+		return
+	}
+	rstInlineStart(out)
+	out.WriteString("*")
+	out.Write(text)
+	out.WriteString("*")
+	options.endMarkup(out)
+}
+
+func (options *Rst) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	img := rstImage{
+		name:  "image" + strconv.Itoa(len(options.images)+1),
+		link:  string(link),
+		title: string(title),
+		alt:   string(alt),
+	}
+	options.images = append(options.images, img)
+	rstInlineStart(out)
+	out.WriteByte('|')
+	out.WriteString(img.name)
+	out.WriteByte('|')
+	options.endMarkup(out)
+}
+
+func (options *Rst) LineBreak(out *bytes.Buffer) {
+	// reStructuredText has no hard line breaks outside of line blocks
+	out.WriteByte('\n')
+}
+
+func (options *Rst) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	rstInlineStart(out)
+	out.WriteByte('`')
+	out.Write(content)
+	out.WriteString(" <")
+	rstEscape(out, link)
+	out.WriteString(">`__")
+	options.endMarkup(out)
+}
+
+func (options *Rst) RawHtmlTag(out *bytes.Buffer, tag []byte) {
+	options.useRole(rstRoleRawHtml)
+	rstInlineStart(out)
+	out.WriteString(":raw-html:`")
+	rstEscape(out, tag)
+	out.WriteByte('`')
+	options.endMarkup(out)
+}
+
+func (options *Rst) TripleEmphasis(out *bytes.Buffer, text []byte) {
+	// inline markup cannot be nested
+	options.DoubleEmphasis(out, text)
+}
+
+func (options *Rst) StrikeThrough(out *bytes.Buffer, text []byte) {
+	options.useRole(rstRoleDel)
+	rstInlineStart(out)
+	out.WriteString(":del:`")
+	out.Write(text)
+	out.WriteByte('`')
+	options.endMarkup(out)
+}
+
+func (options *Rst) FootnoteRef(out *bytes.Buffer, ref []byte, id int) {
+	rstInlineStart(out)
+	out.WriteString("[#")
+	out.Write(slugify(ref))
+	out.WriteString("]_")
+	options.endMarkup(out)
+}
+
+func (options *Rst) Entity(out *bytes.Buffer, entity []byte) {
+	options.NormalText(out, []byte(html.UnescapeString(string(entity))))
+}
+
+func (options *Rst) NormalText(out *bytes.Buffer, text []byte) {
+	if len(text) > 0 && out == options.markupBuf && out.Len() == options.markupEnd &&
+		!isspace(text[0]) && bytes.IndexByte([]byte("-.,:;!?\\/'\")]}>"), text[0]) < 0 {
+		out.WriteString("\\ ")
+	}
+	rstEscape(out, text)
+}
+
+func (options *Rst) endMarkup(out *bytes.Buffer) {
+	options.markupBuf = out
+	options.markupEnd = out.Len()
+}
+
+func (options *Rst) useRole(role string) {
+	for _, r := range options.roles {
+		if r == role {
+			return
+		}
+	}
+	options.roles = append(options.roles, role)
+}
+
+func (options *Rst) DocumentHeader(out *bytes.Buffer) {
+	options.headerMarker = out.Len()
+	options.roles = nil
+	options.images = nil
+}
+
+func (options *Rst) DocumentFooter(out *bytes.Buffer) {
+	for _, img := range options.images {
+		if img.block {
+			continue
+		}
+		blankLine(out)
+		out.WriteString(".. |")
+		out.WriteString(img.name)
+		out.WriteString("| image:: ")
+		out.WriteString(img.link)
+		out.WriteByte('\n')
+		if img.alt != "" {
+			out.WriteString("   :alt: ")
+			out.WriteString(img.alt)
+			out.WriteByte('\n')
+		}
+	}
+
+	// roles must be defined before they are used, so insert them at the top
+	if len(options.roles) > 0 {
+		var temp bytes.Buffer
+		temp.Write(out.Bytes()[options.headerMarker:])
+		out.Truncate(options.headerMarker)
+		for _, role := range options.roles {
+			out.WriteString(role)
+			out.WriteByte('\n')
+		}
+		out.Write(temp.Bytes())
+	}
+}
+
+// Inline markup has to start after whitespace or punctuation; an escaped
+// space separates it from a preceding word without showing up in the output.
+func rstInlineStart(out *bytes.Buffer) {
+	b := out.Bytes()
+	if len(b) == 0 {
+		return
+	}
+	switch c := b[len(b)-1]; {
+	case isspace(c), bytes.IndexByte([]byte("-:/'\"<([{"), c) >= 0:
+	default:
+		out.WriteString("\\ ")
+	}
+}
+
+func rstEscape(out *bytes.Buffer, text []byte) {
+	for i := 0; i < len(text); i++ {
+		org := i
+		for i < len(text) && bytes.IndexByte([]byte("\\*`_|"), text[i]) < 0 {
+			i++
+		}
+		if i > org {
+			out.Write(text[org:i])
+		}
+		if i >= len(text) {
+			break
+		}
+		out.WriteByte('\\')
+		out.WriteByte(text[i])
+	}
+}
+
+// blankLine makes sure the output ends with an empty line, so that the next
+// block in a line-oriented format is separated from whatever came before it.
+func blankLine(out *bytes.Buffer) {
+	b := out.Bytes()
+	switch {
+	case len(b) == 0:
+	case b[len(b)-1] != '\n':
+		out.WriteString("\n\n")
+	case len(b) == 1 || b[len(b)-2] != '\n':
+		out.WriteByte('\n')
+	}
+}
+
+// writeIndented writes text to out, prefixing the first line with first and
+// every following non-empty line with rest. The output ends with a newline.
+func writeIndented(out *bytes.Buffer, text []byte, first, rest string) {
+	text = bytes.TrimRight(text, "\n")
+	for i, line := range bytes.Split(text, []byte("\n")) {
+		if i == 0 {
+			out.WriteString(first)
+		} else if len(line) > 0 {
+			out.WriteString(rest)
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+}
+
+// Check whether the last line written to out starts with whitespace.
+func lastLineIndented(b []byte) bool {
+	b = bytes.TrimRight(b, "\n")
+	start := bytes.LastIndexByte(b, '\n') + 1
+	return start < len(b) && ishorizontalspace(b[start])
+}
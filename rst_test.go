@@ -0,0 +1,138 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the reStructuredText renderer
+//
+
+package blackfriday
+
+import (
+	"testing"
+)
+
+func doTestsRst(t *testing.T, tests []string, extensions int) {
+	doTestsWithRenderer(t, tests, extensions, func() Renderer {
+		return RstRenderer(0)
+	})
+}
+
+func TestRstBlocks(t *testing.T) {
+	var tests = []string{
+		"# Header 1\n\nSome text.\n",
+		"Header 1\n========\n\nSome text.\n",
+
+		"# Header {#custom}\n\n## Sub\n\n###### Six\n",
+		".. _custom:\n\nHeader\n======\n\nSub\n---\n\nSix\n'''\n",
+
+		"> quoted\n> text\n",
+		"    quoted\n    text\n",
+
+		"```go\nfunc main() {}\n```\n",
+		".. code-block:: go\n\n   func main() {}\n",
+
+		"    indented\n\n    code\n",
+		"::\n\n   indented\n\n   code\n",
+
+		"<div>\n<b>raw</b>\n</div>\n",
+		".. raw:: html\n\n   <div>\n   <b>raw</b>\n   </div>\n",
+
+		"one\n\n---\n\ntwo\n",
+		"one\n\n--------\n\ntwo\n",
+	}
+	doTestsRst(t, tests, EXTENSION_FENCED_CODE|EXTENSION_HEADER_IDS)
+}
+
+func TestRstLists(t *testing.T) {
+	var tests = []string{
+		"* one\n* two\n* three\n",
+		"* one\n* two\n* three\n",
+
+		"1. one\n2. two\n",
+		"#. one\n#. two\n",
+
+		"* one\n* two\n    * nested\n* three\n",
+		"* one\n\n* two\n\n  * nested\n\n* three\n",
+
+		"1. first\n\n    more\n\n2. second\n",
+		"#. first\n\n   more\n\n#. second\n",
+
+		"Term\n:   Definition one\n\n:   Definition two\n\nOther\n:   Third\n",
+		"Term\n    Definition one\n\n    Definition two\n\nOther\n    Third\n",
+
+		"Term\n:   Definition\n\n> quote\n",
+		"Term\n    Definition\n\n..\n\n    quote\n",
+	}
+	doTestsRst(t, tests, EXTENSION_DEFINITION_LISTS)
+}
+
+func TestRstTable(t *testing.T) {
+	var tests = []string{
+		"Name | Age | Town\n:----|----:|:---:\nBob | 31 | Rome\nAlice | 7 | X\n",
+		"+-------+-----+------+\n" +
+			"| Name  | Age | Town |\n" +
+			"+=======+=====+======+\n" +
+			"| Bob   |  31 | Rome |\n" +
+			"+-------+-----+------+\n" +
+			"| Alice |   7 |  X   |\n" +
+			"+-------+-----+------+\n",
+
+		"a|b\n---|---\n",
+		"+---+---+\n| a | b |\n+===+===+\n|   |   |\n+---+---+\n",
+	}
+	doTestsRst(t, tests, EXTENSION_TABLES)
+}
+
+func TestRstInline(t *testing.T) {
+	var tests = []string{
+		"*em* **strong** ***both*** `code` ~~del~~\n",
+		".. role:: del\n\n*em* **strong** **both** ``code`` :del:`del`\n",
+
+		"snake_case and a*b and back\\\\slash\n",
+		"snake\\_case and a\\*b and back\\\\slash\n",
+
+		"a [link](http://example.com/a_b) here\n",
+		"a `link <http://example.com/a\\_b>`__ here\n",
+
+		"<http://example.com> and <me@example.com>\n",
+		"`<http://example.com>`__ and `me@example.com <mailto:me@example.com>`__\n",
+
+		"x<b>bold</b>\n",
+		".. role:: raw-html(raw)\n   :format: html\n\nx\\ :raw-html:`<b>`\\ bold\\ :raw-html:`</b>`\n",
+
+		"AT&amp;T &copy;\n",
+		"AT&T ©\n",
+	}
+	doTestsRst(t, tests, EXTENSION_STRIKETHROUGH)
+}
+
+func TestRstImages(t *testing.T) {
+	var tests = []string{
+		"![alt](pic.png \"The title\")\n",
+		".. figure:: pic.png\n   :alt: alt\n\n   The title\n",
+
+		"![alt](pic.png)\n",
+		".. image:: pic.png\n   :alt: alt\n",
+
+		"An ![icon](i.png) inline.\n",
+		"An |image1| inline.\n\n.. |image1| image:: i.png\n   :alt: icon\n",
+	}
+	doTestsRst(t, tests, 0)
+}
+
+func TestRstFootnotesAndTitle(t *testing.T) {
+	var tests = []string{
+		"Text[^note] here.\n\n[^note]: The note.\n",
+		"Text\\ [#note]_ here.\n\n.. [#note] The note.\n",
+
+		"% The Title\n% Ann; Bob\n% 2020-01-02\n\nText.\n",
+		"=========\nThe Title\n=========\n\n:Authors: Ann; Bob\n:Date: 2020-01-02\n\nText.\n",
+	}
+	doTestsRst(t, tests, EXTENSION_FOOTNOTES|EXTENSION_TITLEBLOCK)
+}
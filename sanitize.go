@@ -0,0 +1,309 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Allowlist HTML sanitizer
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"html"
+	"strings"
+)
+
+// HtmlPolicy is an allowlist of the raw HTML that the Html renderer passes
+// through when it is set in HtmlRendererParameters. Elements that are not
+// allowed are dropped, but their contents are kept, except for elements
+// like <script> whose contents are not text. Attributes that are not
+// allowed are dropped, and so are event handlers and URLs with other
+// schemes, whatever the policy says.
+type HtmlPolicy struct {
+	// Allowed elements, by lower case name, with the attributes allowed
+	// on each of them.
+	Elements map[string][]string
+	// Attributes allowed on every allowed element.
+	GlobalAttributes []string
+	// Schemes allowed in URL attributes such as href and src, e.g.
+	// "https". Relative URLs are always allowed.
+	URLSchemes []string
+}
+
+// DefaultHtmlPolicy returns a policy that allows common formatting elements,
+// including <details> and <kbd>, with links and images restricted to http,
+// https and mailto URLs.
+func DefaultHtmlPolicy() *HtmlPolicy {
+	policy := &HtmlPolicy{
+		Elements: map[string][]string{
+			"a":          {"href", "name"},
+			"blockquote": {"cite"},
+			"del":        {"cite", "datetime"},
+			"details":    {"open"},
+			"img":        {"src", "alt", "width", "height", "align"},
+			"ins":        {"cite", "datetime"},
+			"li":         {"value"},
+			"ol":         {"start", "type", "reversed"},
+			"q":          {"cite"},
+			"td":         {"colspan", "rowspan", "align"},
+			"th":         {"colspan", "rowspan", "align", "scope"},
+		},
+		GlobalAttributes: []string{"title", "lang", "dir"},
+		URLSchemes:       []string{"http", "https", "mailto"},
+	}
+	for _, name := range strings.Fields(`abbr b bdi bdo br caption cite code
+		dd dfn div dl dt em figcaption figure h1 h2 h3 h4 h5 h6 hr i kbd mark
+		p pre rp rt ruby s samp small span strike strong sub summary sup table
+		tbody tfoot thead tr tt u ul var wbr`) {
+		policy.Elements[name] = nil
+	}
+	return policy
+}
+
+// Sanitize returns the HTML fragment with everything that the policy does
+// not allow removed, and with any elements left open closed at its end.
+func (policy *HtmlPolicy) Sanitize(fragment []byte) []byte {
+	return policy.sanitize(fragment, nil)
+}
+
+// sanitize is Sanitize with URLs checked by links instead of URLSchemes, if
+// it is not nil.
+func (policy *HtmlPolicy) sanitize(fragment []byte, links *LinkPolicy) []byte {
+	var out bytes.Buffer
+	s := htmlSanitizer{policy: policy, links: links}
+	s.write(&out, fragment)
+	s.close(&out)
+	return out.Bytes()
+}
+
+// Elements that never have an end tag.
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// Elements whose contents are dropped along with them, as they are not
+// text meant for the reader.
+var htmlDroppedContents = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"noscript": true, "template": true, "textarea": true, "title": true,
+	"xmp": true, "noembed": true, "noframes": true, "svg": true, "math": true,
+}
+
+// Attributes that hold URLs.
+var htmlURLAttributes = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true,
+	"formaction": true, "poster": true, "background": true, "longdesc": true,
+}
+
+// htmlSanitizer filters HTML that may come in pieces, such as the inline
+// tags of a paragraph, and keeps track of the elements left open.
+type htmlSanitizer struct {
+	policy  *HtmlPolicy
+	links   *LinkPolicy // overrides policy.URLSchemes if set
+	open    []string    // allowed elements that are open, innermost last
+	dropped string      // element whose contents are being dropped
+}
+
+func (s *htmlSanitizer) write(out *bytes.Buffer, text []byte) {
+	for i := 0; i < len(text); {
+		if s.dropped != "" {
+			// skip to the end tag of the dropped element
+			end := bytes.Index(bytes.ToLower(text[i:]), []byte("</"+s.dropped))
+			if end < 0 {
+				return
+			}
+			i += end
+			s.dropped = ""
+			continue
+		}
+
+		lt := bytes.IndexByte(text[i:], '<')
+		if lt < 0 {
+			out.Write(text[i:])
+			return
+		}
+		out.Write(text[i : i+lt])
+		i += lt
+
+		switch {
+		case bytes.HasPrefix(text[i:], []byte("<!--")):
+			end := bytes.Index(text[i+4:], []byte("-->"))
+			if end < 0 {
+				return
+			}
+			i += 4 + end + 3
+		case bytes.HasPrefix(text[i:], []byte("<!")), bytes.HasPrefix(text[i:], []byte("<?")):
+			end := bytes.IndexByte(text[i:], '>')
+			if end < 0 {
+				return
+			}
+			i += end + 1
+		default:
+			n := s.tag(out, text[i:])
+			if n == 0 {
+				// not a tag
+				out.WriteString("&lt;")
+				n = 1
+			}
+			i += n
+		}
+	}
+}
+
+// tag filters the tag at the start of data and returns its length, or 0
+// if there is no tag there.
+func (s *htmlSanitizer) tag(out *bytes.Buffer, data []byte) int {
+	i := 1
+	closing := i < len(data) && data[i] == '/'
+	if closing {
+		i++
+	}
+	start := i
+	for i < len(data) && (isalnum(data[i]) || data[i] == '-') {
+		i++
+	}
+	if i == start || !isletter(data[start]) {
+		return 0
+	}
+	name := strings.ToLower(string(data[start:i]))
+
+	// read the attributes
+	type attribute struct{ name, value string }
+	var attrs []attribute
+	for {
+		for i < len(data) && (isspace(data[i]) || data[i] == '/') {
+			i++
+		}
+		if i >= len(data) {
+			return 0
+		}
+		if data[i] == '>' {
+			i++
+			break
+		}
+		nameStart := i
+		for i < len(data) && !isspace(data[i]) && data[i] != '=' && data[i] != '>' && data[i] != '/' {
+			i++
+		}
+		attr := attribute{name: strings.ToLower(string(data[nameStart:i]))}
+		for i < len(data) && isspace(data[i]) {
+			i++
+		}
+		if i < len(data) && data[i] == '=' {
+			i++
+			for i < len(data) && isspace(data[i]) {
+				i++
+			}
+			valueStart := i
+			if i < len(data) && (data[i] == '"' || data[i] == '\'') {
+				quote := data[i]
+				end := bytes.IndexByte(data[i+1:], quote)
+				if end < 0 {
+					return 0
+				}
+				attr.value = string(data[i+1 : i+1+end])
+				i += end + 2
+			} else {
+				for i < len(data) && !isspace(data[i]) && data[i] != '>' {
+					i++
+				}
+				attr.value = string(data[valueStart:i])
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+
+	allowed, ok := s.policy.Elements[name]
+	if !ok {
+		if !closing && htmlDroppedContents[name] && !bytes.HasSuffix(data[:i], []byte("/>")) {
+			s.dropped = name
+		}
+		return i
+	}
+
+	if closing {
+		s.closeElement(out, name)
+		return i
+	}
+
+	out.WriteByte('<')
+	out.WriteString(name)
+	for _, attr := range attrs {
+		if !s.allowAttribute(allowed, attr.name, attr.value) {
+			continue
+		}
+		out.WriteByte(' ')
+		out.WriteString(attr.name)
+		out.WriteString("=\"")
+		attrEscape(out, []byte(html.UnescapeString(attr.value)))
+		out.WriteByte('"')
+	}
+	out.WriteByte('>')
+	if !htmlVoidElements[name] {
+		s.open = append(s.open, name)
+	}
+	return i
+}
+
+func (s *htmlSanitizer) allowAttribute(allowed []string, name, value string) bool {
+	if strings.HasPrefix(name, "on") {
+		return false
+	}
+	if !stringIn(name, allowed) && !stringIn(name, s.policy.GlobalAttributes) {
+		return false
+	}
+	if !htmlURLAttributes[name] {
+		return true
+	}
+
+	url := []byte(html.UnescapeString(value))
+	if s.links != nil {
+		return s.links.Allows(url, name == "src" || name == "poster")
+	}
+	scheme := urlScheme(normalizeURL(url))
+	return scheme == "" || stringIn(scheme, s.policy.URLSchemes)
+}
+
+// closeElement writes the end tag of the innermost open element with the
+// given name, and of any elements opened inside it. End tags of elements
+// that are not open are dropped.
+func (s *htmlSanitizer) closeElement(out *bytes.Buffer, name string) {
+	for i := len(s.open) - 1; i >= 0; i-- {
+		if s.open[i] != name {
+			continue
+		}
+		for len(s.open) > i {
+			out.WriteString("</" + s.open[len(s.open)-1] + ">")
+			s.open = s.open[:len(s.open)-1]
+		}
+		return
+	}
+}
+
+// close writes the end tags of the elements left open.
+func (s *htmlSanitizer) close(out *bytes.Buffer) {
+	for len(s.open) > 0 {
+		out.WriteString("</" + s.open[len(s.open)-1] + ">")
+		s.open = s.open[:len(s.open)-1]
+	}
+	s.dropped = ""
+}
+
+func stringIn(s string, list []string) bool {
+	for _, item := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for the HTML sanitizer
+//
+
+package blackfriday
+
+import (
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	var tests = []string{
+		"<details open onclick=\"x()\"><summary>More</summary>Text</details>",
+		"<details open=\"\"><summary>More</summary>Text</details>",
+
+		"Press <kbd>Ctrl</kbd> <KBD Style=\"color: red\">C</KBD>",
+		"Press <kbd>Ctrl</kbd> <kbd>C</kbd>",
+
+		"<script>alert(1)</script><style>p {}</style>ok",
+		"ok",
+
+		"<a href=\"javascript:alert(1)\" title=\"t\">x</a>",
+		"<a title=\"t\">x</a>",
+
+		"<a href=\"java&#09;script:alert(1)\">x</a><a href=' JaVaScRiPt:x'>y</a>",
+		"<a>x</a><a>y</a>",
+
+		"<a href=\"/docs?a=1&amp;b=2\">x</a><a href=https://example.com/>y</a>",
+		"<a href=\"/docs?a=1&amp;b=2\">x</a><a href=\"https://example.com/\">y</a>",
+
+		"<img src=\"data:image/png;base64,xx\" alt=\"a\"><img src=\"p.png\" onerror=\"x()\">",
+		"<img alt=\"a\"><img src=\"p.png\">",
+
+		"<div><p>one<b>two</div>three</i>",
+		"<div><p>one<b>two</b></p></div>three",
+
+		"<iframe src=\"x\">inside</iframe><!-- comment --><custom>text</custom>",
+		"text",
+
+		"1 < 2 <3 <!doctype html>",
+		"1 &lt; 2 &lt;3 ",
+
+		"<a title=\"&quot;&lt;\">x</a><a title='it\"s'>y",
+		"<a title=\"&quot;&lt;\">x</a><a title=\"it&quot;s\">y</a>",
+	}
+	policy := DefaultHtmlPolicy()
+	for i := 0; i+1 < len(tests); i += 2 {
+		if actual := string(policy.Sanitize([]byte(tests[i]))); actual != tests[i+1] {
+			t.Errorf("\nInput   [%#v]\nExpected[%#v]\nActual  [%#v]", tests[i], tests[i+1], actual)
+		}
+	}
+}
+
+func TestHtmlPolicy(t *testing.T) {
+	var tests = []string{
+		"Press <kbd>Ctrl</kbd> and <span onmouseover=\"x()\">hover</span>.\n",
+		"<p>Press <kbd>Ctrl</kbd> and <span>hover</span>.</p>\n",
+
+		"An <b>unclosed tag\n\nNext <script>x</script>\n",
+		"<p>An <b>unclosed tag</b></p>\n\n<p>Next x</p>\n",
+
+		"* <kbd>a\n* b\n",
+		"<ul>\n<li><kbd>a</kbd></li>\n<li>b</li>\n</ul>\n",
+
+		"<details>\n<summary onclick=\"x()\">More</summary>\n\n<script>alert(1)</script>\n</details>\n",
+		"<details>\n<summary>More</summary>\n\n\n</details>\n",
+
+		"<div>\n<form action=\"/x\"><input name=\"a\"></form>\n</div>\n",
+		"<div>\n\n</div>\n",
+	}
+	doTestsInlineParam(t, tests, Options{}, 0, HtmlRendererParameters{HtmlPolicy: DefaultHtmlPolicy()})
+}
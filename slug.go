@@ -0,0 +1,175 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Header IDs compatible with other Markdown processors
+//
+//
+
+package blackfriday
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Slugger makes the ID of a header from its text with the inline markup
+// removed, for EXTENSION_AUTO_HEADER_IDS. Repeated IDs are made unique by the
+// renderer. SanitizedAnchorName can be used as a Slugger to get IDs from the
+// plain text with the default algorithm.
+type Slugger func(text string) string
+
+// GitHubSlug makes header IDs the way GitHub does: the text is lower cased,
+// punctuation other than hyphens and underscores is dropped, and each space
+// becomes a hyphen, so "Hello,  World" gives "hello--world".
+func GitHubSlug(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '-'
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r):
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, strings.TrimSpace(text))
+}
+
+// GitLabSlug makes header IDs the way GitLab does, which is like GitHubSlug
+// except that runs of hyphens are collapsed, so "Hello,  World" gives
+// "hello-world".
+func GitLabSlug(text string) string {
+	slug := GitHubSlug(text)
+	for strings.Contains(slug, "--") {
+		slug = strings.Replace(slug, "--", "-", -1)
+	}
+	return slug
+}
+
+// PandocSlug makes header IDs the way Pandoc does: the text is lower cased,
+// punctuation other than hyphens, underscores and periods is dropped, then
+// runs of spaces become a hyphen and anything before the first letter is
+// dropped. If nothing is left, the ID is "section".
+func PandocSlug(text string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case r == '-' || r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsNumber(r):
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, text)
+	slug = strings.Join(strings.Fields(slug), "-")
+	slug = strings.TrimLeftFunc(slug, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if slug == "" {
+		return "section"
+	}
+	return slug
+}
+
+// headerText returns the text of a header as a reader sees it, which is what
+// a Slugger is given: code spans keep their contents, links and images keep
+// their text, autolinks keep their URL, and emphasis markers, HTML tags and
+// footnote references are dropped.
+func headerText(data []byte) string {
+	var out bytes.Buffer
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data) && bytes.IndexByte(escapeChars, data[i+1]) >= 0:
+			out.WriteByte(data[i+1])
+			i += 2
+
+		case c == '`':
+			n := 1
+			for i+n < len(data) && data[i+n] == '`' {
+				n++
+			}
+			end := bytes.Index(data[i+n:], data[i:i+n])
+			if end < 0 {
+				out.Write(data[i : i+n])
+				i += n
+				break
+			}
+			out.Write(bytes.TrimSpace(data[i+n : i+n+end]))
+			i += n + end + n
+
+		case c == '!' && i+1 < len(data) && data[i+1] == '[':
+			i++
+
+		case c == '[':
+			end := matchingBracket(data, i, '[', ']')
+			if end < 0 {
+				out.WriteByte(c)
+				i++
+				break
+			}
+			if data[i+1] != '^' {
+				out.WriteString(headerText(data[i+1 : end]))
+			}
+			i = end + 1
+			if i < len(data) && (data[i] == '(' || data[i] == '[') {
+				closer := byte(')')
+				if data[i] == '[' {
+					closer = ']'
+				}
+				if end := matchingBracket(data, i, data[i], closer); end >= 0 {
+					i = end + 1
+				}
+			}
+
+		case c == '<':
+			altype := LINK_TYPE_NOT_AUTOLINK
+			end := tagLength(data[i:], &altype)
+			if end == 0 {
+				out.WriteByte(c)
+				i++
+				break
+			}
+			if altype != LINK_TYPE_NOT_AUTOLINK {
+				out.Write(data[i+1 : i+end-1])
+			}
+			i += end
+
+		case c == '*' || c == '~' ||
+			c == '_' && (i == 0 || i+1 == len(data) || !isalnum(data[i-1]) || !isalnum(data[i+1])):
+			i++
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return html.UnescapeString(out.String())
+}
+
+// matchingBracket returns the index of the close bracket matching the open
+// bracket at data[start], or -1 if there is none.
+func matchingBracket(data []byte, start int, opener, closer byte) int {
+	level := 0
+	for i := start; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case opener:
+			level++
+		case closer:
+			level--
+			if level == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,80 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+// Unit tests for header ID sluggers
+//
+
+package blackfriday
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSluggers(t *testing.T) {
+	var tests = []struct {
+		text                   string
+		github, gitlab, pandoc string
+	}{
+		{"Hello,  World!", "hello--world", "hello-world", "hello-world"},
+		{"C++ & Go_lang 2.0", "c--go_lang-20", "c-go_lang-20", "c-go_lang-2.0"},
+		{"  Trimmed  ", "trimmed", "trimmed", "trimmed"},
+		{"3 Ways", "3-ways", "3-ways", "ways"},
+		{"Dogs?--in *my* house?", "dogs--in-my-house", "dogs-in-my-house", "dogs--in-my-house"},
+		{"Hello, 世界", "hello-世界", "hello-世界", "hello-世界"},
+		{"2020", "2020", "2020", "section"},
+	}
+	for _, test := range tests {
+		if got := GitHubSlug(test.text); got != test.github {
+			t.Errorf("GitHubSlug(%q) = %q, want %q", test.text, got, test.github)
+		}
+		if got := GitLabSlug(test.text); got != test.gitlab {
+			t.Errorf("GitLabSlug(%q) = %q, want %q", test.text, got, test.gitlab)
+		}
+		if got := PandocSlug(test.text); got != test.pandoc {
+			t.Errorf("PandocSlug(%q) = %q, want %q", test.text, got, test.pandoc)
+		}
+	}
+}
+
+func TestSluggerOption(t *testing.T) {
+	input := "# Hello,  World!\n\n# Hello,  World!\n\nC++ & Go\n---\n"
+	want := "<h1 id=\"hello--world\">Hello,  World!</h1>\n\n" +
+		"<h1 id=\"hello--world-1\">Hello,  World!</h1>\n\n" +
+		"<h2 id=\"c--go\">C++ &amp; Go</h2>\n"
+	got := string(MarkdownOptions([]byte(input), HtmlRenderer(0, "", ""),
+		Options{Extensions: EXTENSION_AUTO_HEADER_IDS, Slugger: GitHubSlug}))
+	if got != want {
+		t.Errorf("\nExpected[%#v]\nActual  [%#v]", want, got)
+	}
+}
+
+func TestSluggerHeaderText(t *testing.T) {
+	input := "# See [Foo](http://x)\n\n# The `cfg` flag\n\n" +
+		"Image ![Logo](logo.png \"t\") and <em>tags</em>\n---\n\n" +
+		"# Snake_case __strong__ \\*stars\\*[^1] [ref][r] <http://y.example/>\n"
+	tests := []struct {
+		slugger Slugger
+		ids     []string
+	}{
+		{SanitizedAnchorName, []string{"see-foo", "the-cfg-flag", "image-logo-and-tags", "snake-case-strong-stars-ref-http-y-example"}},
+		{GitHubSlug, []string{"see-foo", "the-cfg-flag", "image-logo-and-tags", "snake_case-strong-stars-ref-httpyexample"}},
+		{PandocSlug, []string{"see-foo", "the-cfg-flag", "image-logo-and-tags", "snake_case-strong-stars-ref-httpy.example"}},
+	}
+	for _, test := range tests {
+		got := string(MarkdownOptions([]byte(input), HtmlRenderer(0, "", ""),
+			Options{Extensions: EXTENSION_AUTO_HEADER_IDS | EXTENSION_FOOTNOTES, Slugger: test.slugger}))
+		for _, id := range test.ids {
+			if !strings.Contains(got, "id=\""+id+"\"") {
+				t.Errorf("missing id %q in:\n%s", id, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+//
+// Blackfriday Markdown Processor
+// Available at http://github.com/russross/blackfriday
+//
+// Copyright © 2011 Russ Ross <russ@russross.com>.
+// Distributed under the Simplified BSD License.
+// See README.md for details.
+//
+
+//
+//
+// Table of contents
+//
+//
+
+package blackfriday
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// TocEntry is a header in the outline of a document, as returned by
+// MarkdownToc and Html.Toc.
+type TocEntry struct {
+	Level     int    // 1 to 6
+	Text      string // the header as rendered by the renderer
+	PlainText string // the text of the header without markup
+	ID        string // the id attribute of the header, if it has one
+	Number    string // the number of the header, with HTML_NUMBER_HEADERS
+	Children  []*TocEntry
+}
+
+// Toc returns the headers of the last document rendered as a tree, in which
+// each header is a child of the closest header of a higher level before it.
+// Headers get IDs when EXTENSION_HEADER_IDS, EXTENSION_AUTO_HEADER_IDS or
+// HTML_TOC is enabled.
+func (options *Html) Toc() []*TocEntry {
+	return buildToc(options.headings)
+}
+
+func buildToc(headings []tocHeading) []*TocEntry {
+	var roots []*TocEntry
+
+	// the last entry of each level of the tree, outermost first
+	var path []*TocEntry
+	for _, h := range headings {
+		entry := &TocEntry{
+			Level:     h.level,
+			Text:      string(h.text),
+			PlainText: h.plain,
+			ID:        h.id,
+			Number:    h.number,
+		}
+		if entry.PlainText == "" {
+			entry.PlainText = html.UnescapeString(string(stripTags(h.text)))
+		}
+		for len(path) > 0 && path[len(path)-1].Level >= h.level {
+			path = path[:len(path)-1]
+		}
+		if len(path) == 0 {
+			roots = append(roots, entry)
+		} else {
+			parent := path[len(path)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+		path = append(path, entry)
+	}
+	return roots
+}
+
+// headerNumbers counts the headers of each level to number them.
+type headerNumbers struct {
+	counts [6]int
+}
+
+// next returns the number of a header of the given level, made of the counts
+// of the levels from start to its own, joined by sep. Headers above start
+// get no number.
+func (n *headerNumbers) next(level, start int, sep string) string {
+	if level < start || level > len(n.counts) {
+		return ""
+	}
+	n.counts[level-1]++
+	for i := level; i < len(n.counts); i++ {
+		n.counts[i] = 0
+	}
+
+	var parts []string
+	for i := start - 1; i < level; i++ {
+		parts = append(parts, strconv.Itoa(n.counts[i]))
+	}
+	return strings.Join(parts, sep)
+}